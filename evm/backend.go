@@ -0,0 +1,37 @@
+// Package evm holds the chain plumbing shared by memo, mfile and file-proof.
+package evm
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Backend is what controllers, resolvers and proof instances need from a chain.
+// *ethclient.Client and *backends.SimulatedBackend both implement it.
+type Backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+}
+
+var _ Backend = &ethclient.Client{}
+
+// DefaultChainID is used when the chain id cannot be read from the endpoint
+var DefaultChainID = big.NewInt(985)
+
+// Dial connects to endpoint and returns the client with its chain id
+func Dial(ctx context.Context, endpoint string) (*ethclient.Client, *big.Int, error) {
+	client, err := ethclient.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chainID, err := client.NetworkID(ctx)
+	if err != nil {
+		chainID = DefaultChainID
+	}
+
+	return client, chainID, nil
+}
@@ -0,0 +1,167 @@
+package evm
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// DefaultCacheSize is the number of entries kept by a cache
+	DefaultCacheSize = 10000
+	// DefaultCacheTTL is how long an entry is kept by a cache
+	DefaultCacheTTL = 10 * time.Minute
+)
+
+// CacheOptions bounds the entries of cache
+type CacheOptions struct {
+	// Size is the max number of entries, DefaultCacheSize is used if it is 0
+	Size int
+	// TTL is how long an entry is kept, DefaultCacheTTL is used if it is 0
+	TTL time.Duration
+	// PollInterval is used to poll contract events if backend cannot subscribe them,
+	// pollInterval is used if it is 0
+	PollInterval time.Duration
+}
+
+// Topic returns the topic of identifier indexed in events, which invalidates
+// the cache entries depending on identifier
+func Topic(identifier string) common.Hash {
+	return crypto.Keccak256Hash([]byte(identifier))
+}
+
+// Cache is a LRU cache, the entries expire after ttl and are invalidated by
+// the topics they depend on.
+type Cache[V any] struct {
+	size int
+	ttl  time.Duration
+
+	lk      sync.Mutex
+	entries *list.List
+	keys    map[string]*list.Element
+	topics  map[common.Hash]map[string]struct{}
+	// generation is increased on every invalidation
+	generation uint64
+}
+
+type cacheEntry[V any] struct {
+	key    string
+	value  V
+	expire time.Time
+	topics []common.Hash
+}
+
+// NewCache creates a cache bounded by opts
+func NewCache[V any](opts CacheOptions) *Cache[V] {
+	if opts.Size <= 0 {
+		opts.Size = DefaultCacheSize
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultCacheTTL
+	}
+	return &Cache[V]{
+		size:    opts.Size,
+		ttl:     opts.TTL,
+		entries: list.New(),
+		keys:    make(map[string]*list.Element),
+		topics:  make(map[common.Hash]map[string]struct{}),
+	}
+}
+
+// Generation returns the current generation of cache, which should be read
+// before the value to add is read from chain
+func (c *Cache[V]) Generation() uint64 {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	return c.generation
+}
+
+// Get returns the value of key if it is not expired
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	var zero V
+	elem, ok := c.keys[key]
+	if !ok {
+		return zero, false
+	}
+	entry := elem.Value.(*cacheEntry[V])
+	if time.Now().After(entry.expire) {
+		c.remove(elem)
+		return zero, false
+	}
+	c.entries.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Add saves value of key, which is invalidated by topics. The value is not saved
+// if the cache is invalidated after generation, since it may be read before
+// the invalidating event.
+func (c *Cache[V]) Add(generation uint64, key string, value V, topics ...common.Hash) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if elem, ok := c.keys[key]; ok {
+		c.remove(elem)
+	}
+
+	entry := &cacheEntry[V]{key: key, value: value, expire: time.Now().Add(c.ttl), topics: topics}
+	c.keys[key] = c.entries.PushFront(entry)
+	for _, topic := range topics {
+		if c.topics[topic] == nil {
+			c.topics[topic] = make(map[string]struct{})
+		}
+		c.topics[topic][key] = struct{}{}
+	}
+
+	for c.entries.Len() > c.size {
+		c.remove(c.entries.Back())
+	}
+}
+
+// Invalidate removes the entries depending on topic
+func (c *Cache[V]) Invalidate(topic common.Hash) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.generation++
+	for key := range c.topics[topic] {
+		c.remove(c.keys[key])
+	}
+}
+
+// Purge removes all entries
+func (c *Cache[V]) Purge() {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.generation++
+	c.entries.Init()
+	c.keys = make(map[string]*list.Element)
+	c.topics = make(map[common.Hash]map[string]struct{})
+}
+
+// Len returns the number of entries, including expired ones not removed yet
+func (c *Cache[V]) Len() int {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	return c.entries.Len()
+}
+
+func (c *Cache[V]) remove(elem *list.Element) {
+	entry := c.entries.Remove(elem).(*cacheEntry[V])
+	delete(c.keys, entry.key)
+	for _, topic := range entry.topics {
+		delete(c.topics[topic], entry.key)
+		if len(c.topics[topic]) == 0 {
+			delete(c.topics, topic)
+		}
+	}
+}
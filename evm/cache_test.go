@@ -0,0 +1,99 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestCache(t *testing.T) {
+	cache := NewCache[int](CacheOptions{Size: 2, TTL: time.Hour})
+
+	cache.Add(cache.Generation(), "a", 1, Topic("a"))
+	cache.Add(cache.Generation(), "b", 2, Topic("b"), Topic("a"))
+	if v, ok := cache.Get("a"); !ok || v != 1 {
+		t.Fatal(v, ok)
+	}
+	// b is the least recently used one
+	cache.Add(cache.Generation(), "c", 3, Topic("c"))
+	if _, ok := cache.Get("b"); ok || cache.Len() != 2 {
+		t.Fatal("least recently used entry should be evicted")
+	}
+
+	cache.Invalidate(Topic("a"))
+	if _, ok := cache.Get("a"); ok {
+		t.Fatal("entry should be invalidated")
+	}
+	if v, ok := cache.Get("c"); !ok || v != 3 {
+		t.Fatal(v, ok)
+	}
+
+	// value read before invalidation is not saved
+	generation := cache.Generation()
+	cache.Invalidate(Topic("d"))
+	cache.Add(generation, "d", 4, Topic("d"))
+	if _, ok := cache.Get("d"); ok {
+		t.Fatal("stale value should not be saved")
+	}
+
+	expiring := NewCache[int](CacheOptions{TTL: time.Millisecond})
+	expiring.Add(expiring.Generation(), "a", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := expiring.Get("a"); ok || expiring.Len() != 0 {
+		t.Fatal("entry should expire")
+	}
+}
+
+// pollingChain cannot subscribe logs
+type pollingChain struct {
+	*testChain
+}
+
+func (c pollingChain) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, errors.New("notifications not supported")
+}
+
+func TestWatcher(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+	addr := chain.deployLogger(t)
+	other := chain.deployLogger(t)
+
+	for _, polling := range []bool{false, true} {
+		topics := make(chan common.Hash, 16)
+		fn := func(log types.Log) {
+			topics <- log.Topics[1]
+		}
+
+		var w *Watcher
+		var err error
+		if polling {
+			w, err = NewWatcher(pollingChain{chain}, []common.Address{addr}, 10*time.Millisecond, fn)
+		} else {
+			w, err = NewWatcher(chain, []common.Address{addr}, 0, fn)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		chain.send(t, &other, 100000, crypto.Keccak256([]byte("other")))
+		chain.send(t, &addr, 100000, crypto.Keccak256([]byte("did")))
+		chain.Commit()
+
+		select {
+		case topic := <-topics:
+			if topic != Topic("did") {
+				t.Fatalf("unexpected topic %s", topic)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("log is not watched, polling: %v", polling)
+		}
+		w.Close()
+	}
+}
@@ -0,0 +1,386 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/gorilla/websocket"
+	"golang.org/x/xerrors"
+)
+
+// ErrClosed is returned by the calls on a closed client
+var ErrClosed = xerrors.New("client is closed")
+
+// Backoff is the delay between redials of a broken connection
+type Backoff struct {
+	// Min is the delay before the first redial
+	Min time.Duration
+	// Max caps the delay, which is doubled after each failed redial
+	Max time.Duration
+	// Retries is the number of redials before giving up, DefaultBackoff.Retries is used if it is 0
+	Retries int
+}
+
+// DefaultBackoff is used by clients which are not configured with a backoff
+var DefaultBackoff = Backoff{
+	Min:     500 * time.Millisecond,
+	Max:     30 * time.Second,
+	Retries: 5,
+}
+
+func (b Backoff) orDefault() Backoff {
+	if b == (Backoff{}) {
+		return DefaultBackoff
+	}
+	if b.Retries <= 0 {
+		b.Retries = DefaultBackoff.Retries
+	}
+	return b
+}
+
+// delay returns how long to wait before the redial of attempt
+func (b Backoff) delay(attempt int) time.Duration {
+	d := b.Min
+	for i := 0; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// Client is a long-lived connection to an endpoint, which is redialed with backoff
+// when it is broken. It implements Backend, so one client can be shared by
+// controllers, resolvers and proof instances.
+type Client struct {
+	*conn
+
+	once    sync.Once
+	release func()
+}
+
+var _ Backend = &Client{}
+
+// DialClient connects to endpoint, the connection is redialed with backoff when
+// it is broken, DefaultBackoff is used if backoff is zero.
+func DialClient(ctx context.Context, endpoint string, backoff Backoff) (*Client, error) {
+	cn, err := dialConn(ctx, endpoint, backoff.orDefault())
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: cn, release: cn.close}, nil
+}
+
+// Close releases the client, the connection is closed when it is not shared by
+// others. Calls on the client after Close are not allowed.
+func (c *Client) Close() {
+	c.once.Do(c.release)
+}
+
+// conn is the connection shared by clients of the same endpoint
+type conn struct {
+	endpoint string
+	chainID  *big.Int
+	backoff  Backoff
+
+	lk     sync.RWMutex
+	client *ethclient.Client
+	closed bool
+	done   chan struct{}
+
+	// only one call redials at a time
+	dialLk sync.Mutex
+}
+
+func dialConn(ctx context.Context, endpoint string, backoff Backoff) (*conn, error) {
+	client, chainID, err := Dial(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{
+		endpoint: endpoint,
+		chainID:  chainID,
+		backoff:  backoff,
+		client:   client,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Endpoint returns the endpoint that the client connects to
+func (c *conn) Endpoint() string {
+	return c.endpoint
+}
+
+// ChainID returns the chain id read when the client is dialed
+func (c *conn) ChainID() *big.Int {
+	return new(big.Int).Set(c.chainID)
+}
+
+func (c *conn) close() {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+		c.client.Close()
+	}
+}
+
+func (c *conn) current() (*ethclient.Client, error) {
+	c.lk.RLock()
+	defer c.lk.RUnlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	return c.client, nil
+}
+
+// do calls fn with the current connection, if the connection is broken,
+// it is redialed and fn is called again
+func (c *conn) do(ctx context.Context, fn func(client *ethclient.Client) error) error {
+	client, err := c.current()
+	if err != nil {
+		return err
+	}
+
+	err = fn(client)
+	if !isConnError(ctx, err) {
+		return err
+	}
+
+	client, err = c.redial(ctx, client)
+	if err != nil {
+		return err
+	}
+	return fn(client)
+}
+
+// redial replaces the broken connection, it does nothing if another call
+// has replaced it already
+func (c *conn) redial(ctx context.Context, broken *ethclient.Client) (*ethclient.Client, error) {
+	c.dialLk.Lock()
+	defer c.dialLk.Unlock()
+
+	client, err := c.current()
+	if err != nil || client != broken {
+		return client, err
+	}
+
+	for attempt := 0; attempt < c.backoff.Retries; attempt++ {
+		timer := time.NewTimer(c.backoff.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-c.done:
+			timer.Stop()
+			return nil, ErrClosed
+		case <-timer.C:
+		}
+
+		client, err = ethclient.DialContext(ctx, c.endpoint)
+		if err != nil {
+			continue
+		}
+		// dialing http endpoint always succeeds, make sure it is reachable
+		_, err = client.BlockNumber(ctx)
+		if err != nil {
+			client.Close()
+			continue
+		}
+
+		c.lk.Lock()
+		if c.closed {
+			c.lk.Unlock()
+			client.Close()
+			return nil, ErrClosed
+		}
+		c.client = client
+		c.lk.Unlock()
+
+		broken.Close()
+		return client, nil
+	}
+
+	return nil, xerrors.Errorf("redial %s after %d retries: %w", c.endpoint, c.backoff.Retries, err)
+}
+
+// isConnError reports whether err is caused by a broken connection rather than
+// a failed request, which is worth redialing
+func isConnError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+
+	var netErr net.Error
+	var closeErr *websocket.CloseError
+	return errors.Is(err, rpc.ErrClientQuit) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &netErr) ||
+		errors.As(err, &closeErr)
+}
+
+func (c *conn) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) (code []byte, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		code, err = client.CodeAt(ctx, contract, blockNumber)
+		return err
+	})
+	return code, err
+}
+
+func (c *conn) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) (res []byte, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		res, err = client.CallContract(ctx, call, blockNumber)
+		return err
+	})
+	return res, err
+}
+
+func (c *conn) HeaderByNumber(ctx context.Context, number *big.Int) (head *types.Header, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		head, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return head, err
+}
+
+func (c *conn) PendingCodeAt(ctx context.Context, account common.Address) (code []byte, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		code, err = client.PendingCodeAt(ctx, account)
+		return err
+	})
+	return code, err
+}
+
+func (c *conn) PendingNonceAt(ctx context.Context, account common.Address) (nonce uint64, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		nonce, err = client.PendingNonceAt(ctx, account)
+		return err
+	})
+	return nonce, err
+}
+
+func (c *conn) SuggestGasPrice(ctx context.Context) (price *big.Int, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		price, err = client.SuggestGasPrice(ctx)
+		return err
+	})
+	return price, err
+}
+
+func (c *conn) SuggestGasTipCap(ctx context.Context) (tip *big.Int, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		tip, err = client.SuggestGasTipCap(ctx)
+		return err
+	})
+	return tip, err
+}
+
+func (c *conn) EstimateGas(ctx context.Context, call ethereum.CallMsg) (gas uint64, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		gas, err = client.EstimateGas(ctx, call)
+		return err
+	})
+	return gas, err
+}
+
+// SendTransaction sends tx, it is sent again after redialing since the signed transaction is the
+// same one. The first send may have reached the node before the connection broke, so the errors of
+// sending it again are ignored if the node knows tx already.
+func (c *conn) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	resent := false
+	return c.do(ctx, func(client *ethclient.Client) error {
+		err := client.SendTransaction(ctx, tx)
+		if err != nil && resent && isKnownTx(ctx, client, tx, err) {
+			return nil
+		}
+		resent = true
+		return err
+	})
+}
+
+// isKnownTx reports whether err of sending tx is caused by tx which has been sent, such as
+// "already known" of the txpool, or "nonce too low" after tx is mined
+func isKnownTx(ctx context.Context, client *ethclient.Client, tx *types.Transaction, err error) bool {
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction") {
+		return true
+	}
+	_, _, err = client.TransactionByHash(ctx, tx.Hash())
+	return err == nil
+}
+
+func (c *conn) FilterLogs(ctx context.Context, query ethereum.FilterQuery) (logs []types.Log, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		logs, err = client.FilterLogs(ctx, query)
+		return err
+	})
+	return logs, err
+}
+
+// SubscribeFilterLogs subscribes logs on the current connection, the subscription
+// ends with an error when the connection is broken
+func (c *conn) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (sub ethereum.Subscription, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		sub, err = client.SubscribeFilterLogs(ctx, query, ch)
+		return err
+	})
+	return sub, err
+}
+
+// SubscribeNewHead subscribes new heads on the current connection, the subscription
+// ends with an error when the connection is broken
+func (c *conn) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (sub ethereum.Subscription, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		sub, err = client.SubscribeNewHead(ctx, ch)
+		return err
+	})
+	return sub, err
+}
+
+func (c *conn) TransactionReceipt(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		receipt, err = client.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return receipt, err
+}
+
+func (c *conn) TransactionByHash(ctx context.Context, txHash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		tx, isPending, err = client.TransactionByHash(ctx, txHash)
+		return err
+	})
+	return tx, isPending, err
+}
+
+func (c *conn) BlockNumber(ctx context.Context) (number uint64, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		number, err = client.BlockNumber(ctx)
+		return err
+	})
+	return number, err
+}
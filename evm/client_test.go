@@ -0,0 +1,344 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type ethService struct {
+	number uint64
+	server *testServer
+}
+
+func (s *ethService) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(s.number)
+}
+
+// SendRawTransaction accepts a transaction once like a txpool. If the server is set to drop, the
+// server is stopped before replying, as if the connection breaks after the transaction is sent.
+func (s *ethService) SendRawTransaction(ctx context.Context, data hexutil.Bytes) (common.Hash, error) {
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(data); err != nil {
+		return common.Hash{}, err
+	}
+
+	s.server.lk.Lock()
+	known := s.server.txs[tx.Hash()]
+	s.server.txs[tx.Hash()] = true
+	dropped := s.server.dropped
+	s.server.dropped = nil
+	s.server.lk.Unlock()
+
+	if known {
+		return common.Hash{}, errors.New("already known")
+	}
+	if dropped != nil {
+		go func() {
+			s.server.stop()
+			close(dropped)
+		}()
+		<-ctx.Done()
+	}
+	return tx.Hash(), nil
+}
+
+type netService struct{}
+
+func (netService) Version() string {
+	return "985"
+}
+
+// testServer serves block number on a websocket endpoint, it can be stopped
+// and started again on the same address
+type testServer struct {
+	addr string
+	srv  *rpc.Server
+	hs   *http.Server
+
+	lk  sync.Mutex
+	txs map[common.Hash]bool
+	// dropped is closed after the server is stopped while sending a transaction
+	dropped chan struct{}
+}
+
+func (s *testServer) start(number uint64) error {
+	addr := s.addr
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.addr = l.Addr().String()
+
+	s.srv = rpc.NewServer()
+	if s.txs == nil {
+		s.txs = make(map[common.Hash]bool)
+	}
+	if err := s.srv.RegisterName("eth", &ethService{number: number, server: s}); err != nil {
+		return err
+	}
+	if err := s.srv.RegisterName("net", netService{}); err != nil {
+		return err
+	}
+	s.hs = &http.Server{Handler: s.srv.WebsocketHandler([]string{"*"})}
+	go s.hs.Serve(l)
+	return nil
+}
+
+func startTestServer(t *testing.T) *testServer {
+	s := &testServer{}
+	if err := s.start(1); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (s *testServer) stop() {
+	s.hs.Close()
+	s.srv.Stop()
+}
+
+func (s *testServer) endpoint() string {
+	return "ws://" + s.addr
+}
+
+func TestClientRedial(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := startTestServer(t)
+
+	client, err := DialClient(ctx, s.endpoint(), Backoff{Min: 10 * time.Millisecond, Max: 100 * time.Millisecond, Retries: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if client.ChainID().Int64() != 985 {
+		t.Fatalf("chain id is %s", client.ChainID())
+	}
+	number, err := client.BlockNumber(ctx)
+	if err != nil || number != 1 {
+		t.Fatal(number, err)
+	}
+
+	// restart the server after the client starts redialing
+	s.stop()
+	started := make(chan error, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		started <- s.start(2)
+	}()
+
+	number, err = client.BlockNumber(ctx)
+	if err := <-started; err != nil {
+		t.Fatal(err)
+	}
+	defer s.stop()
+	if err != nil || number != 2 {
+		t.Fatal(number, err)
+	}
+}
+
+func TestClientResend(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := startTestServer(t)
+	client, err := DialClient(ctx, s.endpoint(), Backoff{Min: 10 * time.Millisecond, Max: 100 * time.Millisecond, Retries: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	sk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil), types.NewEIP155Signer(client.ChainID()), sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the connection breaks after the transaction reaches the server, and it is known when it is sent again
+	dropped := make(chan struct{})
+	s.lk.Lock()
+	s.dropped = dropped
+	s.lk.Unlock()
+	started := make(chan error, 1)
+	go func() {
+		<-dropped
+		started <- s.start(1)
+	}()
+
+	err = client.SendTransaction(ctx, tx)
+	if err := <-started; err != nil {
+		t.Fatal(err)
+	}
+	defer s.stop()
+	if err != nil {
+		t.Fatalf("transaction known after redialing should be sent: %v", err)
+	}
+
+	// it is an error to send a known transaction without redialing
+	err = client.SendTransaction(ctx, tx)
+	if err == nil {
+		t.Fatal("known transaction is sent again")
+	}
+}
+
+func TestClientGiveUp(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := startTestServer(t)
+
+	client, err := DialClient(ctx, s.endpoint(), Backoff{Min: time.Millisecond, Max: 10 * time.Millisecond, Retries: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	s.stop()
+
+	_, err = client.BlockNumber(ctx)
+	if err == nil {
+		t.Fatal("block number is read from stopped server")
+	}
+
+	client.Close()
+	_, err = client.BlockNumber(ctx)
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal(err)
+	}
+
+	// the default retries are used if retries is not set
+	s = startTestServer(t)
+	client, err = DialClient(ctx, s.endpoint(), Backoff{Min: time.Millisecond, Max: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if client.backoff.Retries != DefaultBackoff.Retries {
+		t.Fatalf("retries is %d, should be %d", client.backoff.Retries, DefaultBackoff.Retries)
+	}
+	s.stop()
+	_, err = client.BlockNumber(ctx)
+	if err == nil || errors.Unwrap(err) == nil {
+		t.Fatalf("giving up should return the cause: %v", err)
+	}
+}
+
+func TestPool(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s := startTestServer(t)
+	defer s.stop()
+
+	pool := NewPool()
+	c1, err := pool.Get(ctx, s.endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := Options{Pool: pool}.Dial(ctx, s.endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1.conn != c2.conn || pool.Len() != 1 {
+		t.Fatal("connection is not shared")
+	}
+
+	// closing twice releases only once
+	c1.Close()
+	c1.Close()
+	if pool.Len() != 1 {
+		t.Fatal("connection is closed while it is used")
+	}
+	if _, err := c2.BlockNumber(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c2.Close()
+	if pool.Len() != 0 {
+		t.Fatal("connection is not closed")
+	}
+	if _, err := c2.BlockNumber(ctx); !errors.Is(err, ErrClosed) {
+		t.Fatal(err)
+	}
+
+	// an endpoint not answering does not block the others, and the concurrent
+	// calls of an endpoint share one connection
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	hangCtx, hangCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer hangCancel()
+	hung := make(chan error, 1)
+	go func() {
+		_, err := pool.Get(hangCtx, "ws://"+l.Addr().String())
+		hung <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	clients := make([]*Client, 4)
+	errs := make([]error, len(clients))
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			getCtx, getCancel := context.WithTimeout(ctx, time.Second)
+			defer getCancel()
+			clients[i], errs[i] = pool.Get(getCtx, s.endpoint())
+		}(i)
+	}
+	wg.Wait()
+	for i, c := range clients {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if c.conn != clients[0].conn {
+			t.Fatal("connection is not shared by concurrent calls")
+		}
+	}
+	select {
+	case <-hung:
+		t.Fatal("endpoint not answering blocks the others")
+	default:
+	}
+	if err := <-hung; err == nil {
+		t.Fatal("endpoint not answering is dialed")
+	}
+	if pool.Len() != 1 {
+		t.Fatalf("pool has %d connections", pool.Len())
+	}
+	for _, c := range clients {
+		c.Close()
+	}
+
+	c3, err := pool.Get(ctx, s.endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Close()
+	if _, err := c3.BlockNumber(ctx); !errors.Is(err, ErrClosed) {
+		t.Fatal(err)
+	}
+	c3.Close()
+}
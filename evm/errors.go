@@ -0,0 +1,135 @@
+package evm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NotMinedError is returned when transaction is not packaged before timeout or ctx is done
+type NotMinedError struct {
+	Name   string
+	TxHash common.Hash
+	Err    error
+}
+
+func (e *NotMinedError) Error() string {
+	return fmt.Sprintf("%s: cann't get transaction(%s) receipt, not packaged: %s", e.Name, e.TxHash, e.Err)
+}
+
+func (e *NotMinedError) Unwrap() error {
+	return e.Err
+}
+
+// OutOfGasError is returned when transaction is packaged but runs out of gas
+type OutOfGasError struct {
+	Name     string
+	TxHash   common.Hash
+	GasLimit uint64
+	GasUsed  uint64
+}
+
+func (e *OutOfGasError) Error() string {
+	return fmt.Sprintf("%s: transaction(%s) exceed gas limit(%d)", e.Name, e.TxHash, e.GasLimit)
+}
+
+// RevertError is returned when transaction is packaged but execution failed
+type RevertError struct {
+	Name   string
+	TxHash common.Hash
+	// Reason is the message of Error(string), or the signature of panic and custom errors
+	Reason string
+	// ErrorName and Args are set if the revert is a custom error found in the contract abi
+	ErrorName string
+	Args      []interface{}
+	// Data is the raw revert data, empty if it cannot be read
+	Data []byte
+}
+
+func (e *RevertError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%s: transaction(%s) mined but execution failed, please check your tx input", e.Name, e.TxHash)
+	}
+	return fmt.Sprintf("%s: transaction(%s) revert(%s)", e.Name, e.TxHash, e.Reason)
+}
+
+var (
+	errorSig = []byte{0x08, 0xc3, 0x79, 0xa0} // Keccak256("Error(string)")[:4]
+	panicSig = []byte{0x4e, 0x48, 0x7b, 0x71} // Keccak256("Panic(uint256)")[:4]
+
+	abiString, _  = abi.NewType("string", "", nil)
+	abiUint256, _ = abi.NewType("uint256", "", nil)
+)
+
+// DecodeRevert decodes revert data into a readable reason,
+// custom errors are looked up in abis.
+func DecodeRevert(data []byte, abis ...*abi.ABI) (reason string, errorName string, args []interface{}) {
+	if len(data) < 4 {
+		return "", "", nil
+	}
+
+	switch {
+	case bytes.Equal(data[:4], errorSig):
+		vs, err := abi.Arguments{{Type: abiString}}.UnpackValues(data[4:])
+		if err == nil {
+			return vs[0].(string), "Error", vs
+		}
+	case bytes.Equal(data[:4], panicSig):
+		vs, err := abi.Arguments{{Type: abiUint256}}.UnpackValues(data[4:])
+		if err == nil {
+			return fmt.Sprintf("panic(0x%x)", vs[0].(*big.Int)), "Panic", vs
+		}
+	}
+
+	for _, a := range abis {
+		if a == nil {
+			continue
+		}
+		for _, e := range a.Errors {
+			if !bytes.Equal(e.ID[:4], data[:4]) {
+				continue
+			}
+			vs, err := e.Inputs.Unpack(data[4:])
+			if err != nil {
+				continue
+			}
+			return formatError(e.Name, vs), e.Name, vs
+		}
+	}
+
+	return "0x" + hex.EncodeToString(data), "", nil
+}
+
+func formatError(name string, args []interface{}) string {
+	strs := make([]string, 0, len(args))
+	for _, arg := range args {
+		strs = append(strs, fmt.Sprint(arg))
+	}
+	return name + "(" + strings.Join(strs, ", ") + ")"
+}
+
+// revertData extracts revert data from the error returned by eth_call
+func revertData(err error) []byte {
+	var dataErr interface{ ErrorData() interface{} }
+	if !errors.As(err, &dataErr) {
+		return nil
+	}
+
+	switch data := dataErr.ErrorData().(type) {
+	case string:
+		res, err := hex.DecodeString(strings.TrimPrefix(data, "0x"))
+		if err != nil {
+			return nil
+		}
+		return res
+	case []byte:
+		return data
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"golang.org/x/xerrors"
+)
+
+// DefaultGasMultiplier is the safety margin multiplied to estimated gas
+var DefaultGasMultiplier = 1.2
+
+// FeePolicy decides the gas limit and fees of transactions
+type FeePolicy struct {
+	// GasLimit of transactions, gas is estimated if it is 0
+	GasLimit uint64
+	// GasMultiplier is multiplied to the estimated gas, DefaultGasMultiplier is used if it is 0
+	GasMultiplier float64
+
+	// Legacy sends legacy transactions, otherwise dynamic-fee transactions are sent
+	// if the chain supports EIP-1559
+	Legacy bool
+	// GasPrice of legacy transactions, suggested by backend if nil
+	GasPrice *big.Int
+	// GasTipCap of dynamic-fee transactions, suggested by backend if nil
+	GasTipCap *big.Int
+	// MaxFee caps the gas price of legacy transactions and the fee cap of
+	// dynamic-fee transactions, no cap if nil
+	MaxFee *big.Int
+}
+
+type feeKey struct{}
+
+// WithFee returns a copy of ctx with fee policy, which overrides the fee policy
+// of controller or proof instance for the calls made with it.
+func WithFee(ctx context.Context, fee FeePolicy) context.Context {
+	return context.WithValue(ctx, feeKey{}, fee)
+}
+
+func feeFromContext(ctx context.Context, fee FeePolicy) FeePolicy {
+	if override, ok := ctx.Value(feeKey{}).(FeePolicy); ok {
+		return override
+	}
+	return fee
+}
+
+// apply fills the fees of opts according to policy
+func (p FeePolicy) apply(ctx context.Context, backend bind.ContractBackend, opts *bind.TransactOpts) error {
+	opts.GasLimit = p.GasLimit
+	opts.GasPrice, opts.GasFeeCap, opts.GasTipCap = nil, nil, nil
+
+	var head *types.Header
+	if !p.Legacy {
+		var err error
+		head, err = backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	// chain is not London ready, use legacy transaction
+	if head == nil || head.BaseFee == nil {
+		gasPrice := p.GasPrice
+		if gasPrice == nil {
+			var err error
+			gasPrice, err = backend.SuggestGasPrice(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		opts.GasPrice = capFee(gasPrice, p.MaxFee)
+		return nil
+	}
+
+	gasTipCap := p.GasTipCap
+	if gasTipCap == nil {
+		var err error
+		gasTipCap, err = backend.SuggestGasTipCap(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	gasFeeCap := new(big.Int).Add(gasTipCap, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+	gasFeeCap = capFee(gasFeeCap, p.MaxFee)
+	if gasFeeCap.Cmp(head.BaseFee) < 0 {
+		return xerrors.Errorf("max fee %s is lower than base fee %s", p.MaxFee, head.BaseFee)
+	}
+	if gasFeeCap.Cmp(gasTipCap) < 0 {
+		gasTipCap = gasFeeCap
+	}
+	opts.GasFeeCap = gasFeeCap
+	opts.GasTipCap = gasTipCap
+	return nil
+}
+
+// gasLimit multiplies the estimated gas by the multiplier of policy
+func (p FeePolicy) gasLimit(estimated uint64) uint64 {
+	multiplier := p.GasMultiplier
+	if multiplier == 0 {
+		multiplier = DefaultGasMultiplier
+	}
+	return uint64(float64(estimated) * multiplier)
+}
+
+func capFee(fee, max *big.Int) *big.Int {
+	if max != nil && fee.Cmp(max) > 0 {
+		return new(big.Int).Set(max)
+	}
+	return fee
+}
@@ -0,0 +1,76 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	etypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/go-did/types"
+)
+
+// ReadMetadata reads the document metadata of identifier from the logs of contract,
+// the identifier should be the first indexed argument of the events. The time of
+// the first log is created, and the time and block of the last log are updated and versionId.
+func ReadMetadata(ctx context.Context, backend bind.ContractBackend, contract common.Address, identifier string) (types.DocumentMetadata, error) {
+	return ReadMetadataAt(ctx, backend, contract, identifier, nil)
+}
+
+// ReadMetadataAt reads the document metadata of identifier at block, the logs after
+// block are the next update. It is the same as ReadMetadata if block is nil.
+func ReadMetadataAt(ctx context.Context, backend bind.ContractBackend, contract common.Address, identifier string, block *big.Int) (types.DocumentMetadata, error) {
+	var metadata types.DocumentMetadata
+
+	logs, err := backend.FilterLogs(ctx, ethereum.FilterQuery{
+		Addresses: []common.Address{contract},
+		// indexed string is saved as its hash in topics
+		Topics: [][]common.Hash{nil, {crypto.Keccak256Hash([]byte(identifier))}},
+	})
+	if err != nil {
+		return metadata, err
+	}
+
+	var next []etypes.Log
+	if block != nil {
+		for i, log := range logs {
+			if log.BlockNumber > block.Uint64() {
+				logs, next = logs[:i], logs[i:]
+				break
+			}
+		}
+	}
+
+	if len(next) > 0 {
+		nextUpdate, err := BlockTime(ctx, backend, new(big.Int).SetUint64(next[0].BlockNumber))
+		if err != nil {
+			return metadata, err
+		}
+		metadata.NextUpdate = types.FormatTime(nextUpdate)
+		metadata.NextVersionID = strconv.FormatUint(next[0].BlockNumber, 10)
+	}
+	if len(logs) == 0 {
+		return metadata, nil
+	}
+
+	first, last := logs[0], logs[len(logs)-1]
+	created, err := BlockTime(ctx, backend, new(big.Int).SetUint64(first.BlockNumber))
+	if err != nil {
+		return metadata, err
+	}
+	metadata.Created = types.FormatTime(created)
+	metadata.VersionID = strconv.FormatUint(last.BlockNumber, 10)
+
+	if last.BlockNumber != first.BlockNumber {
+		updated, err := BlockTime(ctx, backend, new(big.Int).SetUint64(last.BlockNumber))
+		if err != nil {
+			return metadata, err
+		}
+		metadata.Updated = types.FormatTime(updated)
+	}
+
+	return metadata, nil
+}
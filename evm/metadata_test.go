@@ -0,0 +1,122 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/go-did/types"
+)
+
+// deployLogger deploys a contract which emits a log indexed by the 32 bytes of calldata
+func (c *testChain) deployLogger(t *testing.T) common.Address {
+	// log2(0, 0, 1, calldataload(0))
+	runtime := []byte{0x60, 0x00, 0x35, 0x60, 0x01, 0x60, 0x00, 0x60, 0x00, 0xa2, 0x00}
+	// codecopy(0, 12, len); return(0, len)
+	code := []byte{0x60, byte(len(runtime)), 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, byte(len(runtime)), 0x60, 0x00, 0xf3}
+	return c.deploy(t, append(code, runtime...))
+}
+
+func TestReadMetadata(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+	addr := chain.deployLogger(t)
+
+	metadata, err := ReadMetadata(context.TODO(), chain, addr, "did")
+	if err != nil || metadata != (types.DocumentMetadata{}) {
+		t.Fatal(metadata, err)
+	}
+
+	chain.send(t, &addr, 100000, crypto.Keccak256([]byte("did")))
+	chain.Commit()
+	created, err := chain.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err = ReadMetadata(context.TODO(), chain, addr, "did")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Created != types.FormatTime(time.Unix(int64(created.Time), 0)) || metadata.Updated != "" ||
+		metadata.VersionID != created.Number.String() {
+		t.Fatal(metadata)
+	}
+
+	chain.send(t, &addr, 100000, crypto.Keccak256([]byte("did")))
+	chain.send(t, &addr, 100000, crypto.Keccak256([]byte("other")))
+	chain.Commit()
+	updated, err := chain.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.send(t, &addr, 100000, crypto.Keccak256([]byte("other")))
+	chain.Commit()
+
+	metadata, err = ReadMetadata(context.TODO(), chain, addr, "did")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Created != types.FormatTime(time.Unix(int64(created.Time), 0)) ||
+		metadata.Updated != types.FormatTime(time.Unix(int64(updated.Time), 0)) ||
+		metadata.VersionID != strconv.FormatUint(updated.Number.Uint64(), 10) {
+		t.Fatal(metadata)
+	}
+}
+
+func TestVersionBlock(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+	addr := chain.deployLogger(t)
+
+	chain.send(t, &addr, 100000, crypto.Keccak256([]byte("did")))
+	chain.Commit()
+	first, err := chain.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.Commit()
+	chain.send(t, &addr, 100000, crypto.Keccak256([]byte("did")))
+	chain.Commit()
+	head, err := chain.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := VersionBlock(context.TODO(), chain, nil)
+	if err != nil || block != nil {
+		t.Fatal(block, err)
+	}
+	block, err = VersionBlock(context.TODO(), chain, &types.Version{ID: first.Number})
+	if err != nil || block.Cmp(first.Number) != 0 {
+		t.Fatal(block, err)
+	}
+	_, err = VersionBlock(context.TODO(), chain, &types.Version{ID: new(big.Int).Add(head.Number, big.NewInt(1))})
+	if !errors.Is(err, ErrVersionNotFound) {
+		t.Fatal(err)
+	}
+
+	// the last block mined at or before the time
+	block, err = VersionBlock(context.TODO(), chain, &types.Version{Time: time.Unix(int64(first.Time)+1, 0)})
+	if err != nil || block.Cmp(first.Number) != 0 {
+		t.Fatal(block, err)
+	}
+	block, err = VersionBlock(context.TODO(), chain, &types.Version{Time: time.Unix(int64(head.Time)+100, 0)})
+	if err != nil || block.Cmp(head.Number) != 0 {
+		t.Fatal(block, err)
+	}
+
+	metadata, err := ReadMetadataAt(context.TODO(), chain, addr, "did", first.Number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.VersionID != first.Number.String() || metadata.Updated != "" ||
+		metadata.NextVersionID != head.Number.String() || metadata.NextUpdate != types.FormatTime(time.Unix(int64(head.Time), 0)) {
+		t.Fatal(metadata)
+	}
+}
@@ -0,0 +1,48 @@
+package evm
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Options configures the connections of controllers, resolvers and proof instances,
+// and the transactions sent by them
+type Options struct {
+	// Fee decides the gas limit and fees of transactions
+	Fee FeePolicy
+	// Confirmations is the number of blocks to wait for after transaction is packaged
+	Confirmations uint64
+	// Timeout of waiting for transaction, DefaultTimeout is used if it is 0
+	Timeout time.Duration
+	// Pool shares connections among instances, each instance dials its own if it is nil
+	Pool *Pool
+}
+
+// GetOptions returns the last of opts, or the default options if opts is empty
+func GetOptions(opts ...Options) Options {
+	if len(opts) == 0 {
+		return Options{}
+	}
+	return opts[len(opts)-1]
+}
+
+// NewWaiter creates a waiter on backend configured by o
+func (o Options) NewWaiter(backend Backend, abis ...*abi.ABI) *Waiter {
+	w := NewWaiter(backend, abis...)
+	w.Confirmations = o.Confirmations
+	if o.Timeout > 0 {
+		w.Timeout = o.Timeout
+	}
+	return w
+}
+
+// Dial returns a client of endpoint from the pool of o, or dials a new one
+// if o has no pool
+func (o Options) Dial(ctx context.Context, endpoint string) (*Client, error) {
+	if o.Pool != nil {
+		return o.Pool.Get(ctx, endpoint)
+	}
+	return DialClient(ctx, endpoint, DefaultBackoff)
+}
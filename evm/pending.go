@@ -0,0 +1,230 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"golang.org/x/xerrors"
+)
+
+// TxStatus is the status of a pending transaction
+type TxStatus int
+
+const (
+	// TxPending means the transaction is not packaged yet
+	TxPending TxStatus = iota
+	// TxSucceeded means the transaction is packaged and executed successfully
+	TxSucceeded
+	// TxFailed means the transaction is packaged but execution failed
+	TxFailed
+)
+
+func (s TxStatus) String() string {
+	switch s {
+	case TxPending:
+		return "pending"
+	case TxSucceeded:
+		return "succeeded"
+	case TxFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// PendingTx is a handle of transaction which is sent but maybe not packaged
+type PendingTx struct {
+	tx     *types.Transaction
+	name   string
+	waiter *Waiter
+	// sender reads the nonce again if the transaction is not mined, nil if it is not sent by a Sender
+	sender *Sender
+
+	lk      sync.Mutex
+	done    bool
+	receipt *types.Receipt
+	err     error
+}
+
+// NewPendingTx creates a handle of tx, which is waited by waiter
+func NewPendingTx(waiter *Waiter, tx *types.Transaction, name string) *PendingTx {
+	return &PendingTx{
+		tx:     tx,
+		name:   name,
+		waiter: waiter,
+	}
+}
+
+// Hash returns the hash of transaction
+func (p *PendingTx) Hash() common.Hash {
+	return p.tx.Hash()
+}
+
+// Tx returns the transaction
+func (p *PendingTx) Tx() *types.Transaction {
+	return p.tx
+}
+
+// Wait waits for the transaction to be packaged, the result is saved
+// unless waiting is stopped by ctx, so it can be called many times.
+// If the transaction is sent by a Sender and is not packaged before timeout,
+// the sender reads the nonce from the pending state again at next sending,
+// so that a transaction dropped by the node does not leave a nonce gap.
+func (p *PendingTx) Wait(ctx context.Context) (*types.Receipt, error) {
+	p.lk.Lock()
+	if p.done {
+		p.lk.Unlock()
+		return p.receipt, p.err
+	}
+	p.lk.Unlock()
+
+	receipt, err := p.waiter.Wait(ctx, p.tx, p.name)
+	if _, ok := err.(*NotMinedError); ok {
+		if p.sender != nil {
+			p.sender.resync()
+		}
+		return nil, err
+	}
+
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	p.done = true
+	p.receipt = receipt
+	p.err = err
+	return receipt, err
+}
+
+// Check waits for the transaction and returns nil if it is executed successfully
+func (p *PendingTx) Check(ctx context.Context) error {
+	_, err := p.Wait(ctx)
+	return err
+}
+
+// Receipt returns the receipt of transaction without waiting, nil if it is not packaged
+func (p *PendingTx) Receipt(ctx context.Context) *types.Receipt {
+	p.lk.Lock()
+	done, receipt := p.done, p.receipt
+	p.lk.Unlock()
+	if done {
+		return receipt
+	}
+
+	receipt, err := p.waiter.backend.TransactionReceipt(ctx, p.tx.Hash())
+	if err != nil {
+		return nil
+	}
+	return receipt
+}
+
+// Status returns the status of transaction without waiting
+func (p *PendingTx) Status(ctx context.Context) TxStatus {
+	receipt := p.Receipt(ctx)
+	if receipt == nil {
+		return TxPending
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return TxFailed
+	}
+	return TxSucceeded
+}
+
+// Sender sends transactions of one account with consecutive nonces,
+// so that many of them can be in flight at the same time.
+type Sender struct {
+	opts   *bind.TransactOpts
+	waiter *Waiter
+	fee    FeePolicy
+
+	lk     sync.Mutex
+	synced bool
+	nonce  uint64
+}
+
+// NewSender creates a sender which signs transactions with opts,
+// pays for them as fee and waits for them with waiter
+func NewSender(opts *bind.TransactOpts, waiter *Waiter, fee FeePolicy) *Sender {
+	return &Sender{
+		opts:   opts,
+		waiter: waiter,
+		fee:    fee,
+	}
+}
+
+// From returns the address of account
+func (s *Sender) From() common.Address {
+	return s.opts.From
+}
+
+// Waiter returns the waiter of transactions sent by s
+func (s *Sender) Waiter() *Waiter {
+	return s.waiter
+}
+
+// ForAccount returns a sender of the account of opts, which shares the waiter and fee policy of s,
+// such as the sender of a rotated key
+func (s *Sender) ForAccount(opts *bind.TransactOpts) *Sender {
+	return NewSender(opts, s.waiter, s.fee)
+}
+
+// Send calls fn with transact opts bound to ctx and the next nonce of account,
+// fn should send one transaction with opts. If fn fails, the nonce is read from
+// the pending state of backend again at next sending.
+//
+// Gas and fees are set by the fee policy of s, or the one of ctx set by WithFee.
+// To estimate gas, fn is called with opts.NoSend set at first.
+func (s *Sender) Send(ctx context.Context, name string, fn func(opts *bind.TransactOpts) (*types.Transaction, error)) (*PendingTx, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if !s.synced {
+		nonce, err := s.waiter.backend.PendingNonceAt(ctx, s.opts.From)
+		if err != nil {
+			return nil, err
+		}
+		s.nonce = nonce
+		s.synced = true
+	}
+
+	opts := *s.opts
+	opts.Context = ctx
+	opts.Nonce = new(big.Int).SetUint64(s.nonce)
+
+	fee := feeFromContext(ctx, s.fee)
+	err := fee.apply(ctx, s.waiter.backend, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.GasLimit == 0 {
+		// gas is estimated by binding if limit is not set
+		dryRun := opts
+		dryRun.NoSend = true
+		tx, err := fn(&dryRun)
+		if err != nil {
+			return nil, xerrors.Errorf("%s: %w", name, err)
+		}
+		opts.GasLimit = fee.gasLimit(tx.Gas())
+	}
+
+	tx, err := fn(&opts)
+	if err != nil {
+		s.synced = false
+		return nil, err
+	}
+	s.nonce++
+
+	pending := NewPendingTx(s.waiter, tx, name)
+	pending.sender = s
+	return pending, nil
+}
+
+// resync makes the nonce read from the pending state of backend again at next sending
+func (s *Sender) resync() {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.synced = false
+}
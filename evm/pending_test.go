@@ -0,0 +1,153 @@
+package evm
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestSender(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+
+	opts, err := bind.NewKeyedTransactorWithChainID(chain.key, params.AllEthashProtocolChanges.ChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := NewSender(opts, NewWaiter(chain), FeePolicy{})
+
+	contract := bind.NewBoundContract(chain.deployStop(t), abi.ABI{}, chain, chain, chain)
+	transfer := func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return contract.Transfer(opts)
+	}
+
+	// a failed sending does not leave a nonce gap
+	_, err = sender.Send(context.TODO(), "Fail", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return nil, errors.New("fail")
+	})
+	if err == nil {
+		t.Fatal("should return error of fn")
+	}
+
+	var wg sync.WaitGroup
+	txs := make([]*PendingTx, 5)
+	for i := range txs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			txs[i], err = sender.Send(context.TODO(), "Transfer", transfer)
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	for _, tx := range txs {
+		if tx.Status(context.TODO()) != TxPending || tx.Receipt(context.TODO()) != nil {
+			t.Fatal("should be pending before commit")
+		}
+	}
+
+	chain.Commit()
+
+	for _, tx := range txs {
+		receipt, err := tx.Wait(context.TODO())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if receipt.TxHash != tx.Hash() || tx.Status(context.TODO()) != TxSucceeded {
+			t.Fatal("unexpected result of transaction")
+		}
+		// estimated gas with default multiplier
+		if tx.Tx().Gas() != 25200 {
+			t.Fatalf("unexpected gas limit %d", tx.Tx().Gas())
+		}
+	}
+
+	// a transaction dropped by the node does not leave a nonce gap after waiting for it times out
+	dropped, err := sender.Send(context.TODO(), "Dropped", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		opts.NoSend = true
+		return contract.Transfer(opts)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	_, err = dropped.Wait(ctx)
+	var notMined *NotMinedError
+	if !errors.As(err, &notMined) {
+		t.Fatalf("dropped transaction should not be mined: %v", err)
+	}
+	next, err := sender.Send(context.TODO(), "Transfer", transfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Tx().Nonce() != dropped.Tx().Nonce() {
+		t.Fatalf("nonce %d should be reused after transaction %d is dropped", next.Tx().Nonce(), dropped.Tx().Nonce())
+	}
+	chain.Commit()
+	if err := next.Check(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFeePolicy(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+
+	head, err := chain.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var opts bind.TransactOpts
+	err = FeePolicy{GasTipCap: big.NewInt(1)}.apply(context.TODO(), chain, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.GasPrice != nil || opts.GasTipCap.Int64() != 1 || opts.GasFeeCap.Cmp(new(big.Int).Add(big.NewInt(1), new(big.Int).Mul(head.BaseFee, big.NewInt(2)))) != 0 {
+		t.Fatal("unexpected fees of dynamic-fee transaction")
+	}
+
+	maxFee := new(big.Int).Add(head.BaseFee, big.NewInt(1))
+	err = FeePolicy{GasTipCap: big.NewInt(10), MaxFee: maxFee}.apply(context.TODO(), chain, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.GasFeeCap.Cmp(maxFee) != 0 || opts.GasTipCap.Int64() != 10 {
+		t.Fatal("fee cap should be capped by max fee")
+	}
+
+	err = FeePolicy{MaxFee: big.NewInt(1)}.apply(context.TODO(), chain, &opts)
+	if err == nil {
+		t.Fatal("should report an error when max fee is lower than base fee")
+	}
+
+	err = FeePolicy{Legacy: true, GasPrice: big.NewInt(1e10), MaxFee: big.NewInt(1e9), GasLimit: 50000}.apply(context.TODO(), chain, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.GasFeeCap != nil || opts.GasPrice.Int64() != 1e9 || opts.GasLimit != 50000 {
+		t.Fatal("unexpected fees of legacy transaction")
+	}
+
+	// per-call override
+	fee := feeFromContext(WithFee(context.TODO(), FeePolicy{GasLimit: 1}), FeePolicy{GasLimit: 2})
+	if fee.GasLimit != 1 {
+		t.Fatal("fee policy of context should override the default one")
+	}
+}
@@ -0,0 +1,121 @@
+package evm
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool shares one connection of each endpoint among controllers, resolvers and
+// proof instances created with it, the connection is closed after all of its
+// clients are closed.
+type Pool struct {
+	// Backoff of redialing broken connections, DefaultBackoff is used if it is zero
+	Backoff Backoff
+
+	lk    sync.Mutex
+	conns map[string]*conn
+	refs  map[string]int
+	// endpoints being dialed, the channel is closed when the dial is done
+	dials map[string]chan struct{}
+}
+
+// NewPool creates an empty pool
+func NewPool() *Pool {
+	return &Pool{
+		conns: make(map[string]*conn),
+		refs:  make(map[string]int),
+		dials: make(map[string]chan struct{}),
+	}
+}
+
+// Get returns a client of endpoint, which is dialed if pool has no connection
+// of endpoint. The client should be closed when it is not used. An endpoint is
+// dialed by one call at a time without holding the pool, the others wait for it.
+func (p *Pool) Get(ctx context.Context, endpoint string) (*Client, error) {
+	p.lk.Lock()
+	for {
+		if cn, ok := p.conns[endpoint]; ok {
+			defer p.lk.Unlock()
+			return p.client(endpoint, cn), nil
+		}
+		done, ok := p.dials[endpoint]
+		if !ok {
+			break
+		}
+		p.lk.Unlock()
+		// the connection is dialed again if the dial fails, such as its context is canceled
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-done:
+		}
+		p.lk.Lock()
+	}
+	if p.conns == nil {
+		p.conns = make(map[string]*conn)
+		p.refs = make(map[string]int)
+	}
+	if p.dials == nil {
+		p.dials = make(map[string]chan struct{})
+	}
+	done := make(chan struct{})
+	p.dials[endpoint] = done
+	p.lk.Unlock()
+
+	cn, err := dialConn(ctx, endpoint, p.Backoff.orDefault())
+
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	delete(p.dials, endpoint)
+	close(done)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[endpoint] = cn
+	return p.client(endpoint, cn), nil
+}
+
+// client returns a new client of cn, it is called with lk held
+func (p *Pool) client(endpoint string, cn *conn) *Client {
+	p.refs[endpoint]++
+	return &Client{
+		conn:    cn,
+		release: func() { p.put(endpoint, cn) },
+	}
+}
+
+// Len returns the number of connections in pool
+func (p *Pool) Len() int {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	return len(p.conns)
+}
+
+func (p *Pool) put(endpoint string, cn *conn) {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	if p.conns[endpoint] != cn {
+		// closed by pool already
+		return
+	}
+	p.refs[endpoint]--
+	if p.refs[endpoint] > 0 {
+		return
+	}
+	delete(p.conns, endpoint)
+	delete(p.refs, endpoint)
+	cn.close()
+}
+
+// Close closes all connections of pool, even if their clients are not closed
+func (p *Pool) Close() {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+
+	for endpoint, cn := range p.conns {
+		cn.close()
+		delete(p.conns, endpoint)
+		delete(p.refs, endpoint)
+	}
+}
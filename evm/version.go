@@ -0,0 +1,88 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// ErrVersionNotFound is returned if the version is after the latest block or before the genesis
+var ErrVersionNotFound = xerrors.New("version not found")
+
+// VersionBlock returns the number of the block that version refers to,
+// or nil which means the latest block if version is nil.
+// Reading the state of history blocks needs an archive node.
+func VersionBlock(ctx context.Context, backend bind.ContractBackend, version *types.Version) (*big.Int, error) {
+	if version == nil || (version.ID == nil && version.Time.IsZero()) {
+		return nil, nil
+	}
+
+	head, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if version.ID != nil {
+		if version.ID.Cmp(head.Number) > 0 {
+			return nil, xerrors.Errorf("block %s is after the latest block %s: %w", version.ID, head.Number, ErrVersionNotFound)
+		}
+		return new(big.Int).Set(version.ID), nil
+	}
+
+	if version.Time.Unix() < 0 {
+		return nil, xerrors.Errorf("%s is before the genesis block: %w", version.Time, ErrVersionNotFound)
+	}
+
+	// binary search the last block mined at or before version time
+	target := uint64(version.Time.Unix())
+	if head.Time <= target {
+		return head.Number, nil
+	}
+	lo, hi := uint64(0), head.Number.Uint64()
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		header, err := backend.HeaderByNumber(ctx, new(big.Int).SetUint64(mid))
+		if err != nil {
+			return nil, err
+		}
+		if header.Time <= target {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	if lo == 0 {
+		genesis, err := backend.HeaderByNumber(ctx, big.NewInt(0))
+		if err != nil {
+			return nil, err
+		}
+		if genesis.Time > target {
+			return nil, xerrors.Errorf("%s is before the genesis block: %w", version.Time, ErrVersionNotFound)
+		}
+	}
+	return new(big.Int).SetUint64(lo), nil
+}
+
+// BlockTime returns the time of block, the latest block is used if number is nil
+func BlockTime(ctx context.Context, backend bind.ContractBackend, number *big.Int) (time.Time, error) {
+	head, err := backend.HeaderByNumber(ctx, number)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(head.Time), 0), nil
+}
+
+// FilterOpts filters the events up to block, or all events if block is nil
+func FilterOpts(ctx context.Context, block *big.Int) *bind.FilterOpts {
+	opts := &bind.FilterOpts{Context: ctx}
+	if block != nil {
+		end := block.Uint64()
+		opts.End = &end
+	}
+	return opts
+}
@@ -0,0 +1,215 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// DefaultTimeout is how long a waiter waits for transaction to be packaged
+	DefaultTimeout = 3 * time.Minute
+	// pollInterval is used to check new blocks when backend cannot subscribe new heads
+	pollInterval = time.Second
+)
+
+// Waiter waits for transactions to be packaged and checks their results.
+// The error returned is *NotMinedError, *OutOfGasError or *RevertError,
+// which can be inspected with errors.As.
+type Waiter struct {
+	backend Backend
+	abis    []*abi.ABI
+
+	// Confirmations is the number of blocks after the block of transaction to wait for
+	Confirmations uint64
+	// Timeout of waiting, 0 means waiting until ctx is done
+	Timeout time.Duration
+}
+
+// NewWaiter creates a waiter on backend, custom errors are decoded with abis
+func NewWaiter(backend Backend, abis ...*abi.ABI) *Waiter {
+	return &Waiter{
+		backend: backend,
+		abis:    abis,
+		Timeout: DefaultTimeout,
+	}
+}
+
+// ABIs parses the abi of contract bindings, invalid ones are skipped
+func ABIs(metas ...*bind.MetaData) []*abi.ABI {
+	var abis []*abi.ABI
+	for _, meta := range metas {
+		a, err := meta.GetAbi()
+		if err == nil {
+			abis = append(abis, a)
+		}
+	}
+	return abis
+}
+
+// Check waits for tx and returns nil if it is executed successfully
+func (w *Waiter) Check(ctx context.Context, tx *types.Transaction, name string) error {
+	_, err := w.Wait(ctx, tx, name)
+	return err
+}
+
+// Wait waits for tx to be packaged and confirmed, the receipt is returned with
+// *OutOfGasError and *RevertError as well
+func (w *Waiter) Wait(ctx context.Context, tx *types.Transaction, name string) (*types.Receipt, error) {
+	if w.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.Timeout)
+		defer cancel()
+	}
+
+	receipt, err := bind.WaitMined(ctx, w.backend, tx)
+	if err != nil {
+		return nil, &NotMinedError{Name: name, TxHash: tx.Hash(), Err: err}
+	}
+
+	receipt, err = w.confirm(ctx, receipt)
+	if err != nil {
+		return nil, &NotMinedError{Name: name, TxHash: tx.Hash(), Err: err}
+	}
+
+	if receipt.Status == types.ReceiptStatusFailed {
+		if receipt.GasUsed >= tx.Gas() {
+			return receipt, &OutOfGasError{Name: name, TxHash: tx.Hash(), GasLimit: tx.Gas(), GasUsed: receipt.GasUsed}
+		}
+		return receipt, w.revertError(ctx, tx, receipt, name)
+	}
+
+	return receipt, nil
+}
+
+type headSubscriber interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// confirm waits for w.Confirmations blocks on top of the block of receipt,
+// the receipt is read again in case that the block is reorganized
+func (w *Waiter) confirm(ctx context.Context, receipt *types.Receipt) (*types.Receipt, error) {
+	if w.Confirmations == 0 {
+		return receipt, nil
+	}
+
+	var heads chan *types.Header
+	var subErr <-chan error
+	if subscriber, ok := w.backend.(headSubscriber); ok {
+		heads = make(chan *types.Header, 16)
+		sub, err := subscriber.SubscribeNewHead(ctx, heads)
+		if err == nil {
+			defer sub.Unsubscribe()
+			subErr = sub.Err()
+		} else {
+			heads = nil
+		}
+	}
+
+	var tick <-chan time.Time
+	if heads == nil {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		head, err := w.backend.HeaderByNumber(ctx, nil)
+		if err == nil {
+			target := new(big.Int).Add(receipt.BlockNumber, new(big.Int).SetUint64(w.Confirmations))
+			if head.Number.Cmp(target) >= 0 {
+				latest, err := w.backend.TransactionReceipt(ctx, receipt.TxHash)
+				if err == nil && latest.BlockHash == receipt.BlockHash {
+					return latest, nil
+				}
+				if err == nil {
+					// reorganized into another block, wait for it again
+					receipt = latest
+					continue
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-heads:
+		case <-subErr:
+			// subscription is broken, fall back to polling
+			heads, subErr = nil, nil
+			ticker := time.NewTicker(pollInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		case <-tick:
+		}
+	}
+}
+
+// revertError replays tx on the state before the block of receipt to read and decode its revert
+// reason. The latest state is replayed if the backend has no state of that block, such as a simulated
+// backend, and the reason may differ from the real failure then.
+func (w *Waiter) revertError(ctx context.Context, tx *types.Transaction, receipt *types.Receipt, name string) error {
+	rerr := &RevertError{Name: name, TxHash: tx.Hash()}
+
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return rerr
+	}
+
+	msg := ethereum.CallMsg{
+		From:  from,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		msg.GasFeeCap = tx.GasFeeCap()
+		msg.GasTipCap = tx.GasTipCap()
+	} else {
+		msg.GasPrice = tx.GasPrice()
+	}
+
+	var block *big.Int
+	if receipt.BlockNumber != nil && receipt.BlockNumber.Sign() > 0 {
+		block = new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+		// calling an account without code succeeds if the state of block is available
+		if _, err := w.backend.CallContract(ctx, ethereum.CallMsg{From: from, To: &from}, block); err != nil {
+			block = nil
+		}
+	}
+	_, err = w.backend.CallContract(ctx, msg, block)
+	if err == nil {
+		// succeeds on the state replayed, the reason is unknown
+		return rerr
+	}
+
+	rerr.Data = revertData(err)
+	rerr.Reason, rerr.ErrorName, rerr.Args = DecodeRevert(rerr.Data, w.abis...)
+	if rerr.Reason == "" {
+		rerr.Reason = err.Error()
+	}
+	return rerr
+}
+
+// CheckTx dials endpoint and waits for the transaction of txHash
+func CheckTx(ctx context.Context, endpoint string, txHash common.Hash, name string, abis ...*abi.ABI) error {
+	client, _, err := Dial(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	tx, _, err := client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return &NotMinedError{Name: name, TxHash: txHash, Err: err}
+	}
+
+	return NewWaiter(client, abis...).Check(ctx, tx, name)
+}
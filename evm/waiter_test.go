@@ -0,0 +1,228 @@
+package evm
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const deniedABI = `[{"inputs":[{"internalType":"uint256","name":"code","type":"uint256"}],"name":"Denied","type":"error"}]`
+
+type testChain struct {
+	*backends.SimulatedBackend
+	key   *ecdsa.PrivateKey
+	nonce uint64
+}
+
+func newTestChain(t *testing.T) *testChain {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alloc := core.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(1e18)}}
+	return &testChain{SimulatedBackend: backends.NewSimulatedBackend(alloc, 30000000), key: key}
+}
+
+func (c *testChain) send(t *testing.T, to *common.Address, gas uint64, data []byte) *types.Transaction {
+	var tx *types.Transaction
+	if to == nil {
+		tx = types.NewContractCreation(c.nonce, big.NewInt(0), gas, big.NewInt(1e9), data)
+	} else {
+		tx = types.NewTransaction(c.nonce, *to, big.NewInt(0), gas, big.NewInt(1e9), data)
+	}
+	tx, err := types.SignTx(tx, types.LatestSignerForChainID(params.AllEthashProtocolChanges.ChainID), c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SendTransaction(context.TODO(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.nonce++
+	return tx
+}
+
+// deployStop deploys a contract which accepts any call
+func (c *testChain) deployStop(t *testing.T) common.Address {
+	// codecopy(0, 12, 1); return(0, 1)
+	return c.deploy(t, []byte{0x60, 0x01, 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, 0x01, 0x60, 0x00, 0xf3, 0x00})
+}
+
+func (c *testChain) deploy(t *testing.T, code []byte) common.Address {
+	tx := c.send(t, nil, 200000, code)
+	c.Commit()
+	receipt, err := c.TransactionReceipt(context.TODO(), tx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatal("deploy failed", err)
+	}
+	return receipt.ContractAddress
+}
+
+// deployDenied deploys a contract which always reverts with Denied(7)
+func (c *testChain) deployDenied(t *testing.T) common.Address {
+	sel := crypto.Keccak256([]byte("Denied(uint256)"))[:4]
+	// mstore(0, sel << 224); mstore(4, 7); revert(0, 36)
+	runtime := append([]byte{0x63}, sel...)
+	runtime = append(runtime, 0x60, 0xe0, 0x1b, 0x60, 0x00, 0x52, 0x60, 0x07, 0x60, 0x04, 0x52, 0x60, 0x24, 0x60, 0x00, 0xfd)
+	// codecopy(0, 12, len); return(0, len)
+	code := []byte{0x60, byte(len(runtime)), 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, byte(len(runtime)), 0x60, 0x00, 0xf3}
+	code = append(code, runtime...)
+
+	return c.deploy(t, code)
+}
+
+func TestWaitRevert(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+	addr := chain.deployDenied(t)
+
+	a, err := abi.JSON(strings.NewReader(deniedABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiter := NewWaiter(chain, &a)
+
+	tx := chain.send(t, &addr, 100000, nil)
+	chain.Commit()
+
+	_, err = waiter.Wait(context.TODO(), tx, "Denied")
+	var rerr *RevertError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("should return RevertError, got %v", err)
+	}
+	if rerr.ErrorName != "Denied" || rerr.Reason != "Denied(7)" {
+		t.Fatalf("unexpected revert error %v", rerr)
+	}
+
+	tx = chain.send(t, &addr, 21010, nil)
+	chain.Commit()
+
+	_, err = waiter.Wait(context.TODO(), tx, "OutOfGas")
+	var oerr *OutOfGasError
+	if !errors.As(err, &oerr) {
+		t.Fatalf("should return OutOfGasError, got %v", err)
+	}
+}
+
+// archiveChain pretends to read the state of any block, and records the blocks where contract is called
+type archiveChain struct {
+	*testChain
+	contract common.Address
+	calls    []*big.Int
+}
+
+func (c *archiveChain) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if call.To != nil && *call.To == c.contract {
+		c.calls = append(c.calls, blockNumber)
+	}
+	return c.testChain.CallContract(ctx, call, nil)
+}
+
+func TestWaitRevertAtBlock(t *testing.T) {
+	chain := &archiveChain{testChain: newTestChain(t)}
+	defer chain.Close()
+	addr := chain.deployDenied(t)
+	chain.contract = addr
+
+	a, err := abi.JSON(strings.NewReader(deniedABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := chain.send(t, &addr, 100000, nil)
+	chain.Commit()
+
+	receipt, err := NewWaiter(chain, &a).Wait(context.TODO(), tx, "Denied")
+	var rerr *RevertError
+	if !errors.As(err, &rerr) || rerr.ErrorName != "Denied" {
+		t.Fatalf("should return RevertError, got %v", err)
+	}
+	// the transaction is replayed on the state before its block
+	parent := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	if len(chain.calls) != 1 || chain.calls[0] == nil || chain.calls[0].Cmp(parent) != 0 {
+		t.Fatalf("transaction of block %s is replayed at %v", receipt.BlockNumber, chain.calls)
+	}
+}
+
+func TestWaitNotMined(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+
+	tx := chain.send(t, &common.Address{}, 21000, nil)
+
+	waiter := NewWaiter(chain)
+	waiter.Timeout = 100 * time.Millisecond
+	_, err := waiter.Wait(context.TODO(), tx, "Transfer")
+	var nerr *NotMinedError
+	if !errors.As(err, &nerr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("should return NotMinedError, got %v", err)
+	}
+}
+
+func TestWaitConfirmations(t *testing.T) {
+	chain := newTestChain(t)
+	defer chain.Close()
+
+	tx := chain.send(t, &common.Address{}, 21000, nil)
+	chain.Commit()
+
+	waiter := NewWaiter(chain)
+	waiter.Confirmations = 2
+
+	done := make(chan error)
+	go func() {
+		_, err := waiter.Wait(context.TODO(), tx, "Transfer")
+		done <- err
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			t.Fatalf("returned before confirmed: %v", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+		chain.Commit()
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("not returned after confirmed")
+	}
+}
+
+func TestDecodeRevert(t *testing.T) {
+	data := append([]byte{0x08, 0xc3, 0x79, 0xa0}, common.LeftPadBytes([]byte{0x20}, 32)...)
+	data = append(data, common.LeftPadBytes([]byte{0x02}, 32)...)
+	data = append(data, common.RightPadBytes([]byte("no"), 32)...)
+	reason, name, _ := DecodeRevert(data)
+	if reason != "no" || name != "Error" {
+		t.Fatalf("unexpected reason %s %s", name, reason)
+	}
+
+	data = append([]byte{0x4e, 0x48, 0x7b, 0x71}, common.LeftPadBytes([]byte{0x11}, 32)...)
+	reason, _, _ = DecodeRevert(data)
+	if reason != "panic(0x11)" {
+		t.Fatalf("unexpected reason %s", reason)
+	}
+
+	reason, name, _ = DecodeRevert([]byte{1, 2, 3, 4})
+	if reason != "0x01020304" || name != "" {
+		t.Fatalf("unexpected reason %s %s", name, reason)
+	}
+}
@@ -0,0 +1,115 @@
+package evm
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Watcher calls a function with the logs of contracts mined after it is created.
+// Logs are subscribed if backend supports, otherwise they are polled. A broken
+// subscription falls back to polling from the block of the last log, so some
+// logs may be passed twice.
+type Watcher struct {
+	backend  bind.ContractBackend
+	query    ethereum.FilterQuery
+	interval time.Duration
+	fn       func(log types.Log)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatcher starts watching the logs of contracts, logs are polled every
+// interval if they cannot be subscribed, pollInterval is used if it is 0.
+func NewWatcher(backend bind.ContractBackend, contracts []common.Address, interval time.Duration, fn func(log types.Log)) (*Watcher, error) {
+	if interval <= 0 {
+		interval = pollInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Watcher{
+		backend:  backend,
+		query:    ethereum.FilterQuery{Addresses: contracts},
+		interval: interval,
+		fn:       fn,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+
+	head, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	from := head.Number.Uint64() + 1
+
+	logs := make(chan types.Log, 64)
+	sub, err := backend.SubscribeFilterLogs(ctx, w.query, logs)
+	go func() {
+		defer close(w.done)
+		if err == nil {
+			from = w.drain(ctx, sub, logs, from)
+		}
+		w.poll(ctx, from)
+	}()
+
+	return w, nil
+}
+
+// Close stops watching and waits for the function to return
+func (w *Watcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+// drain passes the logs of subscription to fn until subscription is broken,
+// it returns the block to poll from.
+func (w *Watcher) drain(ctx context.Context, sub ethereum.Subscription, logs <-chan types.Log, from uint64) uint64 {
+	defer sub.Unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return from
+		case <-sub.Err():
+			return from
+		case log := <-logs:
+			w.fn(log)
+			from = log.BlockNumber
+		}
+	}
+}
+
+// poll passes the logs of new blocks to fn every interval until ctx is done
+func (w *Watcher) poll(ctx context.Context, from uint64) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		head, err := w.backend.HeaderByNumber(ctx, nil)
+		if err != nil || head.Number.Uint64() < from {
+			continue
+		}
+		query := w.query
+		query.FromBlock = new(big.Int).SetUint64(from)
+		query.ToBlock = head.Number
+		logs, err := w.backend.FilterLogs(ctx, query)
+		if err != nil {
+			continue
+		}
+		for _, log := range logs {
+			w.fn(log)
+		}
+		from = head.Number.Uint64() + 1
+	}
+}
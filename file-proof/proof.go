@@ -3,9 +3,7 @@ package proof
 import (
 	"context"
 	"crypto/ecdsa"
-	"fmt"
 	"math/big"
-	"time"
 
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
@@ -14,7 +12,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/memoio/go-did/evm"
 	"golang.org/x/xerrors"
 
 	com "github.com/memoio/contractsv2/common"
@@ -24,475 +22,522 @@ import (
 	proxyfileproof "github.com/memoio/did-solidity/go-contracts/proxy-proof"
 )
 
-var (
-	checkTxSleepTime = 6
-	nextBlockTime    = 5
-)
-
 type ContractAddress struct {
 	PledgeAddr common.Address
 	ProofAddr common.Address
 	ProofControlAddr common.Address
 	ProofProxyAddr common.Address
+	// read from the instance contract by NewProofInstance if not set
+	TokenAddr common.Address
+	AuthAddr  common.Address
 }
 
 type ProofInstance struct {
-	endpoint            string
+	backend             evm.Backend
 	transactor          *bind.TransactOpts
+	sender              *evm.Sender
 	proofAddr           common.Address
 	proofProxyAddr      common.Address
 	proofControllerAddr common.Address
 	pledgeAddr          common.Address
 	tokenAddr           common.Address
 	authAddr            common.Address
+
+	// client dialed by instance, nil if backend is passed in
+	client *evm.Client
 }
 
-func NewProofInstance(privateKey *ecdsa.PrivateKey, chain string, addrs *ContractAddress) (*ProofInstance, error) {
+func NewProofInstance(privateKey *ecdsa.PrivateKey, chain string, addrs *ContractAddress, opts ...evm.Options) (*ProofInstance, error) {
+	return NewProofInstanceContext(context.Background(), privateKey, chain, addrs, opts...)
+}
+
+func NewProofInstanceContext(ctx context.Context, privateKey *ecdsa.PrivateKey, chain string, addrs *ContractAddress, opts ...evm.Options) (*ProofInstance, error) {
 	instanceAddr, endpoint := com.GetInsEndPointByChain(chain)
 
-	client, err := ethclient.DialContext(context.TODO(), endpoint)
+	client, err := evm.GetOptions(opts...).Dial(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
-
-	chainID, err := client.NetworkID(context.Background())
-	if err != nil {
-		chainID = big.NewInt(985)
-	}
 
 	// new instance
 	instanceIns, err := inst.NewInstance(instanceAddr, client)
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
 
-	// get token address
-	tokenAddr, err := instanceIns.Instances(&bind.CallOpts{}, com.TypeERC20)
-	if err != nil {
-		return nil, err
+	fullAddrs := *addrs
+	if fullAddrs.TokenAddr == (common.Address{}) {
+		// get token address
+		fullAddrs.TokenAddr, err = instanceIns.Instances(&bind.CallOpts{Context: ctx}, com.TypeERC20)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	if fullAddrs.AuthAddr == (common.Address{}) {
+		// get auth address
+		fullAddrs.AuthAddr, err = instanceIns.Instances(&bind.CallOpts{Context: ctx}, com.TypeAuth)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
 	}
 
-	// get auth address
-	authAddr, err := instanceIns.Instances(&bind.CallOpts{}, com.TypeAuth)
+	ins, err := NewProofInstanceWithBackend(privateKey, client, client.ChainID(), &fullAddrs, opts...)
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
+	ins.client = client
+	return ins, nil
+}
 
+// NewProofInstanceWithBackend creates a proof instance which sends transactions through backend,
+// such as a simulated backend, all contract address including TokenAddr and AuthAddr should be set.
+func NewProofInstanceWithBackend(privateKey *ecdsa.PrivateKey, backend evm.Backend, chainID *big.Int, addrs *ContractAddress, opts ...evm.Options) (*ProofInstance, error) {
 	// new auth
 	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
 	if err != nil {
 		return nil, err
 	}
-	auth.Value = big.NewInt(0)      // in wei
-	auth.GasLimit = uint64(3000000) // in units
-	// auth.GasPrice = big.NewInt(1000)
+	auth.Value = big.NewInt(0) // in wei
+	// gas limit and fees are decided by fee policy of options
+	o := evm.GetOptions(opts...)
 
 	return &ProofInstance{
-		endpoint:            endpoint,
+		backend:             backend,
 		transactor:          auth,
+		sender:              evm.NewSender(auth, o.NewWaiter(backend, evm.ABIs(proxyfileproof.ProxyProofMetaData)...), o.Fee),
 		proofAddr:           addrs.ProofAddr,
 		proofProxyAddr:      addrs.ProofProxyAddr,
 		proofControllerAddr: addrs.ProofControlAddr,
 		pledgeAddr:          addrs.PledgeAddr,
-		tokenAddr:           tokenAddr,
-		authAddr:            authAddr,
+		tokenAddr:           addrs.TokenAddr,
+		authAddr:            addrs.AuthAddr,
 	}, nil
 }
 
-func (ins *ProofInstance) AddFile(commit bls12381.G1Affine, size uint64, start *big.Int, end *big.Int, credential []byte) error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
+// Close closes the client dialed by instance, the backend passed to
+// NewProofInstanceWithBackend is not closed.
+func (ins *ProofInstance) Close() {
+	if ins.client != nil {
+		ins.client.Close()
+	}
+}
+
+// approve lets spender spend amount of the tokens of instance and waits for the approval to be mined,
+// since the transaction spending them is estimated and executed with the allowance on chain.
+func (ins *ProofInstance) approve(ctx context.Context, spender common.Address, amount *big.Int) error {
+	erc20Ins, err := erc.NewERC20(ins.tokenAddr, ins.backend)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
-
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+	tx, err := ins.sender.Send(ctx, "Approve", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return erc20Ins.Approve(opts, spender, amount)
+	})
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
+
+func (ins *ProofInstance) AddFile(commit bls12381.G1Affine, size uint64, start *big.Int, end *big.Int, credential []byte) error {
+	return ins.AddFileContext(context.Background(), commit, size, start, end, credential)
+}
 
-	erc20Ins, err := erc.NewERC20(ins.tokenAddr, client)
+func (ins *ProofInstance) AddFileContext(ctx context.Context, commit bls12381.G1Affine, size uint64, start *big.Int, end *big.Int, credential []byte) error {
+	tx, err := ins.AddFileAsync(ctx, commit, size, start, end, credential)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	setting, err := ins.GetSettingInfo()
+// AddFileAsync approves the fee of the file to the proof contract and sends AddFile. The approval is
+// mined before AddFile is sent, so it blocks until then and only AddFile is pending when it returns.
+func (ins *ProofInstance) AddFileAsync(ctx context.Context, commit bls12381.G1Affine, size uint64, start *big.Int, end *big.Int, credential []byte) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	submitterInfo, err := ins.GetSubmittersInfo()
+	setting, err := ins.GetSettingInfoContext(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	submitterInfo, err := ins.GetSubmittersInfoContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// // check credential
 	hash := ins.GetCredentialHash(ins.transactor.From, commit, size, start, end)
 	publicKey, err := crypto.SigToPub(hash, credential)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if crypto.PubkeyToAddress(*publicKey).Hex() != submitterInfo.MainSubmitter.Hex() {
-		return xerrors.Errorf("credential is not right, signer: %s mainSubmitter: %s", crypto.PubkeyToAddress(*publicKey).Hex(), submitterInfo.MainSubmitter.Hex())
+		return nil, xerrors.Errorf("credential is not right, signer: %s mainSubmitter: %s", crypto.PubkeyToAddress(*publicKey).Hex(), submitterInfo.MainSubmitter.Hex())
 	}
 
 	amount := big.NewInt(int64(setting.Price))
 	amount.Mul(big.NewInt(int64(size)), amount)
 	amount.Mul(amount, new(big.Int).Sub(end, start))
-	tx, err := erc20Ins.Approve(ins.transactor, ins.proofAddr, amount)
-	if err != nil {
-		return err
-	}
-	err = CheckTx(ins.endpoint, ins.transactor.From, tx, "Approve")
-	if err != nil {
-		return err
-	}
-
-	tx, err = proofIns.AddFile(ins.transactor, ToSolidityG1(commit), size, start, end, credential)
+	err = ins.approve(ctx, ins.proofAddr, amount)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "AddFile")
+	return ins.sender.Send(ctx, "AddFile", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.AddFile(opts, ToSolidityG1(commit), size, start, end, credential)
+	})
 }
 
 func (ins *ProofInstance) GenerateRnd() error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return ins.GenerateRndContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GenerateRndContext(ctx context.Context) error {
+	tx, err := ins.GenerateRndAsync(ctx)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proofIns.GenRnd(ins.transactor)
+func (ins *ProofInstance) GenerateRndAsync(ctx context.Context) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "GenerateRnd")
+	return ins.sender.Send(ctx, "GenerateRnd", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.GenRnd(opts)
+	})
 }
 
 func (ins *ProofInstance) BeSubmitter() error {
-	fmt.Println("submitter:", ins.transactor.From)
+	return ins.BeSubmitterContext(context.Background())
+}
 
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
+func (ins *ProofInstance) BeSubmitterContext(ctx context.Context) error {
+	tx, err := ins.BeSubmitterAsync(ctx)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+// BeSubmitterAsync approves the pledge of submitters and sends BeSubmitter. The approval is mined before
+// BeSubmitter is sent, so it blocks until then and only BeSubmitter is pending when it returns.
+func (ins *ProofInstance) BeSubmitterAsync(ctx context.Context) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	info, err := proofIns.GetSettingInfo(&bind.CallOpts{})
+	info, err := proofIns.GetSettingInfo(&bind.CallOpts{Context: ctx})
 	if err != nil {
-		return err
-	}
-	erc20Ins, err := erc.NewERC20(ins.tokenAddr, client)
-	if err != nil {
-		return err
-	}
-	tx, err := erc20Ins.Approve(ins.transactor, ins.pledgeAddr, info.SubPledge)
-	if err != nil {
-		return err
-	}
-	err = CheckTx(ins.endpoint, ins.transactor.From, tx, "Approve")
-	if err != nil {
-		return err
+		return nil, err
 	}
-
-	tx, err = proofIns.BeSubmitter(ins.transactor)
+	err = ins.approve(ctx, ins.pledgeAddr, info.SubPledge)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "BeSubmitter")
+	return ins.sender.Send(ctx, "BeSubmitter", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.BeSubmitter(opts)
+	})
 }
 
 func (ins *ProofInstance) SubmitAggregationProof(randomPoint fr.Element, commit bls12381.G1Affine, proof kzg.OpeningProof) error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
+	return ins.SubmitAggregationProofContext(context.Background(), randomPoint, commit, proof)
+}
+
+func (ins *ProofInstance) SubmitAggregationProofContext(ctx context.Context, randomPoint fr.Element, commit bls12381.G1Affine, proof kzg.OpeningProof) error {
+	tx, err := ins.SubmitAggregationProofAsync(ctx, randomPoint, commit, proof)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+// SubmitAggregationProofAsync approves the pledge of submitters which is not pledged yet and sends
+// SubmitProof. The approval is mined before SubmitProof is sent, so it blocks until then and only
+// SubmitProof is pending when it returns.
+func (ins *ProofInstance) SubmitAggregationProofAsync(ctx context.Context, randomPoint fr.Element, commit bls12381.G1Affine, proof kzg.OpeningProof) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	setting, err := ins.GetSettingInfo()
+	setting, err := ins.GetSettingInfoContext(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	pledgeBal, err := proofIns.Bal(&bind.CallOpts{}, ins.transactor.From)
+	pledgeBal, err := proofIns.Bal(&bind.CallOpts{Context: ctx}, ins.transactor.From)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if pledgeBal.Cmp(setting.SubPledge) < 0 {
-		erc20Ins, err := erc.NewERC20(ins.tokenAddr, client)
-		if err != nil {
-			return err
-		}
 		amount := pledgeBal.Sub(setting.SubPledge, pledgeBal)
-		tx, err := erc20Ins.Approve(ins.transactor, ins.pledgeAddr, amount)
+		err = ins.approve(ctx, ins.pledgeAddr, amount)
 		if err != nil {
-			return err
-		}
-		err = CheckTx(ins.endpoint, ins.transactor.From, tx, "Approve")
-		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	rndBytes, err := proofIns.Rnd(&bind.CallOpts{})
+	rndBytes, err := proofIns.Rnd(&bind.CallOpts{Context: ctx})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var rnd fr.Element
 	rnd.SetBytes(rndBytes[:])
 	if !rnd.Equal(&randomPoint) {
-		return xerrors.Errorf("rnd is not equal to on-chain rnd")
+		return nil, xerrors.Errorf("rnd is not equal to on-chain rnd")
 	}
 
-	tx, err := proofIns.SubmitProof(ins.transactor, ToSolidityG1(commit), ToSolidityProof(proof))
-	if err != nil {
-		return err
-	}
-
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "SubmitAggregationProof")
+	return ins.sender.Send(ctx, "SubmitAggregationProof", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.SubmitProof(opts, ToSolidityG1(commit), ToSolidityProof(proof))
+	})
 }
 
 func (ins *ProofInstance) ChallengePn(submitter common.Address) error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
+	return ins.ChallengePnContext(context.Background(), submitter)
+}
+
+func (ins *ProofInstance) ChallengePnContext(ctx context.Context, submitter common.Address) error {
+	tx, err := ins.ChallengePnAsync(ctx, submitter)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+// ChallengePnAsync approves the pledge of challengers which is not pledged yet and sends ChallengePn.
+// The approval is mined before ChallengePn is sent, so it blocks until then and only ChallengePn is
+// pending when it returns.
+func (ins *ProofInstance) ChallengePnAsync(ctx context.Context, submitter common.Address) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	setting, err := ins.GetSettingInfo()
+	setting, err := ins.GetSettingInfoContext(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	pledgeBal, err := proofIns.Bal(&bind.CallOpts{}, ins.transactor.From)
+	pledgeBal, err := proofIns.Bal(&bind.CallOpts{Context: ctx}, ins.transactor.From)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if pledgeBal.Cmp(setting.ChalPledge) < 0 {
-		erc20Ins, err := erc.NewERC20(ins.tokenAddr, client)
-		if err != nil {
-			return err
-		}
 		amount := pledgeBal.Sub(setting.ChalPledge, pledgeBal)
-		tx, err := erc20Ins.Approve(ins.transactor, ins.pledgeAddr, amount)
-		if err != nil {
-			return err
-		}
-		err = CheckTx(ins.endpoint, ins.transactor.From, tx, "Approve")
+		err = ins.approve(ctx, ins.pledgeAddr, amount)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	tx, err := proofIns.ChallengePn(ins.transactor, submitter)
-	if err != nil {
-		return err
-	}
-
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "ChallengePn")
+	return ins.sender.Send(ctx, "ChallengePn", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.ChallengePn(opts, submitter)
+	})
 }
 
 func (ins *ProofInstance) ChallengeCn(submitter common.Address, challengeIndex uint8) error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
+	return ins.ChallengeCnContext(context.Background(), submitter, challengeIndex)
+}
+
+func (ins *ProofInstance) ChallengeCnContext(ctx context.Context, submitter common.Address, challengeIndex uint8) error {
+	tx, err := ins.ChallengeCnAsync(ctx, submitter, challengeIndex)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+// ChallengeCnAsync approves the pledge of challengers which is not pledged yet and sends ChallengeCn.
+// The approval is mined before ChallengeCn is sent, so it blocks until then and only ChallengeCn is
+// pending when it returns.
+func (ins *ProofInstance) ChallengeCnAsync(ctx context.Context, submitter common.Address, challengeIndex uint8) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	setting, err := ins.GetSettingInfo()
+	setting, err := ins.GetSettingInfoContext(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	pledgeBal, err := proofIns.Bal(&bind.CallOpts{}, ins.transactor.From)
+	pledgeBal, err := proofIns.Bal(&bind.CallOpts{Context: ctx}, ins.transactor.From)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if pledgeBal.Cmp(setting.ChalPledge) < 0 {
-		erc20Ins, err := erc.NewERC20(ins.tokenAddr, client)
-		if err != nil {
-			return err
-		}
 		amount := pledgeBal.Sub(setting.ChalPledge, pledgeBal)
-		tx, err := erc20Ins.Approve(ins.transactor, ins.pledgeAddr, amount)
+		err = ins.approve(ctx, ins.pledgeAddr, amount)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		err = CheckTx(ins.endpoint, ins.transactor.From, tx, "Approve")
-		if err != nil {
-			return err
-		}
-	}
-
-	tx, err := proofIns.ChallengeCn(ins.transactor, submitter, challengeIndex)
-	if err != nil {
-		return err
 	}
 
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "ChallengeCn")
+	return ins.sender.Send(ctx, "ChallengeCn", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.ChallengeCn(opts, submitter, challengeIndex)
+	})
 }
 
 func (ins *ProofInstance) ResponseChallenge(commits [10]bls12381.G1Affine, lastOneStep bool) error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
+	return ins.ResponseChallengeContext(context.Background(), commits, lastOneStep)
+}
+
+func (ins *ProofInstance) ResponseChallengeContext(ctx context.Context, commits [10]bls12381.G1Affine, lastOneStep bool) error {
+	tx, err := ins.ResponseChallengeAsync(ctx, commits, lastOneStep)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) ResponseChallengeAsync(ctx context.Context, commits [10]bls12381.G1Affine, lastOneStep bool) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var commitsBytes [10][4][32]byte
 	for index, commit := range commits {
 		commitsBytes[index] = ToSolidityG1(commit)
 	}
-	tx, err := proofIns.ResponseChal(ins.transactor, commitsBytes, lastOneStep)
-	if err != nil {
-		return err
-	}
-
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "ResponseChallenge")
+	return ins.sender.Send(ctx, "ResponseChallenge", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.ResponseChal(opts, commitsBytes, lastOneStep)
+	})
 }
 
 func (ins *ProofInstance) EndChallenge(submitter common.Address) error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return ins.EndChallengeContext(context.Background(), submitter)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) EndChallengeContext(ctx context.Context, submitter common.Address) error {
+	tx, err := ins.EndChallengeAsync(ctx, submitter)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proofIns.EndChallenge(ins.transactor, submitter)
+func (ins *ProofInstance) EndChallengeAsync(ctx context.Context, submitter common.Address) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "EndChallenge")
+	return ins.sender.Send(ctx, "EndChallenge", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.EndChallenge(opts, submitter)
+	})
 }
 
 func (ins *ProofInstance) WithdrawMissedProfit() error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return ins.WithdrawMissedProfitContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) WithdrawMissedProfitContext(ctx context.Context) error {
+	tx, err := ins.WithdrawMissedProfitAsync(ctx)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proofIns.WithdrawMissedProfit(ins.transactor)
+func (ins *ProofInstance) WithdrawMissedProfitAsync(ctx context.Context) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "WithdrawMissedProfit")
+	return ins.sender.Send(ctx, "WithdrawMissedProfit", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.WithdrawMissedProfit(opts)
+	})
 }
 
 func (ins *ProofInstance) Pledge(amount *big.Int) error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
-
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
-	if err != nil {
-		return err
-	}
+	return ins.PledgeContext(context.Background(), amount)
+}
 
-	erc20Ins, err := erc.NewERC20(ins.tokenAddr, client)
+func (ins *ProofInstance) PledgeContext(ctx context.Context, amount *big.Int) error {
+	tx, err := ins.PledgeAsync(ctx, amount)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := erc20Ins.Approve(ins.transactor, ins.pledgeAddr, amount)
+// PledgeAsync approves amount to the pledge contract and sends Pledge. The approval is mined before
+// Pledge is sent, so it blocks until then and only Pledge is pending when it returns.
+func (ins *ProofInstance) PledgeAsync(ctx context.Context, amount *big.Int) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
-	}
-	err = CheckTx(ins.endpoint, ins.transactor.From, tx, "Approve")
-	if err != nil {
-		return err
+		return nil, err
 	}
 
-	tx, err = proofIns.FpPledge(ins.transactor, amount)
+	err = ins.approve(ctx, ins.pledgeAddr, amount)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "Pledge")
+	return ins.sender.Send(ctx, "Pledge", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.FpPledge(opts, amount)
+	})
 }
 
 func (ins *ProofInstance) Withdraw() error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return ins.WithdrawContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) WithdrawContext(ctx context.Context) error {
+	tx, err := ins.WithdrawAsync(ctx)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proofIns.FpWithdraw(ins.transactor)
+func (ins *ProofInstance) WithdrawAsync(ctx context.Context) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "Withdraw")
+	return ins.sender.Send(ctx, "Withdraw", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.FpWithdraw(opts)
+	})
 }
 
 func (ins *ProofInstance) AlterSetting(setting SettingInfo, vk bls12381.G2Affine, signs [5][]byte) error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
+	return ins.AlterSettingContext(context.Background(), setting, vk, signs)
+}
+
+func (ins *ProofInstance) AlterSettingContext(ctx context.Context, setting SettingInfo, vk bls12381.G2Affine, signs [5][]byte) error {
+	tx, err := ins.AlterSettingAsync(ctx, setting, vk, signs)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) AlterSettingAsync(ctx context.Context, setting SettingInfo, vk bls12381.G2Affine, signs [5][]byte) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	info := proxyfileproof.IFileProofSettingInfo{
@@ -507,47 +552,45 @@ func (ins *ProofInstance) AlterSetting(setting SettingInfo, vk bls12381.G2Affine
 		Vk:                ToSolidityG2(vk),
 	}
 
-	tx, err := proofIns.AlterSetting(ins.transactor, info, signs)
-	if err != nil {
-		return err
-	}
-
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "AlterSetting")
+	return ins.sender.Send(ctx, "AlterSetting", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.AlterSetting(opts, info, signs)
+	})
 }
 
 func (ins *ProofInstance) AlterFoundation(foundation common.Address, signs [5][]byte) error {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return ins.AlterFoundationContext(context.Background(), foundation, signs)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) AlterFoundationContext(ctx context.Context, foundation common.Address, signs [5][]byte) error {
+	tx, err := ins.AlterFoundationAsync(ctx, foundation, signs)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proofIns.AlterFoundation(ins.transactor, foundation, signs)
+func (ins *ProofInstance) AlterFoundationAsync(ctx context.Context, foundation common.Address, signs [5][]byte) (*evm.PendingTx, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(ins.endpoint, ins.transactor.From, tx, "AlterFoundation")
+	return ins.sender.Send(ctx, "AlterFoundation", func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return proofIns.AlterFoundation(opts, foundation, signs)
+	})
 }
 
 func (ins *ProofInstance) GetSelectFileCommit(submitter common.Address, index *big.Int) (bls12381.G1Affine, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return bls12381.G1Affine{}, err
-	}
-	defer client.Close()
+	return ins.GetSelectFileCommitContext(context.Background(), submitter, index)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetSelectFileCommitContext(ctx context.Context, submitter common.Address, index *big.Int) (bls12381.G1Affine, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return bls12381.G1Affine{}, err
 	}
 
-	commit, err := proofIns.SelectFiles(&bind.CallOpts{}, submitter, index)
+	commit, err := proofIns.SelectFiles(&bind.CallOpts{Context: ctx}, submitter, index)
 	if err != nil {
 		return bls12381.G1Affine{}, err
 	}
@@ -556,18 +599,16 @@ func (ins *ProofInstance) GetSelectFileCommit(submitter common.Address, index *b
 }
 
 func (ins *ProofInstance) GetFileCommit(index *big.Int) (*big.Int, bls12381.G1Affine, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, bls12381.G1Affine{}, err
-	}
-	defer client.Close()
+	return ins.GetFileCommitContext(context.Background(), index)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetFileCommitContext(ctx context.Context, index *big.Int) (*big.Int, bls12381.G1Affine, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return nil, bls12381.G1Affine{}, err
 	}
 
-	info, err := proofIns.GetCommit(&bind.CallOpts{}, index)
+	info, err := proofIns.GetCommit(&bind.CallOpts{Context: ctx}, index)
 	if err != nil {
 		return nil, bls12381.G1Affine{}, err
 	}
@@ -576,13 +617,11 @@ func (ins *ProofInstance) GetFileCommit(index *big.Int) (*big.Int, bls12381.G1Af
 }
 
 func (ins *ProofInstance) GetFileInfo(commit bls12381.G1Affine) (uint64, *big.Int, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return 0,nil, err
-	}
-	defer client.Close()
+	return ins.GetFileInfoContext(context.Background(), commit)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetFileInfoContext(ctx context.Context, commit bls12381.G1Affine) (uint64, *big.Int, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return 0,nil, err
 	}
@@ -592,7 +631,7 @@ func (ins *ProofInstance) GetFileInfo(commit bls12381.G1Affine) (uint64, *big.In
 	concatenateCommit = append(concatenateCommit, commitment[2][:]...)
 	concatenateCommit = append(concatenateCommit, commitment[3][:]...)
 
-	info, err := proofIns.GetFileInfo(&bind.CallOpts{}, concatenateCommit)
+	info, err := proofIns.GetFileInfo(&bind.CallOpts{Context: ctx}, concatenateCommit)
 	if err != nil {
 		return 0,nil, err
 	}
@@ -601,87 +640,77 @@ func (ins *ProofInstance) GetFileInfo(commit bls12381.G1Affine) (uint64, *big.In
 }
 
 func (ins *ProofInstance) GetRndRawBytes() ([32]byte, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return [32]byte{}, err
-	}
-	defer client.Close()
+	return ins.GetRndRawBytesContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetRndRawBytesContext(ctx context.Context) ([32]byte, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return [32]byte{}, err
 	}
 
-	rnd, err := proofIns.Rnd(&bind.CallOpts{})
+	rnd, err := proofIns.Rnd(&bind.CallOpts{Context: ctx})
 	return rnd, err
 }
 
 func (ins *ProofInstance) GetLast() (*big.Int, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
+	return ins.GetLastContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetLastContext(ctx context.Context) (*big.Int, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
 
-	last, err := proofIns.Last(&bind.CallOpts{})
+	last, err := proofIns.Last(&bind.CallOpts{Context: ctx})
 	return last, err
 }
 
 func (ins *ProofInstance) GetFilesAmount() (*big.Int, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
+	return ins.GetFilesAmountContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetFilesAmountContext(ctx context.Context) (*big.Int, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
 
-	amount, err := proofIns.FilesNum(&bind.CallOpts{})
+	amount, err := proofIns.FilesNum(&bind.CallOpts{Context: ctx})
 	return amount, err
 }
 
 func (ins *ProofInstance) GetFinalExpire() (*big.Int, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
+	return ins.GetFinalExpireContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetFinalExpireContext(ctx context.Context) (*big.Int, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
 
-	amount, err := proofIns.FinalExpire(&bind.CallOpts{})
+	amount, err := proofIns.FinalExpire(&bind.CallOpts{Context: ctx})
 	return amount, err
 }
 
 func (ins *ProofInstance) GetChallengeInfo(submitter common.Address) (ChallengeInfo, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return ChallengeInfo{}, err
-	}
-	defer client.Close()
+	return ins.GetChallengeInfoContext(context.Background(), submitter)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetChallengeInfoContext(ctx context.Context, submitter common.Address) (ChallengeInfo, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return ChallengeInfo{}, err
 	}
 
-	dividedCn, err := proofIns.DividedCn(&bind.CallOpts{}, submitter)
+	dividedCn, err := proofIns.DividedCn(&bind.CallOpts{Context: ctx}, submitter)
 	if err != nil {
 		return ChallengeInfo{}, err
 	}
 
-	info, err := proofIns.Challenges(&bind.CallOpts{}, submitter)
+	info, err := proofIns.Challenges(&bind.CallOpts{Context: ctx}, submitter)
 	if err != nil {
 		return ChallengeInfo{}, err
 	}
@@ -689,92 +718,76 @@ func (ins *ProofInstance) GetChallengeInfo(submitter common.Address) (ChallengeI
 }
 
 func (ins *ProofInstance) GetSettingInfo() (SettingInfo, error) {
-	var info SettingInfo
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return info, err
-	}
-	defer client.Close()
+	return ins.GetSettingInfoContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetSettingInfoContext(ctx context.Context) (SettingInfo, error) {
+	var info SettingInfo
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return info, err
 	}
 
-	return proofIns.GetSettingInfo(&bind.CallOpts{})
+	return proofIns.GetSettingInfo(&bind.CallOpts{Context: ctx})
 }
 
 func (ins *ProofInstance) GetSubmittersInfo() (SubmitterInfo, error) {
-	var info SubmitterInfo
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return info, err
-	}
-	defer client.Close()
+	return ins.GetSubmittersInfoContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetSubmittersInfoContext(ctx context.Context) (SubmitterInfo, error) {
+	var info SubmitterInfo
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return info, err
 	}
 
-	return proofIns.SubmittersInfo(&bind.CallOpts{})
+	return proofIns.SubmittersInfo(&bind.CallOpts{Context: ctx})
 }
 
 func (ins *ProofInstance) IsSubmitter(account common.Address) (bool, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return false, err
-	}
-	defer client.Close()
+	return ins.IsSubmitterContext(context.Background(), account)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) IsSubmitterContext(ctx context.Context, account common.Address) (bool, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return false, err
 	}
 
-	return proofIns.IsSubmitter(&bind.CallOpts{}, account)
+	return proofIns.IsSubmitter(&bind.CallOpts{Context: ctx}, account)
 }
 
 func (ins *ProofInstance) GetVK() (bls12381.G2Affine, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return bls12381.G2Affine{}, err
-	}
-	defer client.Close()
+	return ins.GetVKContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetVKContext(ctx context.Context) (bls12381.G2Affine, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return bls12381.G2Affine{}, err
 	}
 
-	vkSol, err := proofIns.GetVK(&bind.CallOpts{})
+	vkSol, err := proofIns.GetVK(&bind.CallOpts{Context: ctx})
 	return FromSolidityG2(vkSol), err
 }
 
 func (ins *ProofInstance) GetPledgeBalance(account common.Address) (*big.Int, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
+	return ins.GetPledgeBalanceContext(context.Background(), account)
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) GetPledgeBalanceContext(ctx context.Context, account common.Address) (*big.Int, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
 
-	amount, err := proofIns.Bal(&bind.CallOpts{}, account)
+	amount, err := proofIns.Bal(&bind.CallOpts{Context: ctx}, account)
 	return amount, err
 }
 
 func (ins *ProofInstance) FilterAddFile(opt *bind.FilterOpts, accounts []common.Address) ([]AddFileEvent, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
-
-	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, client)
+	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
@@ -804,13 +817,7 @@ func (ins *ProofInstance) FilterAddFile(opt *bind.FilterOpts, accounts []common.
 }
 
 func (ins *ProofInstance) FilterSubmitProof(opt *bind.FilterOpts, submitters []common.Address, rnds [][32]byte) ([]SubmitProofEvent, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
-
-	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, client)
+	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
@@ -841,13 +848,7 @@ func (ins *ProofInstance) FilterSubmitProof(opt *bind.FilterOpts, submitters []c
 }
 
 func (ins *ProofInstance) FilterNoProofs(opt *bind.FilterOpts) ([]NoProofsEvent, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
-
-	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, client)
+	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
@@ -872,13 +873,7 @@ func (ins *ProofInstance) FilterNoProofs(opt *bind.FilterOpts) ([]NoProofsEvent,
 }
 
 func (ins *ProofInstance) FilterChallengeCn(opt *bind.FilterOpts, submitters []common.Address, challengers []common.Address, lasts []*big.Int) ([]ChallengeCnEvent, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
-
-	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, client)
+	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
@@ -906,13 +901,7 @@ func (ins *ProofInstance) FilterChallengeCn(opt *bind.FilterOpts, submitters []c
 }
 
 func (ins *ProofInstance) FilterResponseChallenge(opt *bind.FilterOpts, submitters []common.Address, challengers []common.Address, lasts []*big.Int) ([]ResponseChallengeEvent, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
-
-	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, client)
+	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
@@ -939,13 +928,7 @@ func (ins *ProofInstance) FilterResponseChallenge(opt *bind.FilterOpts, submitte
 }
 
 func (ins *ProofInstance) FilterChallengeResult(opt *bind.FilterOpts, submitters []common.Address, challengers []common.Address, lasts []*big.Int) ([]ChallengeResultEvent, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
-
-	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, client)
+	proofIns, err := proxyfileproof.NewIFileProof(ins.proofAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
@@ -972,13 +955,7 @@ func (ins *ProofInstance) FilterChallengeResult(opt *bind.FilterOpts, submitters
 }
 
 func (ins *ProofInstance) FilterPenalize(opt *bind.FilterOpts, penalizedAccounts []common.Address, rewardedAccounts []common.Address) ([]PenalizeEvent, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
-
-	pledgeIns, err := proxyfileproof.NewIPledge(ins.pledgeAddr, client)
+	pledgeIns, err := proxyfileproof.NewIPledge(ins.pledgeAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
@@ -1005,23 +982,21 @@ func (ins *ProofInstance) FilterPenalize(opt *bind.FilterOpts, penalizedAccounts
 }
 
 func (ins *ProofInstance) IsSubmitterWinner() (bool, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return false, err
-	}
-	defer client.Close()
+	return ins.IsSubmitterWinnerContext(context.Background())
+}
 
-	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, client)
+func (ins *ProofInstance) IsSubmitterWinnerContext(ctx context.Context) (bool, error) {
+	proofIns, err := proxyfileproof.NewProxyProof(ins.proofProxyAddr, ins.backend)
 	if err != nil {
 		return false, err
 	}
 
-	rnd, err := proofIns.Rnd(&bind.CallOpts{})
+	rnd, err := proofIns.Rnd(&bind.CallOpts{Context: ctx})
 	if err != nil {
 		return false, err
 	}
 
-	submits, err := ins.FilterSubmitProof(&bind.FilterOpts{}, []common.Address{ins.transactor.From}, [][32]byte{rnd})
+	submits, err := ins.FilterSubmitProof(&bind.FilterOpts{Context: ctx}, []common.Address{ins.transactor.From}, [][32]byte{rnd})
 	if err != nil {
 		return false, err
 	}
@@ -1029,7 +1004,7 @@ func (ins *ProofInstance) IsSubmitterWinner() (bool, error) {
 		return false, xerrors.Errorf("Have not submitted proof at current cycle")
 	}
 
-	challengeInfo, err := ins.GetChallengeInfo(ins.transactor.From)
+	challengeInfo, err := ins.GetChallengeInfoContext(ctx, ins.transactor.From)
 	if err != nil {
 		return false, err
 	}
@@ -1044,7 +1019,7 @@ func (ins *ProofInstance) IsSubmitterWinner() (bool, error) {
 
 	last := submits[0].Last
 
-	results, err := ins.FilterChallengeResult(&bind.FilterOpts{}, []common.Address{ins.transactor.From}, nil, []*big.Int{last})
+	results, err := ins.FilterChallengeResult(&bind.FilterOpts{Context: ctx}, []common.Address{ins.transactor.From}, nil, []*big.Int{last})
 	if err != nil {
 		return false, err
 	}
@@ -1061,18 +1036,16 @@ func (ins *ProofInstance) IsSubmitterWinner() (bool, error) {
 }
 
 func (ins *ProofInstance) GetAlterSettingInfoHash(setting SettingInfo, vk bls12381.G2Affine) ([]byte, error) {
-	client, err := ethclient.DialContext(context.TODO(), ins.endpoint)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Close()
+	return ins.GetAlterSettingInfoHashContext(context.Background(), setting, vk)
+}
 
-	authIns, err := auth.NewAuth(ins.authAddr, client)
+func (ins *ProofInstance) GetAlterSettingInfoHashContext(ctx context.Context, setting SettingInfo, vk bls12381.G2Affine) ([]byte, error) {
+	authIns, err := auth.NewAuth(ins.authAddr, ins.backend)
 	if err != nil {
 		return nil, err
 	}
 
-	nonce, err := authIns.Nonce(&bind.CallOpts{})
+	nonce, err := authIns.Nonce(&bind.CallOpts{Context: ctx})
 	if err != nil {
 		return nil, err
 	}
@@ -1106,34 +1079,21 @@ func (ins *ProofInstance) GetCredentialHash(address common.Address, commit bls12
 // 	return getCredentialHash(proofAddr, address, commit, size, start, end), nil
 // }
 
+// Waiter returns the waiter of transactions sent by instance,
+// its confirmations and timeout can be changed before sending transactions.
+func (ins *ProofInstance) Waiter() *evm.Waiter {
+	return ins.sender.Waiter()
+}
+
 // CheckTx check whether transaction is successful through receipt
+//
+// Deprecated: use evm.Waiter, the error of which can be inspected.
 func CheckTx(endPoint string, from common.Address, tx *types.Transaction, name string) error {
-	var receipt *types.Receipt
-
-	t := checkTxSleepTime
-	for i := 0; i < 30; i++ {
-		time.Sleep(time.Duration(t) * time.Second)
-		receipt = com.GetTransactionReceipt(endPoint, tx.Hash())
-		if receipt != nil {
-			break
-		}
-		t = nextBlockTime
-	}
-
-	if receipt == nil {
-		return xerrors.Errorf("%s: cann't get transaction(%s) receipt, not packaged", name, tx.Hash())
+	client, _, err := evm.Dial(context.TODO(), endPoint)
+	if err != nil {
+		return err
 	}
+	defer client.Close()
 
-	// 0 means fail
-	if receipt.Status == 0 {
-		if receipt.GasUsed != receipt.CumulativeGasUsed {
-			return xerrors.Errorf("%s: transaction(%s) exceed gas limit", name, tx.Hash())
-		}
-		reason, err := getErrorReason(context.TODO(), endPoint, from, tx)
-		if err != nil {
-			return xerrors.Errorf("%s: transaction(%s) mined but execution failed: %s", name, tx.Hash(), err.Error())
-		}
-		return xerrors.Errorf("%s: transaction(%s) revert(%s)", name, tx.Hash(), reason)
-	}
-	return nil
+	return evm.NewWaiter(client, evm.ABIs(proxyfileproof.ProxyProofMetaData)...).Check(context.TODO(), tx, name)
 }
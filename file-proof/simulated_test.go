@@ -0,0 +1,31 @@
+package proof_test
+
+import (
+	"testing"
+
+	proof "github.com/memoio/go-did/file-proof"
+	"github.com/memoio/go-did/simulated"
+)
+
+func TestProofLifecycle(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 1)
+	defer chain.Close()
+
+	proofIns, err := proof.NewProofInstanceWithBackend(chain.Keys[0], chain, chain.ChainID, &contracts.Proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = proofIns.GetSettingInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := proofIns.GetPledgeBalance(chain.Address(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Sign() != 0 {
+		t.Fatalf("unexpected pledge balance %s", balance)
+	}
+}
@@ -1,22 +1,15 @@
 package proof
 
 import (
-	"bytes"
-	"context"
 	"encoding/binary"
-	"log"
 	"math/big"
 
-	"github.com/cockroachdb/errors"
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr/kzg"
-	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	proxyfileproof "github.com/memoio/did-solidity/go-contracts/proxy-proof"
 )
 
@@ -252,44 +245,3 @@ func getCredentialHash(proofAddr common.Address, userAddr common.Address, commit
 	endByte := common.LeftPadBytes(end.Bytes(), 32)
 	return crypto.Keccak256(proofAddr.Bytes(), userAddr.Bytes(), ToAppendedBytesG1(commit), sizeByte, startByte, endByte)
 }
-
-// other code using the following...
-func getErrorReason(ctx context.Context, endpoint string, from common.Address, tx *types.Transaction) (string, error) {
-	client, err := ethclient.Dial(endpoint)
-	if err != nil {
-		return "", err
-	}
-	defer client.Close()
-
-	msg := ethereum.CallMsg{
-		From:     from,
-		To:       tx.To(),
-		Gas:      tx.Gas(),
-		GasPrice: tx.GasPrice(),
-		Value:    tx.Value(),
-		Data:     tx.Data(),
-	}
-	res, err := client.CallContract(ctx, msg, nil)
-	if err != nil {
-		log.Println(res)
-		return "", err
-	}
-	return unpackError(res)
-}
-
-var (
-	errorSig     = []byte{0x08, 0xc3, 0x79, 0xa0} // Keccak256("Error(string)")[:4]
-	abiString, _ = abi.NewType("string", "", nil)
-)
-
-func unpackError(result []byte) (string, error) {
-	log.Println(string(result))
-	if !bytes.Equal(result[:4], errorSig) {
-		return "<tx result not Error(string)>", errors.New("TX result not of type Error(string)")
-	}
-	vs, err := abi.Arguments{{Type: abiString}}.UnpackValues(result[4:])
-	if err != nil {
-		return "<invalid tx result>", errors.Wrap(err, "unpacking revert reason")
-	}
-	return vs[0].(string), nil
-}
@@ -0,0 +1,711 @@
+// Package indexer follows the events of the memo did and mfile did contracts block by block, and
+// materializes did documents into a local store, so that they are resolved without filtering the
+// events from the genesis block on every call.
+package indexer
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	etypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/memoio/did-solidity/go-contracts/proxy"
+	"github.com/memoio/go-did/evm"
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/mfile"
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+var (
+	// DefaultReorgDepth is the number of recent blocks whose hashes are kept to handle reorgs
+	DefaultReorgDepth uint64 = 128
+	// DefaultMaxRange is the max number of blocks whose events are filtered by one query
+	DefaultMaxRange uint64 = 2000
+	// DefaultPollInterval is how often new blocks are indexed by Start
+	DefaultPollInterval = 5 * time.Second
+)
+
+var (
+	// ErrReorgTooDeep is returned if the chain reorgs before the recent blocks kept, the store should be indexed again
+	ErrReorgTooDeep = xerrors.New("reorg is deeper than the blocks kept")
+	// errReorged is returned if the chain reorgs while blocks are indexed, they are indexed again at next sync
+	errReorged = xerrors.New("chain reorgs while indexing")
+)
+
+// Options configures how an indexer follows the chain
+type Options struct {
+	// From is the first block indexed if the store is empty, such as the block deploying the contracts
+	From uint64
+	// Confirmations is the number of blocks mined after a block before it is indexed
+	Confirmations uint64
+	// ReorgDepth is the number of recent blocks kept to handle reorgs, DefaultReorgDepth is used if it is 0
+	ReorgDepth uint64
+	// MaxRange is the max number of blocks filtered by one query, which should not exceed the limit
+	// of rpc, DefaultMaxRange is used if it is 0
+	MaxRange uint64
+	// PollInterval is how often new blocks are indexed by Start, DefaultPollInterval is used if it is 0
+	PollInterval time.Duration
+}
+
+// Indexer saves the AddAuth, AddAssertion, AddDelegation and AddRecovery events of the account did
+// contract and the BuyRead and GrantRead events of the file did contract in store, and keeps the
+// documents materialized in store up to date with the events of the dids they depend on.
+//
+// Documents are materialized at the checkpoint, the last block indexed, when they are resolved by
+// MemoResolver or MfileResolver at the first time. Reading the state of a checkpoint which is far
+// behind the chain needs an archive node, so the indexer should be synced before resolving.
+type Indexer struct {
+	backend   bind.ContractBackend
+	contracts []common.Address
+	store     Store
+	opts      Options
+
+	memo       *memo.MemoDIDResolver
+	mfile      *mfile.MfileDIDResolver
+	accountIns *proxy.IAccountDid
+	fileIns    *proxy.IFileDid
+
+	// memoAt and mfileAt read the documents on chain, they are DocumentAt of memo and mfile
+	memoAt  func(ctx context.Context, did *types.MemoDID, block *big.Int, relationships *memo.Relationships) (*types.MemoDIDDocument, error)
+	mfileAt func(ctx context.Context, did *types.MfileDID, block *big.Int, reads []string) (*types.MfileDIDDocument, error)
+
+	// syncLk serializes Sync
+	syncLk sync.Mutex
+	// lk serializes writing store, it is taken for each range of blocks indexed rather than a whole
+	// Sync, so that resolving is not blocked until a long catch-up finishes
+	lk sync.Mutex
+
+	runLk  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+	// err is the result of the last sync run by Start
+	err error
+}
+
+// NewIndexer creates an indexer of the contracts of memoAddrs and mfileAddrs on chain, which saves
+// the index in store. The store is closed by Close of the indexer.
+func NewIndexer(chain string, backend bind.ContractBackend, memoAddrs *memo.ContractAddress, mfileAddrs *mfile.ContractAddress, store Store, opts Options) (*Indexer, error) {
+	if opts.ReorgDepth == 0 {
+		opts.ReorgDepth = DefaultReorgDepth
+	}
+	if opts.MaxRange == 0 {
+		opts.MaxRange = DefaultMaxRange
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+
+	memoResolver, err := memo.NewMemoDIDResolverWithBackend(chain, backend, memoAddrs)
+	if err != nil {
+		return nil, err
+	}
+	mfileResolver, err := mfile.NewMfileDIDResolverWithBackend(backend, mfileAddrs)
+	if err != nil {
+		return nil, err
+	}
+	accountIns, err := proxy.NewIAccountDid(memoAddrs.AccountDidAddr, backend)
+	if err != nil {
+		return nil, err
+	}
+	fileIns, err := proxy.NewIFileDid(mfileAddrs.FileDidAddr, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Indexer{
+		backend:    backend,
+		contracts:  []common.Address{memoAddrs.AccountDidAddr, mfileAddrs.FileDidAddr},
+		store:      store,
+		opts:       opts,
+		memo:       memoResolver,
+		mfile:      mfileResolver,
+		accountIns: accountIns,
+		fileIns:    fileIns,
+		memoAt:     memoResolver.DocumentAt,
+		mfileAt:    mfileResolver.DocumentAt,
+	}, nil
+}
+
+// MemoResolver returns the resolver of memo dids backed by the index
+func (i *Indexer) MemoResolver() *MemoResolver {
+	return &MemoResolver{MemoDIDResolver: i.memo, indexer: i}
+}
+
+// MfileResolver returns the resolver of mfile dids backed by the index
+func (i *Indexer) MfileResolver() *MfileResolver {
+	return &MfileResolver{MfileDIDResolver: i.mfile, indexer: i}
+}
+
+// Checkpoint returns the last block indexed, nil if no block is indexed
+func (i *Indexer) Checkpoint() (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	ok, err := get(i.store, checkpointKey, &checkpoint)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// Start indexes new blocks every PollInterval until Close. Errors of indexing are reported by Err and
+// retried at next time, except ErrReorgTooDeep which stops indexing, the store should be indexed again
+// by a new indexer then.
+func (i *Indexer) Start() {
+	i.runLk.Lock()
+	defer i.runLk.Unlock()
+	if i.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	i.cancel = cancel
+	i.done = make(chan struct{})
+	go func() {
+		defer close(i.done)
+		ticker := time.NewTicker(i.opts.PollInterval)
+		defer ticker.Stop()
+		for {
+			_, err := i.Sync(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			i.runLk.Lock()
+			i.err = err
+			i.runLk.Unlock()
+			if errors.Is(err, ErrReorgTooDeep) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Err returns the error of the last sync run by Start, nil if it succeeds. Resolvers keep serving the
+// documents at the checkpoint while indexing fails.
+func (i *Indexer) Err() error {
+	i.runLk.Lock()
+	defer i.runLk.Unlock()
+	return i.err
+}
+
+// Close stops indexing and closes the store
+func (i *Indexer) Close() error {
+	i.runLk.Lock()
+	cancel, done := i.cancel, i.done
+	i.cancel = nil
+	i.runLk.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return i.store.Close()
+}
+
+// Sync indexes the blocks up to the head of chain less Confirmations, and returns the new checkpoint.
+// If the block of checkpoint is reorged, the index is rewound to the block where the chain forks at first.
+func (i *Indexer) Sync(ctx context.Context) (*Checkpoint, error) {
+	i.syncLk.Lock()
+	defer i.syncLk.Unlock()
+
+	head, err := i.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint, err := i.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	if head.Number.Uint64() < i.opts.Confirmations {
+		return checkpoint, nil
+	}
+	target := head.Number.Uint64() - i.opts.Confirmations
+
+	from := i.opts.From
+	if checkpoint != nil {
+		header, err := i.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(checkpoint.Number))
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return nil, err
+		}
+		if header == nil || header.Hash() != checkpoint.Hash {
+			i.lk.Lock()
+			checkpoint, err = i.rewind(ctx)
+			i.lk.Unlock()
+			if err != nil {
+				return nil, err
+			}
+		}
+		from = checkpoint.Number + 1
+	}
+
+	for from <= target {
+		to := from + i.opts.MaxRange - 1
+		if to > target {
+			to = target
+		}
+		i.lk.Lock()
+		checkpoint, err = i.index(ctx, from, to, head.Number.Uint64())
+		i.lk.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		from = to + 1
+	}
+	return checkpoint, nil
+}
+
+// index saves the events of blocks from to to, and refreshes the documents depending on them
+func (i *Indexer) index(ctx context.Context, from, to, head uint64) (*Checkpoint, error) {
+	batch := i.store.NewBatch()
+
+	// the hashes of recent blocks are kept to find where a reorg forks
+	hashes := make(map[uint64]common.Hash)
+	start := from
+	if head >= i.opts.ReorgDepth && head-i.opts.ReorgDepth+1 > start {
+		start = head - i.opts.ReorgDepth + 1
+	}
+	for number := start; number <= to; number++ {
+		header, err := i.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return nil, err
+		}
+		hashes[number] = header.Hash()
+		if err := batch.Put(numberKey(blockPrefix, number), header.Hash().Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := hashes[to]; !ok {
+		header, err := i.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(to))
+		if err != nil {
+			return nil, err
+		}
+		hashes[to] = header.Hash()
+	}
+
+	logs, err := i.backend.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: i.contracts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	touched := make(map[common.Hash]struct{})
+	for _, log := range logs {
+		if log.Removed || len(log.Topics) < 2 {
+			continue
+		}
+		if hash, ok := hashes[log.BlockNumber]; ok && hash != log.BlockHash {
+			return nil, errReorged
+		}
+		// the did is the first indexed argument of events
+		topic := log.Topics[1]
+		touched[topic] = struct{}{}
+
+		e := i.parse(log)
+		if e == nil {
+			continue
+		}
+		if err := put(batch, eventKey(topic, log.BlockNumber, log.Index), e); err != nil {
+			return nil, err
+		}
+		if err := batch.Put(undoKey(log.BlockNumber, topic, log.Index), nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// blocks before the recent ones are not reorged
+	if to >= i.opts.ReorgDepth {
+		if err := i.prune(batch, to-i.opts.ReorgDepth); err != nil {
+			return nil, err
+		}
+	}
+
+	checkpoint := &Checkpoint{Number: to, Hash: hashes[to]}
+	if err := put(batch, checkpointKey, checkpoint); err != nil {
+		return nil, err
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+
+	for topic := range touched {
+		if err := i.refreshTopic(ctx, topic, to); err != nil {
+			return nil, err
+		}
+	}
+	return checkpoint, nil
+}
+
+// parse returns the event of log saved in store, nil if it is not saved
+func (i *Indexer) parse(log etypes.Log) *event {
+	if auth, err := i.accountIns.ParseAddAuth(log); err == nil {
+		return &event{Kind: eventAddAuth, ID: auth.Id}
+	}
+	if assertion, err := i.accountIns.ParseAddAssertion(log); err == nil {
+		return &event{Kind: eventAddAssertion, ID: assertion.Id}
+	}
+	if delegation, err := i.accountIns.ParseAddDelegation(log); err == nil {
+		return &event{Kind: eventAddDelegation, ID: delegation.Id}
+	}
+	if recovery, err := i.accountIns.ParseAddRecovery(log); err == nil {
+		return &event{Kind: eventAddRecovery, ID: recovery.Recovery}
+	}
+	if read, err := i.fileIns.ParseBuyRead(log); err == nil {
+		return &event{Kind: eventBuyRead, ID: read.MemoDid}
+	}
+	if read, err := i.fileIns.ParseGrantRead(log); err == nil {
+		return &event{Kind: eventGrantRead, ID: read.MemoDid}
+	}
+	return nil
+}
+
+// prune removes the hashes and undo entries of the blocks up to number
+func (i *Indexer) prune(batch ethdb.Batch, number uint64) error {
+	for _, prefix := range [][]byte{blockPrefix, undoPrefix} {
+		it := i.store.NewIterator(prefix, nil)
+		for it.Next() {
+			if binary.BigEndian.Uint64(it.Key()[len(prefix):]) > number {
+				break
+			}
+			if err := batch.Delete(append([]byte{}, it.Key()...)); err != nil {
+				it.Release()
+				return err
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewind removes the events of the blocks reorged, the documents materialized after the block
+// where the chain forks are materialized again at it, which is the new checkpoint.
+func (i *Indexer) rewind(ctx context.Context) (*Checkpoint, error) {
+	type block struct {
+		number uint64
+		hash   common.Hash
+	}
+	var blocks []block
+	it := i.store.NewIterator(blockPrefix, nil)
+	for it.Next() {
+		blocks = append(blocks, block{
+			number: binary.BigEndian.Uint64(it.Key()[len(blockPrefix):]),
+			hash:   common.BytesToHash(it.Value()),
+		})
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	// find the last block kept which is still on chain
+	var fork *Checkpoint
+	for j := len(blocks) - 1; j >= 0; j-- {
+		header, err := i.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(blocks[j].number))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Hash() == blocks[j].hash {
+			fork = &Checkpoint{Number: blocks[j].number, Hash: blocks[j].hash}
+			break
+		}
+	}
+	if fork == nil {
+		return nil, ErrReorgTooDeep
+	}
+
+	batch := i.store.NewBatch()
+	for _, b := range blocks {
+		if b.number > fork.Number {
+			if err := batch.Delete(numberKey(blockPrefix, b.number)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	it = i.store.NewIterator(undoPrefix, numberKey(nil, fork.Number+1))
+	for it.Next() {
+		key := it.Key()[len(undoPrefix):]
+		number := binary.BigEndian.Uint64(key)
+		topic := common.BytesToHash(key[8 : 8+common.HashLength])
+		index := binary.BigEndian.Uint32(key[8+common.HashLength:])
+		if err := batch.Delete(eventKey(topic, number, uint(index))); err != nil {
+			it.Release()
+			return nil, err
+		}
+		if err := batch.Delete(append([]byte{}, it.Key()...)); err != nil {
+			it.Release()
+			return nil, err
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if err := put(batch, checkpointKey, fork); err != nil {
+		return nil, err
+	}
+	if err := batch.Write(); err != nil {
+		return nil, err
+	}
+
+	// documents materialized on the blocks reorged
+	var memoDIDs, mfileDIDs []string
+	for _, prefix := range [][]byte{memoPrefix, mfilePrefix} {
+		it := i.store.NewIterator(prefix, nil)
+		for it.Next() {
+			var record struct {
+				Block uint64 `json:"block"`
+			}
+			if err := json.Unmarshal(it.Value(), &record); err != nil {
+				it.Release()
+				return nil, err
+			}
+			if record.Block > fork.Number {
+				did := string(it.Key()[len(prefix):])
+				if prefix[0] == memoPrefix[0] {
+					memoDIDs = append(memoDIDs, did)
+				} else {
+					mfileDIDs = append(mfileDIDs, did)
+				}
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return nil, err
+		}
+	}
+	for _, did := range memoDIDs {
+		if err := i.refreshMemo(ctx, did, fork.Number); err != nil {
+			return nil, err
+		}
+	}
+	for _, did := range mfileDIDs {
+		if err := i.refreshMfile(ctx, did, fork.Number); err != nil {
+			return nil, err
+		}
+	}
+	return fork, nil
+}
+
+// refreshTopic materializes the documents depending on the events of topic again at number
+func (i *Indexer) refreshTopic(ctx context.Context, topic common.Hash, number uint64) error {
+	prefix := append(append([]byte{}, dependPrefix...), topic.Bytes()...)
+	var memoDIDs, mfileDIDs []string
+	it := i.store.NewIterator(prefix, nil)
+	for it.Next() {
+		key := it.Key()[len(prefix):]
+		if key[0] == memoPrefix[0] {
+			memoDIDs = append(memoDIDs, string(key[1:]))
+		} else {
+			mfileDIDs = append(mfileDIDs, string(key[1:]))
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	for _, did := range memoDIDs {
+		if err := i.refreshMemo(ctx, did, number); err != nil {
+			return err
+		}
+	}
+	for _, did := range mfileDIDs {
+		if err := i.refreshMfile(ctx, did, number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshMemo materializes the memo document of did again at number. If it cannot be materialized,
+// it is removed from store and materialized again when it is resolved.
+func (i *Indexer) refreshMemo(ctx context.Context, didString string, number uint64) error {
+	did, err := types.ParseMemoDID(didString)
+	if err != nil {
+		return err
+	}
+	_, err = i.materializeMemo(ctx, did, number)
+	if err != nil {
+		return i.removeMemo(didString)
+	}
+	return nil
+}
+
+// refreshMfile materializes the mfile document of did again at number. If it cannot be materialized,
+// it is removed from store and materialized again when it is resolved.
+func (i *Indexer) refreshMfile(ctx context.Context, didString string, number uint64) error {
+	did, err := types.ParseMfileDID(didString)
+	if err != nil {
+		return err
+	}
+	_, err = i.materializeMfile(ctx, did, number)
+	if err != nil {
+		return i.removeMfile(didString)
+	}
+	return nil
+}
+
+// memoDocumentAt reads the document of did at block number with the events indexed
+func (i *Indexer) memoDocumentAt(ctx context.Context, did *types.MemoDID, number uint64) (*types.MemoDIDDocument, error) {
+	events, err := events(i.store, evm.Topic(did.Identifier), number)
+	if err != nil {
+		return nil, err
+	}
+	var relationships memo.Relationships
+	for _, e := range events {
+		switch e.Kind {
+		case eventAddAuth:
+			relationships.Authentication = append(relationships.Authentication, e.ID)
+		case eventAddAssertion:
+			relationships.AssertionMethod = append(relationships.AssertionMethod, e.ID)
+		case eventAddDelegation:
+			relationships.CapabilityDelegation = append(relationships.CapabilityDelegation, e.ID)
+		case eventAddRecovery:
+			relationships.Recovery = append(relationships.Recovery, e.ID)
+		}
+	}
+	return i.memoAt(ctx, did, new(big.Int).SetUint64(number), &relationships)
+}
+
+// mfileDocumentAt reads the document of did at block number with the events indexed
+func (i *Indexer) mfileDocumentAt(ctx context.Context, did *types.MfileDID, number uint64) (*types.MfileDIDDocument, error) {
+	events, err := events(i.store, evm.Topic(did.Identifier), number)
+	if err != nil {
+		return nil, err
+	}
+	// read permissions bought are before the ones granted
+	var bought, granted []string
+	for _, e := range events {
+		switch e.Kind {
+		case eventBuyRead:
+			bought = append(bought, e.ID)
+		case eventGrantRead:
+			granted = append(granted, e.ID)
+		}
+	}
+	return i.mfileAt(ctx, did, new(big.Int).SetUint64(number), append(bought, granted...))
+}
+
+// materializeMemo reads the document of did at block number and saves it in store with the
+// topics it depends on. Documents of dids not registered are not saved.
+func (i *Indexer) materializeMemo(ctx context.Context, did *types.MemoDID, number uint64) (*memoRecord, error) {
+	document, err := i.memoDocumentAt(ctx, did, number)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &memoRecord{Block: number, Topics: memo.Dependencies(did, document)}
+	if document.ID.Identifier == "" {
+		record.Deactivated = true
+	} else {
+		record.Document = document
+	}
+	didString := did.String()
+	if !record.Deactivated && len(document.VerificationMethod) == 0 {
+		return record, i.removeMemo(didString)
+	}
+
+	var old memoRecord
+	if _, err := get(i.store, documentKey(memoPrefix, didString), &old); err != nil {
+		return nil, err
+	}
+	batch := i.store.NewBatch()
+	for _, topic := range old.Topics {
+		if err := batch.Delete(dependKey(topic, memoPrefix, didString)); err != nil {
+			return nil, err
+		}
+	}
+	for _, topic := range record.Topics {
+		if err := batch.Put(dependKey(topic, memoPrefix, didString), nil); err != nil {
+			return nil, err
+		}
+	}
+	if err := put(batch, documentKey(memoPrefix, didString), record); err != nil {
+		return nil, err
+	}
+	return record, batch.Write()
+}
+
+// materializeMfile reads the document of did at block number and saves it in store, it depends on
+// the events of did only. Documents of dids not registered are not saved.
+func (i *Indexer) materializeMfile(ctx context.Context, did *types.MfileDID, number uint64) (*mfileRecord, error) {
+	document, err := i.mfileDocumentAt(ctx, did, number)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &mfileRecord{Block: number}
+	if document.ID.Identifier == "" {
+		record.Deactivated = true
+	} else {
+		record.Document = document
+	}
+	didString := did.String()
+	if !record.Deactivated && document.Controller.Identifier == "" {
+		return record, i.removeMfile(didString)
+	}
+
+	batch := i.store.NewBatch()
+	if err := batch.Put(dependKey(evm.Topic(did.Identifier), mfilePrefix, didString), nil); err != nil {
+		return nil, err
+	}
+	if err := put(batch, documentKey(mfilePrefix, didString), record); err != nil {
+		return nil, err
+	}
+	return record, batch.Write()
+}
+
+// removeMemo removes the memo document of did and its dependencies from store
+func (i *Indexer) removeMemo(didString string) error {
+	var record memoRecord
+	ok, err := get(i.store, documentKey(memoPrefix, didString), &record)
+	if err != nil || !ok {
+		return err
+	}
+	batch := i.store.NewBatch()
+	for _, topic := range record.Topics {
+		if err := batch.Delete(dependKey(topic, memoPrefix, didString)); err != nil {
+			return err
+		}
+	}
+	if err := batch.Delete(documentKey(memoPrefix, didString)); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// removeMfile removes the mfile document of did and its dependency from store
+func (i *Indexer) removeMfile(didString string) error {
+	did, err := types.ParseMfileDID(didString)
+	if err != nil {
+		return err
+	}
+	batch := i.store.NewBatch()
+	if err := batch.Delete(dependKey(evm.Topic(did.Identifier), mfilePrefix, didString)); err != nil {
+		return err
+	}
+	if err := batch.Delete(documentKey(mfilePrefix, didString)); err != nil {
+		return err
+	}
+	return batch.Write()
+}
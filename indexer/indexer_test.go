@@ -0,0 +1,649 @@
+package indexer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	etypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/memoio/go-did/evm"
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/memotest"
+	"github.com/memoio/go-did/mfile"
+	"github.com/memoio/go-did/types"
+)
+
+// chain is an in-memory chain without the did contracts, blocks are mined by Commit
+type chain struct {
+	*backends.SimulatedBackend
+	key *ecdsa.PrivateKey
+}
+
+func newChain(t *testing.T) *chain {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alloc := core.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(1e18)}}
+	return &chain{SimulatedBackend: backends.NewSimulatedBackend(alloc, 8000000), key: key}
+}
+
+func newIndexer(t *testing.T, chain *chain, store Store, opts Options) *Indexer {
+	indexer, err := NewIndexer("dev", chain, &memo.ContractAddress{AccountDidAddr: common.HexToAddress("0x01")},
+		&mfile.ContractAddress{FileDidAddr: common.HexToAddress("0x02")}, store, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return indexer
+}
+
+// transfer sends a transaction and mines it, so that the block differs from the one of another fork
+func transfer(t *testing.T, chain *chain) {
+	nonce, err := chain.PendingNonceAt(context.TODO(), crypto.PubkeyToAddress(chain.key.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gasPrice, err := chain.SuggestGasPrice(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := etypes.NewTransaction(nonce, common.HexToAddress("0x03"), big.NewInt(1), 21000, gasPrice, nil)
+	tx, err = etypes.SignTx(tx, etypes.LatestSignerForChainID(params.AllEthashProtocolChanges.ChainID), chain.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = chain.SendTransaction(context.TODO(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.Commit()
+}
+
+func header(t *testing.T, chain *chain, number uint64) *etypes.Header {
+	header, err := chain.HeaderByNumber(context.TODO(), new(big.Int).SetUint64(number))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header
+}
+
+// addEvent saves an event of kind and topic at block number as indexed from a log
+func addEvent(t *testing.T, store Store, topic common.Hash, number uint64, index uint, kind, id string) {
+	batch := store.NewBatch()
+	err := put(batch, eventKey(topic, number, index), &event{Kind: kind, ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = batch.Put(undoKey(number, topic, index), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = batch.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func eventIDs(t *testing.T, store Store, topic common.Hash, number uint64) []string {
+	events, err := events(store, topic, number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+// documents are the documents on a chain without the did contracts, the indexers created by
+// withDocuments read them instead of calling the contracts
+type documents struct {
+	memo  memotest.Documents
+	mfile map[string]*types.MfileDIDDocument
+	// expire is the last block where the delegations are not expired
+	expire uint64
+	// reads counts the documents read from chain
+	reads int
+}
+
+// withDocuments makes indexer read the documents of d
+func (d *documents) withDocuments(indexer *Indexer) *Indexer {
+	indexer.memoAt = d.memoAt
+	indexer.mfileAt = d.mfileAt
+	return indexer
+}
+
+func (d *documents) memoAt(ctx context.Context, did *types.MemoDID, block *big.Int, relationships *memo.Relationships) (*types.MemoDIDDocument, error) {
+	d.reads++
+	document, ok := d.memo[did.Identifier]
+	if !ok {
+		// the did is not registered
+		return &types.MemoDIDDocument{ID: *did}, nil
+	}
+	if document.ID.Identifier == "" {
+		// the did is deactivated
+		return &types.MemoDIDDocument{}, nil
+	}
+
+	result := *document
+	urls := func(ids []string) ([]types.MemoDIDUrl, error) {
+		var urls []types.MemoDIDUrl
+		for _, id := range ids {
+			url, err := types.ParseMemoDIDUrl(id)
+			if err != nil {
+				return nil, err
+			}
+			urls = append(urls, *url)
+		}
+		return urls, nil
+	}
+	authentications, err := urls(relationships.Authentication)
+	if err != nil {
+		return nil, err
+	}
+	result.Authentication = append(append([]types.MemoDIDUrl{}, document.Authentication...), authentications...)
+	if block.Uint64() <= d.expire {
+		result.CapabilityDelegation, err = urls(relationships.CapabilityDelegation)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+func (d *documents) mfileAt(ctx context.Context, did *types.MfileDID, block *big.Int, reads []string) (*types.MfileDIDDocument, error) {
+	d.reads++
+	document, ok := d.mfile[did.Identifier]
+	if !ok {
+		// the did is not registered
+		return &types.MfileDIDDocument{ID: *did}, nil
+	}
+
+	result := *document
+	result.Read = nil
+	for _, read := range reads {
+		reader, err := types.ParseMemoDID(read)
+		if err != nil {
+			return nil, err
+		}
+		result.Read = append(result.Read, *reader)
+	}
+	return &result, nil
+}
+
+func TestEvents(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+
+	topic, other := evm.Topic("a"), evm.Topic("b")
+	addEvent(t, store, topic, 10, 0, eventAddAuth, "3")
+	addEvent(t, store, topic, 2, 1, eventAddAuth, "2")
+	addEvent(t, store, topic, 2, 0, eventAddAuth, "1")
+	addEvent(t, store, other, 1, 0, eventAddAuth, "other")
+	addEvent(t, store, topic, 256, 0, eventAddAuth, "4")
+
+	// events are in the order of blocks and logs
+	if ids := eventIDs(t, store, topic, 10); len(ids) != 3 || ids[0] != "1" || ids[1] != "2" || ids[2] != "3" {
+		t.Fatalf("unexpected events %v", ids)
+	}
+	if ids := eventIDs(t, store, topic, 9); len(ids) != 2 {
+		t.Fatalf("events after block 9 should not be read: %v", ids)
+	}
+	if ids := eventIDs(t, store, topic, 1000); len(ids) != 4 || ids[3] != "4" {
+		t.Fatalf("unexpected events %v", ids)
+	}
+	if ids := eventIDs(t, store, evm.Topic("c"), 1000); len(ids) != 0 {
+		t.Fatalf("unexpected events %v", ids)
+	}
+}
+
+func TestSync(t *testing.T) {
+	chain := newChain(t)
+	defer chain.Close()
+	for i := 0; i < 5; i++ {
+		chain.Commit()
+	}
+
+	store := NewMemoryStore()
+	indexer := newIndexer(t, chain, store, Options{MaxRange: 2, ReorgDepth: 4})
+	defer indexer.Close()
+
+	checkpoint, err := indexer.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Number != 5 || checkpoint.Hash != header(t, chain, 5).Hash() {
+		t.Fatalf("unexpected checkpoint %+v", checkpoint)
+	}
+	saved, err := indexer.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *saved != *checkpoint {
+		t.Fatalf("checkpoint saved %+v is not %+v", saved, checkpoint)
+	}
+	// only the hashes of recent blocks are kept
+	for number := uint64(0); number <= 5; number++ {
+		ok, err := store.Has(numberKey(blockPrefix, number))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != (number > 1) {
+			t.Fatalf("hash of block %d should be kept: %t", number, number > 1)
+		}
+	}
+
+	// events indexed before and after the block where the chain forks
+	topic := evm.Topic("a")
+	addEvent(t, store, topic, 3, 0, eventAddAuth, "kept")
+	addEvent(t, store, topic, 5, 0, eventAddAuth, "reorged")
+
+	err = chain.Fork(context.TODO(), header(t, chain, 3).Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, chain)
+	chain.Commit()
+	chain.Commit()
+	if header(t, chain, 5).Hash() == checkpoint.Hash {
+		t.Fatal("chain is not reorged")
+	}
+
+	checkpoint, err = indexer.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Number != 6 || checkpoint.Hash != header(t, chain, 6).Hash() {
+		t.Fatalf("unexpected checkpoint after reorg %+v", checkpoint)
+	}
+	if ids := eventIDs(t, store, topic, 6); len(ids) != 1 || ids[0] != "kept" {
+		t.Fatalf("events of blocks reorged should be removed: %v", ids)
+	}
+
+	// the chain forks before the blocks kept
+	err = chain.Fork(context.TODO(), header(t, chain, 1).Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, chain)
+	for i := 0; i < 6; i++ {
+		chain.Commit()
+	}
+	_, err = indexer.Sync(context.TODO())
+	if !errors.Is(err, ErrReorgTooDeep) {
+		t.Fatalf("reorg before the blocks kept should not be indexed: %v", err)
+	}
+}
+
+func TestConfirmations(t *testing.T) {
+	chain := newChain(t)
+	defer chain.Close()
+
+	indexer := newIndexer(t, chain, NewMemoryStore(), Options{From: 1, Confirmations: 2})
+	defer indexer.Close()
+
+	checkpoint, err := indexer.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint != nil {
+		t.Fatalf("blocks without enough confirmations should not be indexed: %+v", checkpoint)
+	}
+
+	for i := 0; i < 4; i++ {
+		chain.Commit()
+	}
+	checkpoint, err = indexer.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint == nil || checkpoint.Number != 2 {
+		t.Fatalf("unexpected checkpoint %+v", checkpoint)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	chain := newChain(t)
+	defer chain.Close()
+	for i := 0; i < 3; i++ {
+		chain.Commit()
+	}
+
+	path := filepath.Join(t.TempDir(), "index")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexer := newIndexer(t, chain, store, Options{})
+	checkpoint, err := indexer.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addEvent(t, store, evm.Topic("a"), 2, 0, eventAddAuth, "saved")
+	err = indexer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// indexing continues from the checkpoint saved
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexer = newIndexer(t, chain, store, Options{})
+	defer indexer.Close()
+
+	saved, err := indexer.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved == nil || *saved != *checkpoint {
+		t.Fatalf("checkpoint saved %+v is not %+v", saved, checkpoint)
+	}
+	if ids := eventIDs(t, store, evm.Topic("a"), 3); len(ids) != 1 || ids[0] != "saved" {
+		t.Fatalf("unexpected events %v", ids)
+	}
+
+	chain.Commit()
+	checkpoint, err = indexer.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Number != 4 {
+		t.Fatalf("unexpected checkpoint %+v", checkpoint)
+	}
+}
+
+func TestMemoResolver(t *testing.T) {
+	chain := newChain(t)
+	defer chain.Close()
+	for i := 0; i < 3; i++ {
+		chain.Commit()
+	}
+
+	d := &documents{memo: memotest.Documents{}}
+	a := d.memo.NewIdentity(t, strings.Repeat("ab", 32))
+	b := d.memo.NewIdentity(t, strings.Repeat("cd", 32))
+	store := NewMemoryStore()
+	indexer := d.withDocuments(newIndexer(t, chain, store, Options{}))
+	defer indexer.Close()
+	_, err := indexer.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a key of b is added to the authentication of a at block 2
+	addEvent(t, store, evm.Topic(a.DID.Identifier), 2, 0, eventAddAuth, b.Methods[0].String())
+
+	resolver := indexer.MemoResolver()
+	document, err := resolver.Resolve(a.DID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Authentication) != 2 || document.Authentication[1].String() != b.Methods[0].String() {
+		t.Fatalf("unexpected authentication %v", document.Authentication)
+	}
+	// the document is materialized at the checkpoint with the dids it depends on
+	var record memoRecord
+	ok, err := get(store, documentKey(memoPrefix, a.DID.String()), &record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || record.Block != 3 || len(record.Topics) != 2 || record.Topics[1] != evm.Topic(b.DID.Identifier) {
+		t.Fatalf("unexpected record %+v", record)
+	}
+	_, err = resolver.Resolve(a.DID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.reads != 1 {
+		t.Fatalf("document saved should not be read from chain again, read %d times", d.reads)
+	}
+
+	// versions before the checkpoint are read with the events up to them
+	document, err = resolver.Resolve(a.DID.String() + "?versionId=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Authentication) != 1 {
+		t.Fatalf("events after the version should not be read: %v", document.Authentication)
+	}
+
+	// the document is materialized again when the events of a did it depends on are indexed
+	d.memo[a.DID.Identifier].AssertionMethod = nil
+	reads := d.reads
+	err = indexer.refreshTopic(context.TODO(), evm.Topic("other"), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.reads != reads {
+		t.Fatal("documents not depending on topic should not be materialized again")
+	}
+	err = indexer.refreshTopic(context.TODO(), evm.Topic(b.DID.Identifier), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err = resolver.Resolve(a.DID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.AssertionMethod) != 0 || d.reads != reads+1 {
+		t.Fatalf("document should be materialized again once: %v", document.AssertionMethod)
+	}
+
+	// deactivated dids are resolved as empty documents
+	d.memo[a.DID.Identifier] = &types.MemoDIDDocument{}
+	err = indexer.refreshTopic(context.TODO(), evm.Topic(a.DID.Identifier), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err = resolver.Resolve(a.DID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.ID.Identifier != "" {
+		t.Fatalf("deactivated did should be resolved as an empty document: %+v", document)
+	}
+
+	// dids not registered are not saved
+	unknown := strings.Repeat("ef", 32)
+	document, err = resolver.Resolve("did:memo:" + unknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.VerificationMethod) != 0 {
+		t.Fatalf("unexpected document %+v", document)
+	}
+	ok, err = store.Has(documentKey(memoPrefix, "did:memo:"+unknown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("did not registered should not be saved")
+	}
+}
+
+func TestDelegations(t *testing.T) {
+	chain := newChain(t)
+	defer chain.Close()
+	for i := 0; i < 3; i++ {
+		chain.Commit()
+	}
+
+	// the delegations expire after block 4
+	d := &documents{memo: memotest.Documents{}, expire: 4}
+	a := d.memo.NewIdentity(t, strings.Repeat("ab", 32))
+	b := d.memo.NewIdentity(t, strings.Repeat("cd", 32))
+	store := NewMemoryStore()
+	indexer := d.withDocuments(newIndexer(t, chain, store, Options{}))
+	defer indexer.Close()
+	_, err := indexer.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addEvent(t, store, evm.Topic(a.DID.Identifier), 2, 0, eventAddDelegation, b.Methods[0].String())
+
+	resolver := indexer.MemoResolver()
+	resolve := func(did types.MemoDID) *types.MemoDIDDocument {
+		document, err := resolver.Resolve(did.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return document
+	}
+	if document := resolve(a.DID); len(document.CapabilityDelegation) != 1 {
+		t.Fatalf("unexpected delegations %v", document.CapabilityDelegation)
+	}
+	resolve(b.DID)
+	resolve(a.DID)
+	if d.reads != 2 {
+		t.Fatalf("documents should not be materialized again at the same checkpoint, read %d times", d.reads)
+	}
+
+	// documents with delegations are materialized again at a new checkpoint without events
+	chain.Commit()
+	chain.Commit()
+	_, err = indexer.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document := resolve(a.DID); len(document.CapabilityDelegation) != 0 {
+		t.Fatalf("delegations should be expired: %v", document.CapabilityDelegation)
+	}
+	resolve(b.DID)
+	resolve(a.DID)
+	if d.reads != 3 {
+		t.Fatalf("only the document with delegations should be materialized again, read %d times", d.reads)
+	}
+}
+
+func TestMfileResolver(t *testing.T) {
+	chain := newChain(t)
+	defer chain.Close()
+	for i := 0; i < 3; i++ {
+		chain.Commit()
+	}
+
+	controller := types.MemoDID{Method: "memo", Identifier: strings.Repeat("ab", 32)}
+	buyer, granted := "did:memo:"+strings.Repeat("cd", 32), "did:memo:"+strings.Repeat("ef", 32)
+	did, err := types.ParseMfileDID("did:mfile:bafybeibml5uieyxa5tufngvg7fgwbkwvlsuntwbxgtskoqynbt7wlchmfm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &documents{mfile: map[string]*types.MfileDIDDocument{
+		did.Identifier: {ID: *did, Controller: controller, Price: 1},
+	}}
+	store := NewMemoryStore()
+	indexer := d.withDocuments(newIndexer(t, chain, store, Options{}))
+	defer indexer.Close()
+	_, err = indexer.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addEvent(t, store, evm.Topic(did.Identifier), 1, 0, eventGrantRead, granted)
+	addEvent(t, store, evm.Topic(did.Identifier), 2, 0, eventBuyRead, buyer)
+
+	resolver := indexer.MfileResolver()
+	document, err := resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reads bought are before the ones granted
+	if len(document.Read) != 2 || document.Read[0].String() != buyer || document.Read[1].String() != granted {
+		t.Fatalf("unexpected reads %v", document.Read)
+	}
+	_, err = resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.reads != 1 {
+		t.Fatalf("document saved should not be read from chain again, read %d times", d.reads)
+	}
+
+	// the document is materialized again when its events are indexed
+	d.mfile[did.Identifier].Price = 2
+	err = indexer.refreshTopic(context.TODO(), evm.Topic(did.Identifier), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err = resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.Price != 2 {
+		t.Fatalf("document should be materialized again: %+v", document)
+	}
+
+	// dids not registered are not saved
+	unknown, err := types.ParseMfileDID("did:mfile:bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = resolver.Resolve(unknown.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := store.Has(documentKey(mfilePrefix, unknown.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("did not registered should not be saved")
+	}
+}
+
+func TestStart(t *testing.T) {
+	chain := newChain(t)
+	defer chain.Close()
+	for i := 0; i < 5; i++ {
+		chain.Commit()
+	}
+
+	indexer := newIndexer(t, chain, NewMemoryStore(), Options{ReorgDepth: 2, PollInterval: 10 * time.Millisecond})
+	defer indexer.Close()
+	wait := func(done func() bool) {
+		for deadline := time.Now().Add(5 * time.Second); !done(); time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatal("indexer does not sync in time")
+			}
+		}
+	}
+
+	indexer.Start()
+	wait(func() bool {
+		checkpoint, err := indexer.Checkpoint()
+		return err == nil && checkpoint != nil && checkpoint.Number == 5
+	})
+	if err := indexer.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the chain forks before the blocks kept, indexing stops
+	err := chain.Fork(context.TODO(), header(t, chain, 1).Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer(t, chain)
+	for i := 0; i < 6; i++ {
+		chain.Commit()
+	}
+	wait(func() bool {
+		return errors.Is(indexer.Err(), ErrReorgTooDeep)
+	})
+	select {
+	case <-indexer.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("indexing should stop if the reorg is too deep")
+	}
+}
@@ -0,0 +1,168 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/memoio/go-did/evm"
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/mfile"
+	"github.com/memoio/go-did/types"
+)
+
+// MemoResolver resolves memo dids with the documents materialized by an indexer. DIDs on other
+// chains and versions after the checkpoint are resolved from chain by the embedded MemoDIDResolver,
+// and so are the other methods, such as ResolveWithMetadata.
+//
+// Documents are as of the checkpoint, they are updated when the indexer syncs.
+type MemoResolver struct {
+	*memo.MemoDIDResolver
+
+	indexer *Indexer
+}
+
+var _ memo.DIDResolver = &MemoResolver{}
+
+func (r *MemoResolver) Resolve(didString string) (*types.MemoDIDDocument, error) {
+	return r.ResolveContext(context.Background(), didString)
+}
+
+// ResolveContext resolves the document of did at the checkpoint, or the version selected by the
+// versionId or versionTime parameter with the events indexed.
+func (r *MemoResolver) ResolveContext(ctx context.Context, didString string) (*types.MemoDIDDocument, error) {
+	plain, version, err := types.SplitVersion(didString)
+	if err != nil {
+		return nil, err
+	}
+	did, err := types.ParseMemoDID(plain)
+	if err != nil {
+		return nil, err
+	}
+	if did.ChainID() != "" && did.ChainID() != r.Chain() {
+		return r.MemoDIDResolver.ResolveContext(ctx, didString)
+	}
+	checkpoint, err := r.indexer.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil {
+		return r.MemoDIDResolver.ResolveContext(ctx, didString)
+	}
+
+	block, err := evm.VersionBlock(ctx, r.indexer.backend, version)
+	if err != nil {
+		return nil, err
+	}
+	if block != nil {
+		if block.Uint64() > checkpoint.Number {
+			return r.MemoDIDResolver.ResolveContext(ctx, didString)
+		}
+		return r.indexer.memoDocumentAt(ctx, did, block.Uint64())
+	}
+	return r.indexer.memoDocument(ctx, did)
+}
+
+// MfileResolver resolves mfile dids with the documents materialized by an indexer. Versions after
+// the checkpoint are resolved from chain by the embedded MfileDIDResolver, and so are the other
+// methods, such as ResolveWithMetadata.
+//
+// Documents are as of the checkpoint, they are updated when the indexer syncs.
+type MfileResolver struct {
+	*mfile.MfileDIDResolver
+
+	indexer *Indexer
+}
+
+func (r *MfileResolver) Resolve(didString string) (*types.MfileDIDDocument, error) {
+	return r.ResolveContext(context.Background(), didString)
+}
+
+// ResolveContext resolves the document of did at the checkpoint, or the version selected by the
+// versionId or versionTime parameter with the events indexed.
+func (r *MfileResolver) ResolveContext(ctx context.Context, didString string) (*types.MfileDIDDocument, error) {
+	plain, version, err := types.SplitVersion(didString)
+	if err != nil {
+		return nil, err
+	}
+	did, err := types.ParseMfileDID(plain)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint, err := r.indexer.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil {
+		return r.MfileDIDResolver.ResolveContext(ctx, didString)
+	}
+
+	block, err := evm.VersionBlock(ctx, r.indexer.backend, version)
+	if err != nil {
+		return nil, err
+	}
+	if block != nil {
+		if block.Uint64() > checkpoint.Number {
+			return r.MfileDIDResolver.ResolveContext(ctx, didString)
+		}
+		return r.indexer.mfileDocumentAt(ctx, did, block.Uint64())
+	}
+	return r.indexer.mfileDocument(ctx, did)
+}
+
+// memoDocument returns the document of did saved in store, it is materialized at the checkpoint if it
+// is not saved yet. Delegations expire by time, so the documents with them are materialized again if
+// they are materialized before the checkpoint.
+func (i *Indexer) memoDocument(ctx context.Context, did *types.MemoDID) (*types.MemoDIDDocument, error) {
+	i.lk.Lock()
+	defer i.lk.Unlock()
+
+	checkpoint, err := i.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	var record memoRecord
+	ok, err := get(i.store, documentKey(memoPrefix, did.String()), &record)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || (record.Document != nil && len(record.Document.CapabilityDelegation) > 0 && record.Block < checkpoint.Number) {
+		r, err := i.materializeMemo(ctx, did, checkpoint.Number)
+		if err != nil {
+			return nil, err
+		}
+		record = *r
+	}
+
+	if record.Deactivated {
+		return &types.MemoDIDDocument{}, nil
+	}
+	return record.Document, nil
+}
+
+// mfileDocument returns the document of did saved in store, it is materialized at the checkpoint if it
+// is not saved yet
+func (i *Indexer) mfileDocument(ctx context.Context, did *types.MfileDID) (*types.MfileDIDDocument, error) {
+	i.lk.Lock()
+	defer i.lk.Unlock()
+
+	checkpoint, err := i.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	var record mfileRecord
+	ok, err := get(i.store, documentKey(mfilePrefix, did.String()), &record)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		r, err := i.materializeMfile(ctx, did, checkpoint.Number)
+		if err != nil {
+			return nil, err
+		}
+		record = *r
+	}
+
+	if record.Deactivated {
+		return &types.MfileDIDDocument{}, nil
+	}
+	return record.Document, nil
+}
@@ -0,0 +1,171 @@
+package indexer_test
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/go-did/indexer"
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/mfile"
+	"github.com/memoio/go-did/simulated"
+	mtypes "github.com/memoio/go-did/types"
+)
+
+// sameJSON reports whether a and b are serialized to the same json
+func sameJSON(t *testing.T, a, b interface{}) bool {
+	aData, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bData, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(aData) == string(bData)
+}
+
+func TestIndexer(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 2)
+	defer chain.Close()
+
+	did, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := memo.NewMemoDIDResolverWithBackend("dev", chain, &contracts.Memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfileResolver, err := mfile.NewMfileDIDResolverWithBackend(chain, &contracts.Mfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = controller.RegisterDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered, err := chain.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := crypto.CompressPubkey(&chain.Keys[1].PublicKey)
+	err = controller.AddVerificationMethod("EcdsaSecp256k1VerificationKey2019", *did, hex.EncodeToString(pk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey, err := did.DIDUrl(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddRelationShip(mtypes.AssertionMethod, masterKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := indexer.NewIndexer("dev", chain, &contracts.Memo, &contracts.Mfile, indexer.NewMemoryStore(), indexer.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	indexed := index.MemoResolver()
+
+	// the document materialized is the same as the one resolved from chain
+	checkpoint, err := index.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := chain.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Number != head.Number.Uint64() {
+		t.Fatalf("checkpoint %d is not the head %s", checkpoint.Number, head.Number)
+	}
+	compare := func(didString string) *mtypes.MemoDIDDocument {
+		expected, err := resolver.Resolve(didString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		document, err := indexed.Resolve(didString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameJSON(t, document, expected) {
+			t.Fatalf("indexed document %v is not %v", document, expected)
+		}
+		return document
+	}
+	document := compare(did.String())
+	if len(document.AssertionMethod) != 1 {
+		t.Fatalf("unexpected document %v", document)
+	}
+	compare(did.String() + "?versionId=" + registered.Number.String())
+
+	// the document saved is updated by the events indexed
+	err = controller.AddRelationShip(mtypes.Authentication, document.VerificationMethod[1].ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = index.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	document = compare(did.String())
+	if len(document.Authentication) != 2 {
+		t.Fatalf("authentication added is not indexed: %v", document)
+	}
+
+	cid := "bafkreiaay2fxn7gplx6a2i47djwfrelwwrd7wcd6ih5ssspzjm3gb3dxna"
+	mfileController, err := mfile.NewMfileDIDControllerWithBackend(chain.Keys[0], chain, chain.ChainID, &contracts.Mfile, "did:mfile:"+cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mfileController.RegisterDID("cid", 0, big.NewInt(10), []string{"test"}, *did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mfileController.AddRelationShip(mtypes.Read, *did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = index.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := mfileResolver.Resolve("did:mfile:" + cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfileDocument, err := index.MfileResolver().Resolve("did:mfile:" + cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameJSON(t, mfileDocument, expected) || len(mfileDocument.Read) != 1 {
+		t.Fatalf("indexed document %v is not %v", mfileDocument, expected)
+	}
+
+	err = controller.DeactivateDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = index.Sync(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err = indexed.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.VerificationMethod) != 0 {
+		t.Fatalf("deactivated did should have an empty document: %v", document)
+	}
+}
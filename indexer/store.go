@@ -0,0 +1,156 @@
+package indexer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/memoio/go-did/types"
+)
+
+// Store is the key-value database that the index is saved in, such as NewMemoryStore and
+// NewFileStore. Other databases implementing ethdb.KeyValueStore of go-ethereum can be used.
+type Store interface {
+	ethdb.KeyValueReader
+	ethdb.Batcher
+	ethdb.Iteratee
+	io.Closer
+}
+
+// NewMemoryStore returns a store in memory, the index is lost after it is closed
+func NewMemoryStore() Store {
+	return memorydb.New()
+}
+
+// NewFileStore opens the leveldb store in directory path, the index is kept after it is closed
+// and indexing continues from its checkpoint when it is opened again.
+func NewFileStore(path string) (Store, error) {
+	return leveldb.New(path, 16, 16, "did/indexer/", false)
+}
+
+// Checkpoint is the last block indexed, indexing continues from the block after it
+type Checkpoint struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// keys of store, numbers are big endian so that they are iterated in order
+var (
+	// checkpoint -> json of Checkpoint
+	checkpointKey = []byte("h")
+	// b + number -> hash of the recent blocks, to find where a reorg forks
+	blockPrefix = []byte("b")
+	// e + topic + number + log index -> json of event, the events of a did in order
+	eventPrefix = []byte("e")
+	// u + number + topic + log index -> nil, to remove the events of the recent blocks reorged
+	undoPrefix = []byte("u")
+	// m + did -> json of memoRecord
+	memoPrefix = []byte("m")
+	// f + did -> json of mfileRecord
+	mfilePrefix = []byte("f")
+	// t + topic + m/f + did -> nil, the documents depending on the events of topic
+	dependPrefix = []byte("t")
+)
+
+// event kinds saved in store, other events of the contracts are not saved
+const (
+	eventAddAuth       = "AddAuth"
+	eventAddAssertion  = "AddAssertion"
+	eventAddDelegation = "AddDelegation"
+	eventAddRecovery   = "AddRecovery"
+	eventBuyRead       = "BuyRead"
+	eventGrantRead     = "GrantRead"
+)
+
+// event is an event adding a method id or a memo did to the relationships of a did
+type event struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+}
+
+// memoRecord is a memo did document materialized at block
+type memoRecord struct {
+	Block       uint64                 `json:"block"`
+	Deactivated bool                   `json:"deactivated,omitempty"`
+	Document    *types.MemoDIDDocument `json:"document,omitempty"`
+	// topics of the events which the document depends on
+	Topics []common.Hash `json:"topics"`
+}
+
+// mfileRecord is a mfile did document materialized at block
+type mfileRecord struct {
+	Block       uint64                  `json:"block"`
+	Deactivated bool                    `json:"deactivated,omitempty"`
+	Document    *types.MfileDIDDocument `json:"document,omitempty"`
+}
+
+func numberKey(prefix []byte, number uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, prefix...), number)
+}
+
+func eventKey(topic common.Hash, number uint64, index uint) []byte {
+	key := append(append([]byte{}, eventPrefix...), topic.Bytes()...)
+	key = binary.BigEndian.AppendUint64(key, number)
+	return binary.BigEndian.AppendUint32(key, uint32(index))
+}
+
+func undoKey(number uint64, topic common.Hash, index uint) []byte {
+	key := numberKey(undoPrefix, number)
+	key = append(key, topic.Bytes()...)
+	return binary.BigEndian.AppendUint32(key, uint32(index))
+}
+
+func dependKey(topic common.Hash, prefix []byte, did string) []byte {
+	key := append(append([]byte{}, dependPrefix...), topic.Bytes()...)
+	return append(append(key, prefix...), did...)
+}
+
+func documentKey(prefix []byte, did string) []byte {
+	return append(append([]byte{}, prefix...), did...)
+}
+
+// get reads the json of key into v, it reports false if key is not found
+func get(store ethdb.KeyValueReader, key []byte, v interface{}) (bool, error) {
+	ok, err := store.Has(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	data, err := store.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// put writes the json of v to key
+func put(w ethdb.KeyValueWriter, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return w.Put(key, data)
+}
+
+// events returns the events of topic up to block number
+func events(store Store, topic common.Hash, number uint64) ([]event, error) {
+	prefix := append(append([]byte{}, eventPrefix...), topic.Bytes()...)
+	it := store.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var events []event
+	for it.Next() {
+		if binary.BigEndian.Uint64(it.Key()[len(prefix):]) > number {
+			break
+		}
+		var e event
+		if err := json.Unmarshal(it.Value(), &e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, it.Error()
+}
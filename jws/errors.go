@@ -0,0 +1,20 @@
+package jws
+
+import "golang.org/x/xerrors"
+
+var (
+	// ErrMalformed is returned if a token is not a compact jws or its claims cannot be decoded
+	ErrMalformed = xerrors.New("malformed token")
+	// ErrUnsupportedAlgorithm is returned if the algorithm is not accepted or does not match the key
+	ErrUnsupportedAlgorithm = xerrors.New("unsupported algorithm")
+	// ErrInvalidSignature is returned if the signature is not signed by the key
+	ErrInvalidSignature = xerrors.New("invalid signature")
+	// ErrInvalidKid is returned if kid is missing or is not a verification method of the issuer
+	ErrInvalidKid = xerrors.New("invalid kid")
+	// ErrExpired is returned if exp of a JWT is passed
+	ErrExpired = xerrors.New("token is expired")
+	// ErrNotValidYet is returned if nbf of a JWT is not reached
+	ErrNotValidYet = xerrors.New("token is not valid yet")
+	// ErrInvalidAudience is returned if aud of a JWT does not include the audience expected
+	ErrInvalidAudience = xerrors.New("invalid audience")
+)
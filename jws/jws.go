@@ -0,0 +1,256 @@
+package jws
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// algorithms of signature
+const (
+	// ES256K is ECDSA of the sha256 hash with secp256k1, the signature is r || s
+	ES256K = "ES256K"
+	// ES256KR is ES256K with the recovery id, the signature is r || s || v
+	ES256KR = "ES256K-R"
+	// EdDSA is ed25519
+	EdDSA = "EdDSA"
+)
+
+// Algorithms returns the algorithms supported by the keys of vtype, the first is preferred
+func Algorithms(vtype string) []string {
+	switch vtype {
+	case types.EcdsaSecp256k1VerificationKey2019:
+		return []string{ES256K, ES256KR}
+	case types.Ed25519VerificationKey2020:
+		return []string{EdDSA}
+	default:
+		return nil
+	}
+}
+
+// Negotiate returns the first of accepted supported by the keys of vtype, or the
+// preferred algorithm of vtype if accepted is empty
+func Negotiate(vtype string, accepted ...string) (string, error) {
+	supported := Algorithms(vtype)
+	if len(supported) == 0 {
+		return "", xerrors.Errorf("%s cannot sign jws: %w", vtype, ErrUnsupportedAlgorithm)
+	}
+	if len(accepted) == 0 {
+		return supported[0], nil
+	}
+	for _, alg := range accepted {
+		for _, s := range supported {
+			if alg == s {
+				return alg, nil
+			}
+		}
+	}
+	return "", xerrors.Errorf("%s supports none of %v: %w", vtype, accepted, ErrUnsupportedAlgorithm)
+}
+
+// Header is the protected header of jws, Kid is the did url of the verification method signing
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// Signer signs jws with the private key of a verification method
+type Signer interface {
+	Algorithm() string
+	// KeyID returns the id of the verification method signing
+	KeyID() types.MemoDIDUrl
+	Sign(signingInput []byte) ([]byte, error)
+}
+
+type es256kSigner struct {
+	kid        types.MemoDIDUrl
+	privateKey *ecdsa.PrivateKey
+}
+
+func NewES256KSigner(kid types.MemoDIDUrl, privateKey *ecdsa.PrivateKey) Signer {
+	return &es256kSigner{kid: kid, privateKey: privateKey}
+}
+
+func (s *es256kSigner) Algorithm() string {
+	return ES256K
+}
+
+func (s *es256kSigner) KeyID() types.MemoDIDUrl {
+	return s.kid
+}
+
+func (s *es256kSigner) Sign(signingInput []byte) ([]byte, error) {
+	hash := sha256.Sum256(signingInput)
+	sig, err := crypto.Sign(hash[:], s.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return sig[:64], nil
+}
+
+type es256krSigner struct {
+	es256kSigner
+}
+
+func NewES256KRSigner(kid types.MemoDIDUrl, privateKey *ecdsa.PrivateKey) Signer {
+	return &es256krSigner{es256kSigner{kid: kid, privateKey: privateKey}}
+}
+
+func (s *es256krSigner) Algorithm() string {
+	return ES256KR
+}
+
+func (s *es256krSigner) Sign(signingInput []byte) ([]byte, error) {
+	hash := sha256.Sum256(signingInput)
+	return crypto.Sign(hash[:], s.privateKey)
+}
+
+type eddsaSigner struct {
+	kid        types.MemoDIDUrl
+	privateKey ed25519.PrivateKey
+}
+
+func NewEdDSASigner(kid types.MemoDIDUrl, privateKey ed25519.PrivateKey) Signer {
+	return &eddsaSigner{kid: kid, privateKey: privateKey}
+}
+
+func (s *eddsaSigner) Algorithm() string {
+	return EdDSA
+}
+
+func (s *eddsaSigner) KeyID() types.MemoDIDUrl {
+	return s.kid
+}
+
+func (s *eddsaSigner) Sign(signingInput []byte) ([]byte, error) {
+	return ed25519.Sign(s.privateKey, signingInput), nil
+}
+
+// NewSigner returns a signer of privateKey, which is *ecdsa.PrivateKey or ed25519.PrivateKey,
+// with the algorithm negotiated from accepted
+func NewSigner(kid types.MemoDIDUrl, privateKey interface{}, accepted ...string) (Signer, error) {
+	switch privateKey := privateKey.(type) {
+	case *ecdsa.PrivateKey:
+		alg, err := Negotiate(types.EcdsaSecp256k1VerificationKey2019, accepted...)
+		if err != nil {
+			return nil, err
+		}
+		if alg == ES256KR {
+			return NewES256KRSigner(kid, privateKey), nil
+		}
+		return NewES256KSigner(kid, privateKey), nil
+	case ed25519.PrivateKey:
+		if _, err := Negotiate(types.Ed25519VerificationKey2020, accepted...); err != nil {
+			return nil, err
+		}
+		return NewEdDSASigner(kid, privateKey), nil
+	default:
+		return nil, xerrors.Errorf("private key %T cannot sign jws: %w", privateKey, ErrUnsupportedAlgorithm)
+	}
+}
+
+// Sign returns the compact jws of payload signed by signer, typ is the type of payload such as JWT
+func Sign(typ string, payload []byte, signer Signer) (string, error) {
+	kid := signer.KeyID()
+	header, err := json.Marshal(Header{Alg: signer.Algorithm(), Typ: typ, Kid: kid.String()})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sig, err := signer.Sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// JWS is a compact jws parsed, whose signature is not verified
+type JWS struct {
+	Header  Header
+	Payload []byte
+
+	signingInput []byte
+	signature    []byte
+}
+
+// Parse parses a compact jws
+func Parse(token string) (*JWS, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, xerrors.Errorf("compact jws should have 3 parts, not %d: %w", len(parts), ErrMalformed)
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, xerrors.Errorf("invalid jws header: %v: %w", err, ErrMalformed)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, xerrors.Errorf("invalid jws payload: %v: %w", err, ErrMalformed)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, xerrors.Errorf("invalid jws signature: %v: %w", err, ErrMalformed)
+	}
+
+	jws := &JWS{
+		Payload:      payload,
+		signingInput: []byte(parts[0] + "." + parts[1]),
+		signature:    signature,
+	}
+	if err := json.Unmarshal(header, &jws.Header); err != nil {
+		return nil, xerrors.Errorf("invalid jws header: %v: %w", err, ErrMalformed)
+	}
+	return jws, nil
+}
+
+// Verify verifies the signature of jws with publicKey, the algorithm in header
+// should match the type of publicKey.
+func (j *JWS) Verify(publicKey types.PublicKey) error {
+	key, err := publicKey.Bytes()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case j.Header.Alg == ES256K && publicKey.Type == types.EcdsaSecp256k1VerificationKey2019:
+		hash := sha256.Sum256(j.signingInput)
+		if len(j.signature) != 64 || !crypto.VerifySignature(key, hash[:], j.signature) {
+			return xerrors.Errorf("%s: %w", j.Header.Alg, ErrInvalidSignature)
+		}
+	case j.Header.Alg == ES256KR && publicKey.Type == types.EcdsaSecp256k1VerificationKey2019:
+		hash := sha256.Sum256(j.signingInput)
+		if len(j.signature) != 65 {
+			return xerrors.Errorf("%s: %w", j.Header.Alg, ErrInvalidSignature)
+		}
+		recovered, err := crypto.SigToPub(hash[:], j.signature)
+		if err != nil || !sameSecp256k1Key(recovered, key) {
+			return xerrors.Errorf("%s: %w", j.Header.Alg, ErrInvalidSignature)
+		}
+	case j.Header.Alg == EdDSA && publicKey.Type == types.Ed25519VerificationKey2020:
+		if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, j.signingInput, j.signature) {
+			return xerrors.Errorf("%s: %w", j.Header.Alg, ErrInvalidSignature)
+		}
+	default:
+		return xerrors.Errorf("algorithm %s is not supported by %s: %w", j.Header.Alg, publicKey.Type, ErrUnsupportedAlgorithm)
+	}
+	return nil
+}
+
+// sameSecp256k1Key reports whether key is the compressed or uncompressed form of publicKey
+func sameSecp256k1Key(publicKey *ecdsa.PublicKey, key []byte) bool {
+	if len(key) == 33 {
+		return bytes.Equal(crypto.CompressPubkey(publicKey), key)
+	}
+	return bytes.Equal(crypto.FromECDSAPub(publicKey), key)
+}
@@ -0,0 +1,292 @@
+package jws
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	did, err := types.ParseMemoDID("did:memo:" + strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, err := did.DIDUrl(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secp256k1Key := types.NewPublicKey(types.EcdsaSecp256k1VerificationKey2019, crypto.CompressPubkey(&sk.PublicKey), types.PublicKeyJwkFormat)
+	ed25519Key := types.NewPublicKey(types.Ed25519VerificationKey2020, edPublicKey, types.PublicKeyMultibaseFormat)
+
+	for _, test := range []struct {
+		signer    Signer
+		publicKey types.PublicKey
+		other     types.PublicKey
+	}{
+		{NewES256KSigner(kid, sk), secp256k1Key, ed25519Key},
+		{NewES256KRSigner(kid, sk), secp256k1Key, ed25519Key},
+		{NewEdDSASigner(kid, edPrivateKey), ed25519Key, secp256k1Key},
+	} {
+		token, err := Sign("JWT", []byte(`{"iss":"did:memo:test"}`), test.signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jws, err := Parse(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if jws.Header.Alg != test.signer.Algorithm() || jws.Header.Typ != "JWT" || jws.Header.Kid != kid.String() {
+			t.Fatalf("unexpected header %+v", jws.Header)
+		}
+		if string(jws.Payload) != `{"iss":"did:memo:test"}` {
+			t.Fatalf("unexpected payload %s", jws.Payload)
+		}
+		if err := jws.Verify(test.publicKey); err != nil {
+			t.Fatal(err)
+		}
+		if err := jws.Verify(test.other); err == nil {
+			t.Fatalf("%s should not be verified by %s", jws.Header.Alg, test.other.Type)
+		}
+
+		parts := strings.Split(token, ".")
+		tampered, err := Parse(parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"did:memo:other"}`)) + "." + parts[2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tampered.Verify(test.publicKey) == nil {
+			t.Fatal("tampered jws should not be verified")
+		}
+	}
+
+	_, err = Parse("a.b")
+	if err == nil {
+		t.Fatal("jws with 2 parts should not be parsed")
+	}
+}
+
+// keys dereferences the keys in memory
+type keys map[string][]types.PublicKey
+
+func (k keys) DereferenceContext(ctx context.Context, didUrlString string) ([]types.PublicKey, error) {
+	publicKeys, ok := k[didUrlString]
+	if !ok {
+		return nil, xerrors.Errorf("%s is not found", didUrlString)
+	}
+	return publicKeys, nil
+}
+
+func TestNegotiate(t *testing.T) {
+	alg, err := Negotiate(types.EcdsaSecp256k1VerificationKey2019)
+	if err != nil || alg != ES256K {
+		t.Fatalf("secp256k1 negotiates %s %v", alg, err)
+	}
+	alg, err = Negotiate(types.EcdsaSecp256k1VerificationKey2019, EdDSA, ES256KR, ES256K)
+	if err != nil || alg != ES256KR {
+		t.Fatalf("secp256k1 negotiates %s %v", alg, err)
+	}
+	_, err = Negotiate(types.Ed25519VerificationKey2020, ES256K)
+	if !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("ed25519 should not negotiate ES256K: %v", err)
+	}
+	_, err = Negotiate(types.X25519KeyAgreementKey2020)
+	if !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("x25519 should not sign: %v", err)
+	}
+
+	did, err := types.ParseMemoDID("did:memo:" + strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := did.DIDUrl(0)
+	sk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewSigner(kid, sk, ES256KR)
+	if err != nil || signer.Algorithm() != ES256KR {
+		t.Fatalf("signer of secp256k1 key is %v %v", signer, err)
+	}
+	_, edPrivateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err = NewSigner(kid, edPrivateKey)
+	if err != nil || signer.Algorithm() != EdDSA {
+		t.Fatalf("signer of ed25519 key is %v %v", signer, err)
+	}
+	_, err = NewSigner(kid, edPrivateKey, ES256K, ES256KR)
+	if !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("ed25519 key should not sign ES256K: %v", err)
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	did, err := types.ParseMemoDID("did:memo:" + strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey, _ := did.DIDUrl(0)
+	edKey, _ := did.DIDUrl(2)
+	authentication := types.MemoDIDUrl{Method: did.Method, Identifier: did.Identifier, Identidiers: did.Identifiers, Fragment: "authentication"}
+	recovery := types.MemoDIDUrl{Method: did.Method, Identifier: did.Identifier, Identidiers: did.Identifiers, Fragment: "recovery"}
+
+	sk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secp256k1Key := types.NewPublicKey(types.EcdsaSecp256k1VerificationKey2019, crypto.CompressPubkey(&sk.PublicKey), types.PublicKeyHexFormat)
+	ed25519Key := types.NewPublicKey(types.Ed25519VerificationKey2020, edPublicKey, types.PublicKeyMultibaseFormat)
+	// the recovery key is the master key of another did
+	recoveryKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := keys{
+		masterKey.String():      {secp256k1Key},
+		edKey.String():          {ed25519Key},
+		authentication.String(): {secp256k1Key, ed25519Key},
+		recovery.String():       {types.NewPublicKey(types.EcdsaSecp256k1VerificationKey2019, crypto.CompressPubkey(&recoveryKey.PublicKey), types.PublicKeyHexFormat)},
+	}
+	verifier := NewVerifier(resolver)
+
+	now := time.Now().Unix()
+	claims := &Claims{
+		Subject:   "did:memo:" + strings.Repeat("cd", 32),
+		Audience:  Audience{"gateway.metamemo.one"},
+		ExpiresAt: now + 60,
+		NotBefore: now,
+		IssuedAt:  now,
+		Extra:     map[string]interface{}{"scope": "mfile:read"},
+	}
+	for _, signer := range []Signer{
+		NewES256KSigner(masterKey, sk),
+		NewES256KRSigner(masterKey, sk),
+		NewEdDSASigner(edKey, edPrivateKey),
+	} {
+		token, err := SignJWT(claims, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verified, err := verifier.VerifyJWT(token, "gateway.metamemo.one")
+		if err != nil {
+			t.Fatalf("%s: %v", signer.Algorithm(), err)
+		}
+		if verified.Issuer != did.String() || verified.Subject != claims.Subject || verified.Extra["scope"] != "mfile:read" {
+			t.Fatalf("unexpected claims %+v", verified)
+		}
+
+		_, err = verifier.VerifyJWT(token, "other.metamemo.one")
+		if !errors.Is(err, ErrInvalidAudience) {
+			t.Fatalf("JWT should not be verified for other audience: %v", err)
+		}
+	}
+
+	// kid is a relationship, whose keys may be of other dids
+	for _, signer := range []Signer{
+		NewEdDSASigner(authentication, edPrivateKey),
+		NewES256KSigner(recovery, recoveryKey),
+	} {
+		token, err := SignJWT(claims, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kid := signer.KeyID()
+		_, err = verifier.VerifyJWT(token, "")
+		if !errors.Is(err, ErrInvalidKid) {
+			t.Fatalf("JWT signed by %s should not be verified: %v", kid.String(), err)
+		}
+	}
+
+	// kid refers to a key of other type
+	token, err := SignJWT(claims, NewEdDSASigner(masterKey, edPrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyJWT(token, "")
+	if !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("EdDSA should not be verified by secp256k1 key: %v", err)
+	}
+	// kid refers to another key
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err = SignJWT(claims, NewES256KRSigner(masterKey, other))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyJWT(token, "")
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("JWT signed by other key should not be verified: %v", err)
+	}
+	// the algorithm is not accepted
+	token, err = SignJWT(claims, NewES256KSigner(masterKey, sk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewVerifier(resolver, EdDSA).VerifyJWT(token, "")
+	if !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Fatalf("ES256K should not be accepted: %v", err)
+	}
+	// iss is not the did of kid
+	issued := *claims
+	issued.Issuer = "did:memo:" + strings.Repeat("cd", 32)
+	_, err = SignJWT(&issued, NewES256KSigner(masterKey, sk))
+	if !errors.Is(err, ErrInvalidKid) {
+		t.Fatalf("JWT of other issuer should not be signed: %v", err)
+	}
+
+	expired := *claims
+	expired.ExpiresAt = now - 10
+	token, err = SignJWT(&expired, NewES256KSigner(masterKey, sk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyJWT(token, "")
+	if !errors.Is(err, ErrExpired) {
+		t.Fatalf("expired JWT should not be verified: %v", err)
+	}
+	verifier.Leeway = time.Minute
+	_, err = verifier.VerifyJWT(token, "")
+	if err != nil {
+		t.Fatalf("JWT expired within leeway should be verified: %v", err)
+	}
+	verifier.Leeway = 0
+
+	notBefore := *claims
+	notBefore.NotBefore = now + 60
+	token, err = SignJWT(&notBefore, NewES256KSigner(masterKey, sk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyJWT(token, "")
+	if !errors.Is(err, ErrNotValidYet) {
+		t.Fatalf("JWT should not be verified before nbf: %v", err)
+	}
+
+	_, err = verifier.VerifyJWT("a.b", "")
+	if !errors.Is(err, ErrMalformed) {
+		t.Fatalf("malformed JWT should not be verified: %v", err)
+	}
+}
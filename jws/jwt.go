@@ -0,0 +1,131 @@
+package jws
+
+import (
+	"encoding/json"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// Audience is the aud claim, which is a string or an array of strings
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var audience string
+	if err := json.Unmarshal(data, &audience); err == nil {
+		*a = Audience{audience}
+		return nil
+	}
+	var audiences []string
+	if err := json.Unmarshal(data, &audiences); err != nil {
+		return err
+	}
+	*a = audiences
+	return nil
+}
+
+// Contains reports whether audience is one of a
+func (a Audience) Contains(audience string) bool {
+	for _, aud := range a {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims are the registered claims of JWT, the times are unix seconds and are not
+// checked if they are 0. Extra keeps the other claims.
+type Claims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+
+	Extra map[string]interface{} `json:"-"`
+}
+
+type registeredClaims Claims
+
+var registeredNames = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+func (c Claims) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(registeredClaims(c))
+	if err != nil || len(c.Extra) == 0 {
+		return data, err
+	}
+
+	claims := make(map[string]interface{}, len(c.Extra))
+	for name, value := range c.Extra {
+		claims[name] = value
+	}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, err
+	}
+	return json.Marshal(claims)
+}
+
+func (c *Claims) UnmarshalJSON(data []byte) error {
+	var registered registeredClaims
+	if err := json.Unmarshal(data, &registered); err != nil {
+		return err
+	}
+	var extra map[string]interface{}
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return err
+	}
+	for _, name := range registeredNames {
+		delete(extra, name)
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+
+	*c = Claims(registered)
+	c.Extra = extra
+	return nil
+}
+
+// Validate checks the claims are valid at now with leeway for clock skew, and aud includes
+// audience if it is not empty.
+func (c *Claims) Validate(now time.Time, leeway time.Duration, audience string) error {
+	if c.ExpiresAt != 0 && !now.Add(-leeway).Before(time.Unix(c.ExpiresAt, 0)) {
+		return xerrors.Errorf("exp is %s: %w", time.Unix(c.ExpiresAt, 0).UTC().Format(time.RFC3339), ErrExpired)
+	}
+	if c.NotBefore != 0 && now.Add(leeway).Before(time.Unix(c.NotBefore, 0)) {
+		return xerrors.Errorf("nbf is %s: %w", time.Unix(c.NotBefore, 0).UTC().Format(time.RFC3339), ErrNotValidYet)
+	}
+	if audience != "" && !c.Audience.Contains(audience) {
+		return xerrors.Errorf("aud %v does not include %s: %w", []string(c.Audience), audience, ErrInvalidAudience)
+	}
+	return nil
+}
+
+// SignJWT returns the JWT of claims signed by signer, iss is the did of signer if it is empty
+func SignJWT(claims *Claims, signer Signer) (string, error) {
+	kid := signer.KeyID()
+	did := kid.DID()
+	c := *claims
+	if c.Issuer == "" {
+		c.Issuer = did.String()
+	}
+	if c.Issuer != did.String() {
+		return "", xerrors.Errorf("%s is not a verification method of iss %s: %w", kid.String(), c.Issuer, ErrInvalidKid)
+	}
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return Sign("JWT", payload, signer)
+}
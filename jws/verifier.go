@@ -0,0 +1,111 @@
+package jws
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// KeyResolver dereferences the public keys of a did url, such as MemoDIDResolver
+// and CachingResolver of memo
+type KeyResolver interface {
+	DereferenceContext(ctx context.Context, didUrlString string) ([]types.PublicKey, error)
+}
+
+// Verifier verifies jws with the keys dereferenced from their kid
+type Verifier struct {
+	// Leeway is the clock skew allowed when exp and nbf are checked
+	Leeway time.Duration
+
+	resolver   KeyResolver
+	algorithms []string
+}
+
+// NewVerifier creates a verifier accepting algorithms, all supported algorithms are
+// accepted if it is empty
+func NewVerifier(resolver KeyResolver, algorithms ...string) *Verifier {
+	if len(algorithms) == 0 {
+		algorithms = []string{ES256K, ES256KR, EdDSA}
+	}
+	return &Verifier{resolver: resolver, algorithms: algorithms}
+}
+
+// Verify parses token and verifies it with the key of its kid, which is a verification
+// method of a did such as #masterKey or #key-2. Relationships such as #authentication or
+// #recovery are not accepted as kid, since they may refer to the keys of other dids.
+func (v *Verifier) Verify(token string) (*JWS, error) {
+	return v.VerifyContext(context.Background(), token)
+}
+
+func (v *Verifier) VerifyContext(ctx context.Context, token string) (*JWS, error) {
+	jws, err := Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(v.algorithms, jws.Header.Alg) {
+		return nil, xerrors.Errorf("algorithm %s is not accepted: %w", jws.Header.Alg, ErrUnsupportedAlgorithm)
+	}
+	if jws.Header.Kid == "" {
+		return nil, xerrors.Errorf("jws has no kid: %w", ErrInvalidKid)
+	}
+	kid, err := types.ParseMemoDIDUrl(jws.Header.Kid)
+	if err != nil {
+		return nil, xerrors.Errorf("%s: %v: %w", jws.Header.Kid, err, ErrInvalidKid)
+	}
+	if kid.GetMethodIndex() < 0 {
+		return nil, xerrors.Errorf("%s is not a verification method: %w", jws.Header.Kid, ErrInvalidKid)
+	}
+
+	keys, err := v.resolver.DereferenceContext(ctx, jws.Header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	err = xerrors.Errorf("%s has no key: %w", jws.Header.Kid, ErrInvalidKid)
+	for _, key := range keys {
+		if err = jws.Verify(key); err == nil {
+			return jws, nil
+		}
+	}
+	return nil, err
+}
+
+// VerifyJWT verifies token, and checks its claims are valid now and aud includes audience
+// if it is not empty. iss should be the did of kid if it is set.
+func (v *Verifier) VerifyJWT(token string, audience string) (*Claims, error) {
+	return v.VerifyJWTContext(context.Background(), token, audience)
+}
+
+func (v *Verifier) VerifyJWTContext(ctx context.Context, token string, audience string) (*Claims, error) {
+	jws, err := v.VerifyContext(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	var claims Claims
+	if err := json.Unmarshal(jws.Payload, &claims); err != nil {
+		return nil, xerrors.Errorf("invalid JWT claims: %v: %w", err, ErrMalformed)
+	}
+
+	kid, err := types.ParseMemoDIDUrl(jws.Header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if did := kid.DID(); claims.Issuer != "" && claims.Issuer != did.String() {
+		return nil, xerrors.Errorf("%s is not a verification method of iss %s: %w", jws.Header.Kid, claims.Issuer, ErrInvalidKid)
+	}
+	if err := claims.Validate(time.Now(), v.Leeway, audience); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package memo
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	etypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/memoio/go-did/evm"
+	"github.com/memoio/go-did/types"
+)
+
+// CachingResolver caches the documents resolved by a MemoDIDResolver. An entry is
+// invalidated when the account did contract emits an event of any DID it depends on,
+// or it expires. DIDs on other chains are not cached, and neither are the documents with
+// capability delegations, which expire by time without any event.
+//
+// The documents returned are shared by callers, and should not be modified.
+type CachingResolver struct {
+	*MemoDIDResolver
+
+	documents *evm.Cache[*types.MemoDIDDocument]
+	results   *evm.Cache[*types.MemoDIDResolutionResult]
+	watcher   *evm.Watcher
+}
+
+var _ DIDResolver = &CachingResolver{}
+
+// NewCachingResolver caches the documents resolved by resolver, the resolver is
+// closed by Close of the caching resolver.
+func NewCachingResolver(resolver *MemoDIDResolver, opts evm.CacheOptions) (*CachingResolver, error) {
+	c := &CachingResolver{
+		MemoDIDResolver: resolver,
+		documents:       evm.NewCache[*types.MemoDIDDocument](opts),
+		results:         evm.NewCache[*types.MemoDIDResolutionResult](opts),
+	}
+
+	watcher, err := evm.NewWatcher(resolver.backend, []common.Address{resolver.accountAddr}, opts.PollInterval, c.invalidate)
+	if err != nil {
+		return nil, err
+	}
+	c.watcher = watcher
+
+	return c, nil
+}
+
+// invalidate removes the entries depending on the did of log, which is the first indexed argument
+func (c *CachingResolver) invalidate(log etypes.Log) {
+	if len(log.Topics) < 2 {
+		return
+	}
+	c.documents.Invalidate(log.Topics[1])
+	c.results.Invalidate(log.Topics[1])
+}
+
+// Close stops watching events and closes the resolver
+func (c *CachingResolver) Close() {
+	c.watcher.Close()
+	c.MemoDIDResolver.Close()
+}
+
+func (c *CachingResolver) Resolve(didString string) (*types.MemoDIDDocument, error) {
+	return c.ResolveContext(context.Background(), didString)
+}
+
+func (c *CachingResolver) ResolveContext(ctx context.Context, didString string) (*types.MemoDIDDocument, error) {
+	did, ok := c.cacheable(didString)
+	if !ok {
+		return c.MemoDIDResolver.ResolveContext(ctx, didString)
+	}
+	if document, ok := c.documents.Get(didString); ok {
+		return document, nil
+	}
+
+	generation := c.documents.Generation()
+	document, err := c.MemoDIDResolver.ResolveContext(ctx, didString)
+	if err != nil {
+		return nil, err
+	}
+	if !hasDelegation(document) {
+		c.documents.Add(generation, didString, document, Dependencies(did, document)...)
+	}
+	return document, nil
+}
+
+func (c *CachingResolver) ResolveWithMetadata(didString string) (*types.MemoDIDResolutionResult, error) {
+	return c.ResolveWithMetadataContext(context.Background(), didString)
+}
+
+func (c *CachingResolver) ResolveWithMetadataContext(ctx context.Context, didString string) (*types.MemoDIDResolutionResult, error) {
+	did, ok := c.cacheable(didString)
+	if !ok {
+		return c.MemoDIDResolver.ResolveWithMetadataContext(ctx, didString)
+	}
+	if result, ok := c.results.Get(didString); ok {
+		return result, nil
+	}
+
+	generation := c.results.Generation()
+	result, err := c.MemoDIDResolver.ResolveWithMetadataContext(ctx, didString)
+	if err != nil {
+		return nil, err
+	}
+	if !hasDelegation(result.DIDDocument) {
+		c.results.Add(generation, didString, result, Dependencies(did, result.DIDDocument)...)
+	}
+	return result, nil
+}
+
+// cacheable parses didString and reports whether it is resolved on the chain of resolver
+func (c *CachingResolver) cacheable(didString string) (*types.MemoDID, bool) {
+	plain, _, err := types.SplitVersion(didString)
+	if err != nil {
+		return nil, false
+	}
+	did, err := types.ParseMemoDID(plain)
+	if err != nil {
+		return nil, false
+	}
+	if did.ChainID() != "" && did.ChainID() != c.chain {
+		return nil, false
+	}
+	return did, true
+}
+
+// hasDelegation reports whether document has capability delegations
+func hasDelegation(document *types.MemoDIDDocument) bool {
+	return document != nil && len(document.CapabilityDelegation) > 0
+}
+
+// Dependencies returns the topics of did and the DIDs whose verification methods
+// are referred by document, the document changes when the account did contract
+// emits an event of any of them.
+func Dependencies(did *types.MemoDID, document *types.MemoDIDDocument) []common.Hash {
+	topics := []common.Hash{evm.Topic(did.Identifier)}
+	if document == nil {
+		return topics
+	}
+
+	seen := map[string]bool{did.Identifier: true}
+	for _, urls := range [][]types.MemoDIDUrl{document.Authentication, document.AssertionMethod, document.CapabilityDelegation, document.Recovery} {
+		for _, url := range urls {
+			if !seen[url.Identifier] {
+				seen[url.Identifier] = true
+				topics = append(topics, evm.Topic(url.Identifier))
+			}
+		}
+	}
+	return topics
+}
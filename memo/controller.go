@@ -6,157 +6,245 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	etypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/memoio/go-did/evm"
 	"github.com/memoio/go-did/types"
 	"golang.org/x/xerrors"
 
 	com "github.com/memoio/contractsv2/common"
 	"github.com/memoio/contractsv2/go_contracts/erc"
-	inst "github.com/memoio/contractsv2/go_contracts/instance"
 	"github.com/memoio/did-solidity/go-contracts/proxy"
 )
 
-var (
-	checkTxSleepTime = 6 // wait 6s
-	nextBlockTime    = 5 // blocktime
-)
-
 type MemoDIDController struct {
 	did           *types.MemoDID
-	instanceAddr  common.Address
-	endpoint      string
+	chain         string
+	backend       evm.Backend
 	privateKey    *ecdsa.PrivateKey
+	chainID       *big.Int
 	didTransactor *bind.TransactOpts
-	proxyAddr     common.Address
+	sender        *evm.Sender
+	addrs         ContractAddress
+
+	// client dialed by controller, nil if backend is passed in
+	client *evm.Client
 }
 
 var _ DIDController = &MemoDIDController{}
 
-func NewMemoDIDController(privateKey *ecdsa.PrivateKey, chain string) (*MemoDIDController, error) {
-	did, err := CreatMemoDID(privateKey, chain)
-	if err != nil {
-		return nil, err
-	}
-	controller, err := NewMemoDIDControllerWithDID(privateKey, chain, did.String())
-	return controller, err
+func NewMemoDIDController(privateKey *ecdsa.PrivateKey, chain string, opts ...evm.Options) (*MemoDIDController, error) {
+	return NewMemoDIDControllerContext(context.Background(), privateKey, chain, opts...)
+}
+
+func NewMemoDIDControllerContext(ctx context.Context, privateKey *ecdsa.PrivateKey, chain string, opts ...evm.Options) (*MemoDIDController, error) {
+	return newMemoDIDController(ctx, privateKey, chain, "", opts...)
+}
+
+func NewMemoDIDControllerWithDID(privateKey *ecdsa.PrivateKey, chain, didString string, opts ...evm.Options) (*MemoDIDController, error) {
+	return NewMemoDIDControllerWithDIDContext(context.Background(), privateKey, chain, didString, opts...)
 }
 
-func NewMemoDIDControllerWithDID(privateKey *ecdsa.PrivateKey, chain, didString string) (*MemoDIDController, error) {
-	instanceAddr, endpoint := com.GetInsEndPointByChain(chain)
+func NewMemoDIDControllerWithDIDContext(ctx context.Context, privateKey *ecdsa.PrivateKey, chain, didString string, opts ...evm.Options) (*MemoDIDController, error) {
+	return newMemoDIDController(ctx, privateKey, chain, didString, opts...)
+}
 
-	client, err := ethclient.DialContext(context.TODO(), endpoint)
+// newMemoDIDController dials chain once, and creates an unregistered DID on it if didString is empty
+func newMemoDIDController(ctx context.Context, privateKey *ecdsa.PrivateKey, chain, didString string, opts ...evm.Options) (*MemoDIDController, error) {
+	client, addrs, err := dialChain(ctx, chain, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	chainID, err := client.NetworkID(context.Background())
-	if err != nil {
-		chainID = big.NewInt(985)
+	if didString == "" {
+		did, err := CreatMemoDIDWithBackendContext(ctx, privateKey, client)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		didString = did.String()
 	}
 
-	// new instanceIns
-	instanceIns, err := inst.NewInstance(instanceAddr, client)
+	controller, err := NewMemoDIDControllerWithBackend(privateKey, chain, client, client.ChainID(), addrs, didString, opts...)
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
+	controller.client = client
+	return controller, nil
+}
 
-	// get proxyAddr
-	proxyAddr, err := instanceIns.Instances(&bind.CallOpts{}, com.TypeDidProxy)
+// NewMemoDIDControllerWithBackend creates a controller which sends transactions through backend,
+// such as a simulated backend, rather than dialing the endpoint of chain.
+// chain is only used to check chain-qualified DIDs.
+func NewMemoDIDControllerWithBackend(privateKey *ecdsa.PrivateKey, chain string, backend evm.Backend, chainID *big.Int, addrs *ContractAddress, didString string, opts ...evm.Options) (*MemoDIDController, error) {
+	did, err := types.ParseMemoDID(didString)
 	if err != nil {
 		return nil, err
 	}
+	if did.ChainID() != "" && did.ChainID() != chain {
+		return nil, xerrors.Errorf("%s is on chain %s, but controller is on chain %s", didString, did.ChainID(), chain)
+	}
 
 	// new auth
 	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
 	if err != nil {
 		return nil, err
 	}
-	auth.Value = big.NewInt(0)     // in wei
-	auth.GasLimit = uint64(300000) // in units
-	// auth.GasPrice = big.NewInt(1000)
+	auth.Value = big.NewInt(0) // in wei
+	// gas limit and fees are decided by fee policy of options
+	o := evm.GetOptions(opts...)
 
-	did, err := types.ParseMemoDID(didString)
 	return &MemoDIDController{
 		did:           did,
-		instanceAddr:  instanceAddr,
-		endpoint:      endpoint,
+		chain:         chain,
+		backend:       backend,
 		privateKey:    privateKey,
+		chainID:       chainID,
 		didTransactor: auth,
-		proxyAddr:     proxyAddr,
-	}, err
+		sender:        evm.NewSender(auth, o.NewWaiter(backend, evm.ABIs(proxy.ProxyMetaData, proxy.IAccountDidMetaData)...), o.Fee),
+		addrs:         *addrs,
+	}, nil
 }
 
 // Create unregistered DID
-func CreatMemoDID(privateKey *ecdsa.PrivateKey, chain string) (*types.MemoDID, error) {
+func CreatMemoDID(privateKey *ecdsa.PrivateKey, chain string, opts ...evm.Options) (*types.MemoDID, error) {
+	return CreatMemoDIDContext(context.Background(), privateKey, chain, opts...)
+}
+
+func CreatMemoDIDContext(ctx context.Context, privateKey *ecdsa.PrivateKey, chain string, opts ...evm.Options) (*types.MemoDID, error) {
 	_, endpoint := com.GetInsEndPointByChain(chain)
-	client, err := ethclient.DialContext(context.TODO(), endpoint)
+	client, err := evm.GetOptions(opts...).Dial(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 	defer client.Close()
 
+	return CreatMemoDIDWithBackendContext(ctx, privateKey, client)
+}
+
+// Create unregistered DID through backend
+func CreatMemoDIDWithBackend(privateKey *ecdsa.PrivateKey, backend bind.ContractBackend) (*types.MemoDID, error) {
+	return CreatMemoDIDWithBackendContext(context.Background(), privateKey, backend)
+}
+
+func CreatMemoDIDWithBackendContext(ctx context.Context, privateKey *ecdsa.PrivateKey, backend bind.ContractBackend) (*types.MemoDID, error) {
 	publicKey := privateKey.Public()
 	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
 	if !ok {
 		return nil, xerrors.Errorf("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
 	}
 	address := crypto.PubkeyToAddress(*publicKeyECDSA)
-	nonce, err := client.PendingNonceAt(context.TODO(), address)
+	nonce, err := backend.PendingNonceAt(ctx, address)
 	if err != nil {
 		return nil, err
 	}
 
-	identifier := hex.EncodeToString(crypto.Keccak256(binary.AppendUvarint(address.Bytes(), nonce)))
+	return DeriveMemoDID(publicKeyECDSA, binary.AppendUvarint(nil, nonce)), nil
+}
+
+// DeriveMemoDID derives an unregistered DID from publicKey and salt without the chain,
+// the identifier is hex(keccak256(address || salt)), so the same key and salt always
+// derive the same DID. CreatMemoDID uses the uvarint of pending nonce as salt.
+// Use MemoDIDResolver.IsRegistered to check whether it is taken before registering.
+func DeriveMemoDID(publicKey *ecdsa.PublicKey, salt []byte) *types.MemoDID {
+	address := crypto.PubkeyToAddress(*publicKey)
+	identifier := hex.EncodeToString(crypto.Keccak256(address.Bytes(), salt))
 
 	return &types.MemoDID{
 		Method:      "memo",
 		Identifier:  identifier,
 		Identifiers: []string{identifier},
-	}, nil
+	}
+}
+
+// Close closes the client dialed by controller, the backend passed to
+// NewMemoDIDControllerWithBackend is not closed.
+func (c *MemoDIDController) Close() {
+	if c.client != nil {
+		c.client.Close()
+	}
 }
 
 func (c *MemoDIDController) DID() *types.MemoDID {
 	return c.did
 }
 
+// Waiter returns the waiter of transactions sent by controller,
+// its confirmations and timeout can be changed before sending transactions.
+func (c *MemoDIDController) Waiter() *evm.Waiter {
+	return c.sender.Waiter()
+}
+
+// Chain returns the chain that the controller sends transactions to
+func (c *MemoDIDController) Chain() string {
+	return c.chain
+}
+
+// checkChain refuses chain-qualified DIDs that live on other chains
+func (c *MemoDIDController) checkChain(chainID string) error {
+	if chainID != "" && chainID != c.chain {
+		return xerrors.Errorf("did on chain %s cannot be updated by controller on chain %s", chainID, c.chain)
+	}
+	return nil
+}
+
 func (c *MemoDIDController) RegisterDID() error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
+	return c.RegisterDIDContext(context.Background())
+}
+
+func (c *MemoDIDController) RegisterDIDContext(ctx context.Context) error {
+	tx, err := c.RegisterDIDAsync(ctx)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MemoDIDController) RegisterDIDAsync(ctx context.Context) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Get public key from private key
 	publicKey := c.privateKey.Public()
 	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
 	if !ok {
-		return xerrors.Errorf("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
+		return nil, xerrors.Errorf("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
 	}
 	publicKeyBytes := crypto.CompressPubkey(publicKeyECDSA)
 
-	tx, err := proxyIns.CreateDID(c.didTransactor, c.did.Identifier, "EcdsaSecp256k1VerificationKey2019", publicKeyBytes)
+	return c.sender.Send(ctx, "RegisterDID", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.CreateDID(opts, c.did.Identifier, types.EcdsaSecp256k1VerificationKey2019, publicKeyBytes)
+	})
+}
+
+func (c *MemoDIDController) AddVerificationMethod(vtype string, controller types.MemoDID, publicKeyHex string) error {
+	return c.AddVerificationMethodContext(context.Background(), vtype, controller, publicKeyHex)
+}
+
+func (c *MemoDIDController) AddVerificationMethodContext(ctx context.Context, vtype string, controller types.MemoDID, publicKeyHex string) error {
+	tx, err := c.AddVerificationMethodAsync(ctx, vtype, controller, publicKeyHex)
 	if err != nil {
 		return err
 	}
-
-	return CheckTx(c.endpoint, tx.Hash(), "RegisterDID")
+	return tx.Check(ctx)
 }
 
-func (c *MemoDIDController) AddVerificationMethod(vtype string, controller types.MemoDID, publicKeyHex string) error {
-	publicKeyBytes, err := hex.DecodeString(publicKeyHex)
+func (c *MemoDIDController) AddVerificationMethodAsync(ctx context.Context, vtype string, controller types.MemoDID, publicKeyHex string) (*evm.PendingTx, error) {
+	if err := c.checkChain(controller.ChainID()); err != nil {
+		return nil, err
+	}
+
+	publicKeyBytes, err := decodePublicKey(vtype, publicKeyHex)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	publicKey := proxy.IAccountDidPublicKey{
@@ -166,230 +254,427 @@ func (c *MemoDIDController) AddVerificationMethod(vtype string, controller types
 		Deactivated: false,
 	}
 
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer client.Close()
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+	return c.sender.Send(ctx, "AddVerificationMethod", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.AddVeri(opts, c.did.Identifier, publicKey)
+	})
+}
+
+func (c *MemoDIDController) UpdateVerificationMethod(didUrl types.MemoDIDUrl, vtype string, publicKeyHex string) error {
+	return c.UpdateVerificationMethodContext(context.Background(), didUrl, vtype, publicKeyHex)
+}
+
+func (c *MemoDIDController) UpdateVerificationMethodContext(ctx context.Context, didUrl types.MemoDIDUrl, vtype string, publicKeyHex string) error {
+	tx, err := c.UpdateVerificationMethodAsync(ctx, didUrl, vtype, publicKeyHex)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
+
+func (c *MemoDIDController) UpdateVerificationMethodAsync(ctx context.Context, didUrl types.MemoDIDUrl, vtype string, publicKeyHex string) (*evm.PendingTx, error) {
+	if err := c.checkChain(didUrl.ChainID()); err != nil {
+		return nil, err
+	}
+	index := didUrl.GetMethodIndex()
+	if index < 0 {
+		return nil, xerrors.Errorf("%s is not a verification method", didUrl.String())
+	}
+	// the master key signs the transactions of did
+	if index == 0 && vtype != types.EcdsaSecp256k1VerificationKey2019 {
+		return nil, xerrors.Errorf("master key should be %s, not %s", types.EcdsaSecp256k1VerificationKey2019, vtype)
+	}
 
-	tx, err := proxyIns.AddVeri(c.didTransactor, c.did.Identifier, publicKey)
+	publicKeyBytes, err := decodePublicKey(vtype, publicKeyHex)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := c.checkMethodSlot(ctx, didUrl); err != nil {
+		return nil, err
+	}
+
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
+	if err != nil {
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "AddVerificationMethod")
+	return c.sender.Send(ctx, "UpdateVerificationMethod", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.UpdateVeri(opts, didUrl.Identifier, big.NewInt(int64(index)), vtype, publicKeyBytes)
+	})
 }
 
-func (c *MemoDIDController) UpdateVerificationMethod(didUrl types.MemoDIDUrl, vtype string, publicKeyHex string) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
+// decodePublicKey decodes publicKeyHex, with or without 0x prefix, and checks it is a valid key of vtype
+func decodePublicKey(vtype, publicKeyHex string) ([]byte, error) {
+	publicKeyBytes, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(publicKeyHex, "0x"), "0X"))
 	if err != nil {
-		return err
+		return nil, xerrors.Errorf("invalid public key hex: %w", err)
 	}
-	defer client.Close()
+	if err := types.CheckPublicKey(vtype, publicKeyBytes); err != nil {
+		return nil, err
+	}
+	return publicKeyBytes, nil
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MemoDIDController) DeactivateVerificationMethod(didUrl types.MemoDIDUrl) error {
+	return c.DeactivateVerificationMethodContext(context.Background(), didUrl)
+}
+
+func (c *MemoDIDController) DeactivateVerificationMethodContext(ctx context.Context, didUrl types.MemoDIDUrl) error {
+	tx, err := c.DeactivateVerificationMethodAsync(ctx, didUrl)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
+
+func (c *MemoDIDController) DeactivateVerificationMethodAsync(ctx context.Context, didUrl types.MemoDIDUrl) (*evm.PendingTx, error) {
+	if err := c.checkChain(didUrl.ChainID()); err != nil {
+		return nil, err
+	}
+	if err := c.checkMethodSlot(ctx, didUrl); err != nil {
+		return nil, err
+	}
 
-	tx, err := proxyIns.UpdateVeri(c.didTransactor, didUrl.Identifier, big.NewInt(int64(didUrl.GetMethodIndex())), vtype, []byte(publicKeyHex))
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return CheckTx(c.endpoint, tx.Hash(), "UpdateVerificationMethod")
+
+	return c.sender.Send(ctx, "DeactivateVerificationMethod", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.DeactivateVeri(opts, didUrl.Identifier, big.NewInt(int64(didUrl.GetMethodIndex())), true)
+	})
 }
 
-func (c *MemoDIDController) DeactivateVerificationMethod(didUrl types.MemoDIDUrl) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
-	if err != nil {
-		return err
+// checkMethodSlot checks the slot of didUrl saves a verification method, services share the slots
+// and they are changed by UpdateService and RemoveService
+func (c *MemoDIDController) checkMethodSlot(ctx context.Context, didUrl types.MemoDIDUrl) error {
+	index := didUrl.GetMethodIndex()
+	if index < 0 {
+		return xerrors.Errorf("%s is not a verification method", didUrl.String())
 	}
-	defer client.Close()
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+	accountIns, err := proxy.NewIAccountDid(c.addrs.AccountDidAddr, c.backend)
 	if err != nil {
 		return err
 	}
-
-	tx, err := proxyIns.DeactivateVeri(c.didTransactor, didUrl.Identifier, big.NewInt(int64(didUrl.GetMethodIndex())), true)
+	method, err := accountIns.GetVeri(&bind.CallOpts{Context: ctx}, didUrl.Identifier, big.NewInt(int64(index)))
 	if err != nil {
 		return err
 	}
-
-	return CheckTx(c.endpoint, tx.Hash(), "DeactivateVerificationMethod")
+	if method.MethodType == types.ServiceType {
+		return xerrors.Errorf("%s is a service, not a verification method", didUrl.String())
+	}
+	return nil
 }
 
 func (c *MemoDIDController) AddRelationShip(relationType int, didUrl types.MemoDIDUrl, expireTime int64) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
+	return c.AddRelationShipContext(context.Background(), relationType, didUrl, expireTime)
+}
+
+func (c *MemoDIDController) AddRelationShipContext(ctx context.Context, relationType int, didUrl types.MemoDIDUrl, expireTime int64) error {
+	tx, err := c.AddRelationShipAsync(ctx, relationType, didUrl, expireTime)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
+
+func (c *MemoDIDController) AddRelationShipAsync(ctx context.Context, relationType int, didUrl types.MemoDIDUrl, expireTime int64) (*evm.PendingTx, error) {
+	if err := c.checkChain(didUrl.ChainID()); err != nil {
+		return nil, err
+	}
+	// chain id is implied by the contract, so save did url without it
+	didUrl = didUrl.WithChainID("")
+	if err := c.checkMethodSlot(ctx, didUrl); err != nil {
+		return nil, err
+	}
+
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.sender.Send(ctx, "AddRelationShip", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		switch relationType {
+		case types.Authentication:
+			return proxyIns.AddAuth(opts, c.did.Identifier, didUrl.String())
+		case types.AssertionMethod:
+			return proxyIns.AddAssertion(opts, c.did.Identifier, didUrl.String())
+		case types.CapabilityDelegation:
+			return proxyIns.AddDelegation(opts, c.did.Identifier, didUrl.String(), big.NewInt(expireTime+time.Now().Unix()))
+		case types.Recovery:
+			return proxyIns.AddRecovery(opts, c.did.Identifier, didUrl.String())
+		default:
+			return nil, xerrors.Errorf("unsupported relation ships")
+		}
+	})
+}
+
+func (c *MemoDIDController) DeactivateRelationShip(relationType int, didUrl types.MemoDIDUrl) error {
+	return c.DeactivateRelationShipContext(context.Background(), relationType, didUrl)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MemoDIDController) DeactivateRelationShipContext(ctx context.Context, relationType int, didUrl types.MemoDIDUrl) error {
+	tx, err := c.DeactivateRelationShipAsync(ctx, relationType, didUrl)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	var tx *etypes.Transaction
-	switch relationType {
-	case types.Authentication:
-		tx, err = proxyIns.AddAuth(c.didTransactor, c.did.Identifier, didUrl.String())
-	case types.AssertionMethod:
-		tx, err = proxyIns.AddAssertion(c.didTransactor, c.did.Identifier, didUrl.String())
-	case types.CapabilityDelegation:
-		tx, err = proxyIns.AddDelegation(c.didTransactor, c.did.Identifier, didUrl.String(), big.NewInt(expireTime+time.Now().Unix()))
-	case types.Recovery:
-		tx, err = proxyIns.AddRecovery(c.didTransactor, c.did.Identifier, didUrl.String())
-	default:
-		return xerrors.Errorf("unsupported relation ships")
+func (c *MemoDIDController) DeactivateRelationShipAsync(ctx context.Context, relationType int, didUrl types.MemoDIDUrl) (*evm.PendingTx, error) {
+	if err := c.checkChain(didUrl.ChainID()); err != nil {
+		return nil, err
 	}
+	didUrl = didUrl.WithChainID("")
+
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "AddRelationShip")
+	return c.sender.Send(ctx, "DeactivateRelationShip", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		switch relationType {
+		case types.Authentication:
+			return proxyIns.RemoveAuth(opts, c.did.Identifier, didUrl.String())
+		case types.AssertionMethod:
+			return proxyIns.RemoveAssertion(opts, c.did.Identifier, didUrl.String())
+		case types.CapabilityDelegation:
+			return proxyIns.RemoveDelegation(opts, c.did.Identifier, didUrl.String())
+		case types.Recovery:
+			return proxyIns.RemoveRecovery(opts, c.did.Identifier, didUrl.String())
+		default:
+			return nil, xerrors.Errorf("unsupported relation ships")
+		}
+	})
 }
 
-func (c *MemoDIDController) DeactivateRelationShip(relationType int, didUrl types.MemoDIDUrl) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
+// AddService publishes service, its id is did#id, #id or id and should not be used by other services
+func (c *MemoDIDController) AddService(service types.Service) error {
+	return c.AddServiceContext(context.Background(), service)
+}
+
+func (c *MemoDIDController) AddServiceContext(ctx context.Context, service types.Service) error {
+	tx, err := c.AddServiceAsync(ctx, service)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MemoDIDController) AddServiceAsync(ctx context.Context, service types.Service) (*evm.PendingTx, error) {
+	id, err := types.ServiceIDOf(*c.did, service.ID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	service.ID = id
+	data, err := types.EncodeService(service)
+	if err != nil {
+		return nil, err
+	}
+	_, found, err := c.findService(ctx, service.ID)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return nil, xerrors.Errorf("service %s already exists", service.ID)
 	}
 
-	var tx *etypes.Transaction
-	switch relationType {
-	case types.Authentication:
-		tx, err = proxyIns.RemoveAuth(c.didTransactor, c.did.Identifier, didUrl.String())
-	case types.AssertionMethod:
-		tx, err = proxyIns.RemoveAssertion(c.didTransactor, c.did.Identifier, didUrl.String())
-	case types.CapabilityDelegation:
-		tx, err = proxyIns.RemoveDelegation(c.didTransactor, c.did.Identifier, didUrl.String())
-	case types.Recovery:
-		tx, err = proxyIns.RemoveRecovery(c.didTransactor, c.did.Identifier, didUrl.String())
-	default:
-		return xerrors.Errorf("unsupported relation ships")
+	publicKey := proxy.IAccountDidPublicKey{
+		MethodType:  types.ServiceType,
+		Controller:  c.did.Identifier,
+		PubKeyData:  data,
+		Deactivated: false,
 	}
+
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "DeactivateRelationShip")
+	return c.sender.Send(ctx, "AddService", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.AddVeri(opts, c.did.Identifier, publicKey)
+	})
 }
 
-func (c *MemoDIDController) ApproveOfMfileContract(amount int) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
+// UpdateService replaces the type and endpoint of the service with the same id
+func (c *MemoDIDController) UpdateService(service types.Service) error {
+	return c.UpdateServiceContext(context.Background(), service)
+}
+
+func (c *MemoDIDController) UpdateServiceContext(ctx context.Context, service types.Service) error {
+	tx, err := c.UpdateServiceAsync(ctx, service)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
 
-	instanceIns, err := inst.NewInstance(c.instanceAddr, client)
+func (c *MemoDIDController) UpdateServiceAsync(ctx context.Context, service types.Service) (*evm.PendingTx, error) {
+	id, err := types.ServiceIDOf(*c.did, service.ID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	service.ID = id
+	data, err := types.EncodeService(service)
+	if err != nil {
+		return nil, err
+	}
+	index, err := c.serviceSlot(ctx, service.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	// get fileDIDCtrAddr
-	fileDIDCtrAddr, err := instanceIns.Instances(&bind.CallOpts{}, com.TypeFileDidControl)
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// get ERC20Addr
-	ERC20Addr, err := instanceIns.Instances(&bind.CallOpts{}, com.TypeERC20)
+	return c.sender.Send(ctx, "UpdateService", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.UpdateVeri(opts, c.did.Identifier, big.NewInt(index), types.ServiceType, data)
+	})
+}
+
+// RemoveService removes the service of id, which is #id or id
+func (c *MemoDIDController) RemoveService(id string) error {
+	return c.RemoveServiceContext(context.Background(), id)
+}
+
+func (c *MemoDIDController) RemoveServiceContext(ctx context.Context, id string) error {
+	tx, err := c.RemoveServiceAsync(ctx, id)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	// get ERC20Ins
-	ERC20Ins, err := erc.NewERC20(ERC20Addr, client)
+func (c *MemoDIDController) RemoveServiceAsync(ctx context.Context, id string) (*evm.PendingTx, error) {
+	index, err := c.serviceSlot(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	tx, err := ERC20Ins.Approve(c.didTransactor, fileDIDCtrAddr, big.NewInt(int64(amount)))
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "ApproveOfMfileContract")
+	return c.sender.Send(ctx, "RemoveService", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.DeactivateVeri(opts, c.did.Identifier, big.NewInt(index), true)
+	})
 }
 
-func (c *MemoDIDController) BuyReadPermission(did types.MfileDID) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
+// serviceSlot returns the index of the verification method slot saving the service of id
+func (c *MemoDIDController) serviceSlot(ctx context.Context, id string) (int64, error) {
+	index, found, err := c.findService(ctx, id)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer client.Close()
+	if !found {
+		return 0, xerrors.Errorf("service %s is not found in %s", id, c.did.String())
+	}
+	return index, nil
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MemoDIDController) findService(ctx context.Context, id string) (int64, bool, error) {
+	id, err := types.ServiceIDOf(*c.did, id)
 	if err != nil {
-		return err
+		return 0, false, err
 	}
 
-	tx, err := proxyIns.BuyRead(c.didTransactor, did.Identifier, c.did.Identifier)
+	accountIns, err := proxy.NewIAccountDid(c.addrs.AccountDidAddr, c.backend)
 	if err != nil {
-		return err
+		return 0, false, err
+	}
+	slots, err := queryServiceSlots(ctx, accountIns, *c.did, nil)
+	if err != nil {
+		return 0, false, err
 	}
+	for _, slot := range slots {
+		if slot.service.ID == c.did.String()+"#"+id {
+			return slot.index, true, nil
+		}
+	}
+	return 0, false, nil
+}
 
-	return CheckTx(c.endpoint, tx.Hash(), "BuyReadPermission")
+func (c *MemoDIDController) ApproveOfMfileContract(amount int) error {
+	return c.ApproveOfMfileContractContext(context.Background(), amount)
 }
 
-func (c *MemoDIDController) DeactivateDID() error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
+func (c *MemoDIDController) ApproveOfMfileContractContext(ctx context.Context, amount int) error {
+	tx, err := c.ApproveOfMfileContractAsync(ctx, amount)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	return tx.Check(ctx)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MemoDIDController) ApproveOfMfileContractAsync(ctx context.Context, amount int) (*evm.PendingTx, error) {
+	// get ERC20Ins
+	ERC20Ins, err := erc.NewERC20(c.addrs.TokenAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	tx, err := proxyIns.DeactivateDID(c.didTransactor, c.did.Identifier, true)
+	return c.sender.Send(ctx, "ApproveOfMfileContract", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return ERC20Ins.Approve(opts, c.addrs.FileDidControlAddr, big.NewInt(int64(amount)))
+	})
+}
+
+func (c *MemoDIDController) BuyReadPermission(did types.MfileDID) error {
+	return c.BuyReadPermissionContext(context.Background(), did)
+}
+
+func (c *MemoDIDController) BuyReadPermissionContext(ctx context.Context, did types.MfileDID) error {
+	tx, err := c.BuyReadPermissionAsync(ctx, did)
 	if err != nil {
 		return err
 	}
-
-	return CheckTx(c.endpoint, tx.Hash(), "DeactivateDID")
+	return tx.Check(ctx)
 }
 
-// CheckTx check whether transaction is successful through receipt
-func CheckTx(endPoint string, txHash common.Hash, name string) error {
-	var receipt *etypes.Receipt
-
-	t := checkTxSleepTime
-	for i := 0; i < 10; i++ {
-		time.Sleep(time.Duration(t) * time.Second)
-		receipt = com.GetTransactionReceipt(endPoint, txHash)
-		if receipt != nil {
-			break
-		}
-		t = nextBlockTime
+func (c *MemoDIDController) BuyReadPermissionAsync(ctx context.Context, did types.MfileDID) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
+	if err != nil {
+		return nil, err
 	}
 
-	if receipt == nil {
-		return xerrors.Errorf("%s: cann't get transaction(%s) receipt, not packaged", name, txHash)
+	return c.sender.Send(ctx, "BuyReadPermission", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.BuyRead(opts, did.Identifier, c.did.Identifier)
+	})
+}
+
+func (c *MemoDIDController) DeactivateDID() error {
+	return c.DeactivateDIDContext(context.Background())
+}
+
+func (c *MemoDIDController) DeactivateDIDContext(ctx context.Context) error {
+	tx, err := c.DeactivateDIDAsync(ctx)
+	if err != nil {
+		return err
 	}
+	return tx.Check(ctx)
+}
 
-	// 0 means fail
-	if receipt.Status == 0 {
-		if receipt.GasUsed != receipt.CumulativeGasUsed {
-			return xerrors.Errorf("%s: transaction(%s) exceed gas limit", name, txHash)
-		}
-		return xerrors.Errorf("%s: transaction(%s) mined but execution failed, please check your tx input", name, txHash)
+func (c *MemoDIDController) DeactivateDIDAsync(ctx context.Context) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.addrs.ProxyAddr, c.backend)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+
+	return c.sender.Send(ctx, "DeactivateDID", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.DeactivateDID(opts, c.did.Identifier, true)
+	})
+}
+
+// CheckTx check whether transaction is successful through receipt
+//
+// Deprecated: use evm.Waiter, the error of which can be inspected.
+func CheckTx(endPoint string, txHash common.Hash, name string) error {
+	return evm.CheckTx(context.TODO(), endPoint, txHash, name, evm.ABIs(proxy.ProxyMetaData, proxy.IAccountDidMetaData)...)
 }
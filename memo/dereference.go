@@ -0,0 +1,122 @@
+package memo
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/memoio/go-did/types"
+)
+
+// DereferenceWithMetadata dereferences didUrlString following the W3C DID URL dereferencing
+// algorithm. The DID is resolved at the version of versionId or versionTime, then
+//   - service selects the service endpoint url, relativeRef is resolved against it
+//   - fragment selects the verification method or service in the document
+//   - otherwise the document is returned
+//
+// Errors are reported in dereferencingMetadata, an error is returned only if chain cannot be read.
+func (r *MemoDIDResolver) DereferenceWithMetadata(didUrlString string) (*types.MemoDIDDereferencingResult, error) {
+	return r.DereferenceWithMetadataContext(context.Background(), didUrlString)
+}
+
+func (r *MemoDIDResolver) DereferenceWithMetadataContext(ctx context.Context, didUrlString string) (*types.MemoDIDDereferencingResult, error) {
+	return dereference(ctx, r.ResolveWithMetadataContext, didUrlString)
+}
+
+// DereferenceWithMetadata dereferences didUrlString with the cached documents
+func (c *CachingResolver) DereferenceWithMetadata(didUrlString string) (*types.MemoDIDDereferencingResult, error) {
+	return c.DereferenceWithMetadataContext(context.Background(), didUrlString)
+}
+
+func (c *CachingResolver) DereferenceWithMetadataContext(ctx context.Context, didUrlString string) (*types.MemoDIDDereferencingResult, error) {
+	return dereference(ctx, c.ResolveWithMetadataContext, didUrlString)
+}
+
+func dereference(ctx context.Context, resolve func(context.Context, string) (*types.MemoDIDResolutionResult, error), didUrlString string) (*types.MemoDIDDereferencingResult, error) {
+	result := &types.MemoDIDDereferencingResult{Context: types.ResolutionContext}
+
+	didUrl, err := types.ParseDIDURL(didUrlString)
+	if err != nil {
+		result.DereferencingMetadata.Error = types.ErrorInvalidDidUrl
+		return result, nil
+	}
+
+	resolution, err := resolve(ctx, didUrl.VersionedDID())
+	if err != nil {
+		return nil, err
+	}
+	result.ContentMetadata = resolution.DIDDocumentMetadata
+	if resolution.DIDResolutionMetadata.Error != "" {
+		result.DereferencingMetadata.Error = resolution.DIDResolutionMetadata.Error
+		return result, nil
+	}
+	document := resolution.DIDDocument
+
+	// memo did has no resource at path
+	if didUrl.Path != "" && didUrl.Path != "/" {
+		result.DereferencingMetadata.Error = types.ErrorNotFound
+		return result, nil
+	}
+
+	if didUrl.Query.Has("service") {
+		endpoint, ok := serviceEndpoint(document, didUrl)
+		if !ok {
+			result.DereferencingMetadata.Error = types.ErrorNotFound
+			return result, nil
+		}
+		result.DereferencingMetadata.ContentType = types.ContentTypeURIList
+		result.ContentStream = endpoint
+		return result, nil
+	}
+
+	if didUrl.Fragment == "" {
+		result.DereferencingMetadata.ContentType = resolution.DIDResolutionMetadata.ContentType
+		result.ContentStream = document
+		return result, nil
+	}
+
+	for i := range document.VerificationMethod {
+		if document.VerificationMethod[i].ID.Fragment == didUrl.Fragment {
+			result.DereferencingMetadata.ContentType = types.ContentTypeDIDLDJSON
+			result.ContentStream = &document.VerificationMethod[i]
+			return result, nil
+		}
+	}
+	for i := range document.Service {
+		if strings.HasSuffix(document.Service[i].ID, "#"+didUrl.Fragment) {
+			result.DereferencingMetadata.ContentType = types.ContentTypeDIDLDJSON
+			result.ContentStream = &document.Service[i]
+			return result, nil
+		}
+	}
+
+	result.DereferencingMetadata.Error = types.ErrorNotFound
+	return result, nil
+}
+
+// serviceEndpoint returns the endpoint url of the service selected by didUrl, with
+// relativeRef resolved against it as RFC 3986 and the fragment of didUrl appended.
+func serviceEndpoint(document *types.MemoDIDDocument, didUrl *types.DIDURL) (string, bool) {
+	for _, service := range document.Service {
+		if !strings.HasSuffix(service.ID, "#"+didUrl.Query.Get("service")) {
+			continue
+		}
+
+		endpoint, err := url.Parse(service.ServiceEndpoint)
+		if err != nil {
+			return "", false
+		}
+		if didUrl.Query.Has("relativeRef") {
+			ref, err := url.Parse(didUrl.Query.Get("relativeRef"))
+			if err != nil {
+				return "", false
+			}
+			endpoint = endpoint.ResolveReference(ref)
+		}
+		if didUrl.Fragment != "" && endpoint.Fragment == "" {
+			endpoint.Fragment = didUrl.Fragment
+		}
+		return endpoint.String(), true
+	}
+	return "", false
+}
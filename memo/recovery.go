@@ -0,0 +1,385 @@
+package memo
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	etypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	com "github.com/memoio/contractsv2/common"
+	"github.com/memoio/did-solidity/go-contracts/proxy"
+	"github.com/memoio/go-did/evm"
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// DefaultRecoveryTTL is how long a recovery request is valid
+var DefaultRecoveryTTL = 24 * time.Hour
+
+var (
+	// ErrRecoveryThreshold is returned if a recovery request is not approved by enough recovery dids
+	ErrRecoveryThreshold = xerrors.New("recovery is not approved by enough recovery dids")
+	// ErrRecoveryExpired is returned if a recovery request is expired
+	ErrRecoveryExpired = xerrors.New("recovery request is expired")
+)
+
+// RecoveryRequest asks to replace the master key of DID with NewMasterKey, it is approved by
+// the signatures of the keys in the recovery of DID. The request is bound to the master key
+// replaced, so that it cannot be used again after recovery.
+type RecoveryRequest struct {
+	DID string `json:"did"`
+	// MasterKey is the address of the master key replaced
+	MasterKey string `json:"masterKey"`
+	// NewMasterKey is the hex of the compressed secp256k1 public key
+	NewMasterKey   string    `json:"newMasterKey"`
+	ExpirationTime time.Time `json:"expirationTime"`
+}
+
+// Message returns the message signed by recovery keys to approve the request
+func (r *RecoveryRequest) Message() []byte {
+	var b strings.Builder
+	b.WriteString("Replace the master key of " + r.DID + "\n\n")
+	b.WriteString("Master Key: " + r.MasterKey + "\n")
+	b.WriteString("New Master Key: " + r.NewMasterKey + "\n")
+	b.WriteString("Expiration Time: " + r.ExpirationTime.UTC().Format(time.RFC3339))
+	return []byte(b.String())
+}
+
+// Approve signs request with signer, which should be a key in the recovery of the did of request
+func (r *RecoveryRequest) Approve(signer types.Signer) (*types.Signature, error) {
+	return signer.Sign(r.Message())
+}
+
+// NewRecoveryRequest returns a request to replace the current master key of did with newMasterKey,
+// which expires after DefaultRecoveryTTL
+func (r *MemoDIDResolver) NewRecoveryRequest(didString string, newMasterKey *ecdsa.PublicKey) (*RecoveryRequest, error) {
+	return r.NewRecoveryRequestContext(context.Background(), didString, newMasterKey)
+}
+
+func (r *MemoDIDResolver) NewRecoveryRequestContext(ctx context.Context, didString string, newMasterKey *ecdsa.PublicKey) (*RecoveryRequest, error) {
+	masterKey, err := r.GetMasterKeyContext(ctx, didString)
+	if err != nil {
+		return nil, err
+	}
+	return &RecoveryRequest{
+		DID:            didString,
+		MasterKey:      masterKey,
+		NewMasterKey:   hexutil.Encode(crypto.CompressPubkey(newMasterKey)),
+		ExpirationTime: time.Now().UTC().Truncate(time.Second).Add(DefaultRecoveryTTL),
+	}, nil
+}
+
+// RecoveryMethods returns the keys in the recovery of did and their public keys
+func (r *MemoDIDResolver) RecoveryMethods(didString string) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	return r.RecoveryMethodsContext(context.Background(), didString)
+}
+
+func (r *MemoDIDResolver) RecoveryMethodsContext(ctx context.Context, didString string) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	did, err := types.ParseMemoDID(didString)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resolver, err := r.route(did.ChainID()); err != nil {
+		return nil, nil, err
+	} else if resolver != r {
+		return resolver.RecoveryMethodsContext(ctx, didString)
+	}
+
+	accountIns, err := proxy.NewIAccountDid(r.accountAddr, r.backend)
+	if err != nil {
+		return nil, nil, err
+	}
+	return QueryAllRecoveryContext(ctx, accountIns, *did)
+}
+
+// CheckRecoveryEligibility checks did can be recovered by threshold recovery dids, that is, it is
+// registered and activated, and the keys in its recovery belong to at least threshold dids.
+func (r *MemoDIDResolver) CheckRecoveryEligibility(didString string, threshold int) error {
+	return r.CheckRecoveryEligibilityContext(context.Background(), didString, threshold)
+}
+
+func (r *MemoDIDResolver) CheckRecoveryEligibilityContext(ctx context.Context, didString string, threshold int) error {
+	if threshold <= 0 {
+		return xerrors.Errorf("recovery threshold should be positive, not %d", threshold)
+	}
+	did, err := types.ParseMemoDID(didString)
+	if err != nil {
+		return err
+	}
+	if resolver, err := r.route(did.ChainID()); err != nil {
+		return err
+	} else if resolver != r {
+		return resolver.CheckRecoveryEligibilityContext(ctx, didString, threshold)
+	}
+
+	registered, err := r.IsRegisteredContext(ctx, didString)
+	if err != nil {
+		return err
+	}
+	if !registered {
+		return xerrors.Errorf("%s is not registered", didString)
+	}
+	accountIns, err := proxy.NewIAccountDid(r.accountAddr, r.backend)
+	if err != nil {
+		return err
+	}
+	deactivated, err := accountIns.IsDeactivated(&bind.CallOpts{Context: ctx}, did.Identifier)
+	if err != nil {
+		return err
+	}
+	if deactivated {
+		return xerrors.Errorf("%s is deactivated", didString)
+	}
+
+	recovery, _, err := QueryAllRecoveryContext(ctx, accountIns, *did)
+	if err != nil {
+		return err
+	}
+	if n := len(recoveryDIDs(recovery, did.WithChainID(r.chain), r.chain)); n < threshold {
+		return xerrors.Errorf("%s has %d recovery dids, less than %d: %w", didString, n, threshold, ErrRecoveryThreshold)
+	}
+	return nil
+}
+
+// VerifyRecovery checks request is approved by the signatures of keys in the recovery of its did,
+// which belong to at least threshold dids, and the master key of request is not replaced yet.
+// The recovery keys approving are returned.
+func (r *MemoDIDResolver) VerifyRecovery(request *RecoveryRequest, sigs []*types.Signature, threshold int) ([]types.MemoDIDUrl, error) {
+	return r.VerifyRecoveryContext(context.Background(), request, sigs, threshold)
+}
+
+func (r *MemoDIDResolver) VerifyRecoveryContext(ctx context.Context, request *RecoveryRequest, sigs []*types.Signature, threshold int) ([]types.MemoDIDUrl, error) {
+	if threshold <= 0 {
+		return nil, xerrors.Errorf("recovery threshold should be positive, not %d", threshold)
+	}
+	if !time.Now().Before(request.ExpirationTime) {
+		return nil, ErrRecoveryExpired
+	}
+	if _, err := decodePublicKey(types.EcdsaSecp256k1VerificationKey2019, request.NewMasterKey); err != nil {
+		return nil, xerrors.Errorf("invalid new master key: %w", err)
+	}
+
+	masterKey, err := r.GetMasterKeyContext(ctx, request.DID)
+	if err != nil {
+		return nil, err
+	}
+	if !common.IsHexAddress(request.MasterKey) || common.HexToAddress(request.MasterKey).Hex() != masterKey {
+		return nil, xerrors.Errorf("master key of %s is %s, not %s", request.DID, masterKey, request.MasterKey)
+	}
+
+	recovery, keys, err := r.RecoveryMethodsContext(ctx, request.DID)
+	if err != nil {
+		return nil, err
+	}
+	did, err := types.ParseMemoDID(request.DID)
+	if err != nil {
+		return nil, err
+	}
+	return checkApprovals(request.Message(), *did, recovery, keys, sigs, threshold, r.chain)
+}
+
+// checkApprovals returns the recovery keys signing message, which should belong to at least threshold dids
+// other than did, the did recovered. The recovery keys without chain id are on the chain of did, and the keys
+// signing without chain id are on chain, the default chain of resolver. Signatures of other keys are ignored.
+func checkApprovals(message []byte, did types.MemoDID, recovery []types.MemoDIDUrl, keys []types.PublicKey, sigs []*types.Signature, threshold int, chain string) ([]types.MemoDIDUrl, error) {
+	didChain := did.ChainID()
+	if didChain == "" {
+		didChain = chain
+	}
+	self := did.WithChainID(didChain)
+
+	var approved []types.MemoDIDUrl
+	signed := make(map[int]bool)
+	for _, sig := range sigs {
+		if sig == nil {
+			continue
+		}
+		signer := onChain(sig.VerificationMethod, chain)
+		for i := range recovery {
+			if !sameMethod(onChain(recovery[i], didChain), signer, "") {
+				continue
+			}
+			// the keys of did itself cannot approve its recovery
+			if signed[i] || isDIDOf(onChain(recovery[i], didChain), self) {
+				break
+			}
+			if ok, err := keys[i].VerifySignature(sig.Signature, message); err == nil && ok {
+				signed[i] = true
+				approved = append(approved, recovery[i])
+			}
+			break
+		}
+	}
+
+	if n := len(recoveryDIDs(approved, self, didChain)); n < threshold {
+		return nil, xerrors.Errorf("approved by %d recovery dids, less than %d: %w", n, threshold, ErrRecoveryThreshold)
+	}
+	return approved, nil
+}
+
+// recoveryDIDs returns the dids which recovery keys of did belong to, except did itself. The recovery
+// keys without chain id are on didChain, the chain of did.
+func recoveryDIDs(recovery []types.MemoDIDUrl, did types.MemoDID, didChain string) map[string]struct{} {
+	dids := make(map[string]struct{})
+	for _, didUrl := range recovery {
+		didUrl = onChain(didUrl, didChain)
+		if isDIDOf(didUrl, did) {
+			continue
+		}
+		dids[documentKey(didUrl)] = struct{}{}
+	}
+	return dids
+}
+
+// isDIDOf reports whether didUrl is a url of did, both of them are qualified with chain id
+func isDIDOf(didUrl types.MemoDIDUrl, did types.MemoDID) bool {
+	return didUrl.Identifier == did.Identifier && didUrl.ChainID() == did.ChainID()
+}
+
+// RecoveryRelayer submits the replacement of master keys approved by recovery dids. The owner of
+// a lost master key cannot send the replacement, so it is sent by the account of relayer, which
+// should be the account trusted by the proxy contract to update dids, such as its admin. The relayer
+// sends it only if the request is approved by its threshold of recovery dids, the threshold is fixed
+// when the relayer is created rather than chosen by whoever asks for recovery.
+type RecoveryRelayer struct {
+	chain     string
+	backend   evm.Backend
+	addrs     ContractAddress
+	threshold int
+	resolver  *MemoDIDResolver
+	sender    *evm.Sender
+
+	// client dialed by relayer, nil if backend is passed in
+	client *evm.Client
+}
+
+// NewRecoveryRelayer dials chain and creates a relayer sending with privateKey, which requires
+// approvals of threshold recovery dids
+func NewRecoveryRelayer(privateKey *ecdsa.PrivateKey, chain string, threshold int, opts ...evm.Options) (*RecoveryRelayer, error) {
+	return NewRecoveryRelayerContext(context.Background(), privateKey, chain, threshold, opts...)
+}
+
+func NewRecoveryRelayerContext(ctx context.Context, privateKey *ecdsa.PrivateKey, chain string, threshold int, opts ...evm.Options) (*RecoveryRelayer, error) {
+	if chain == "" {
+		chain = com.DevChain
+	}
+	client, addrs, err := dialChain(ctx, chain, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	relayer, err := NewRecoveryRelayerWithBackend(privateKey, chain, client, client.ChainID(), addrs, threshold, opts...)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	relayer.client = client
+	return relayer, nil
+}
+
+// NewRecoveryRelayerWithBackend creates a relayer which sends transactions through backend
+func NewRecoveryRelayerWithBackend(privateKey *ecdsa.PrivateKey, chain string, backend evm.Backend, chainID *big.Int, addrs *ContractAddress, threshold int, opts ...evm.Options) (*RecoveryRelayer, error) {
+	if threshold <= 0 {
+		return nil, xerrors.Errorf("recovery threshold should be positive, not %d", threshold)
+	}
+	resolver, err := NewMemoDIDResolverWithBackend(chain, backend, addrs, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
+	if err != nil {
+		return nil, err
+	}
+	auth.Value = big.NewInt(0)
+	o := evm.GetOptions(opts...)
+
+	return &RecoveryRelayer{
+		chain:     resolver.Chain(),
+		backend:   backend,
+		addrs:     *addrs,
+		threshold: threshold,
+		resolver:  resolver,
+		sender:    evm.NewSender(auth, o.NewWaiter(backend, evm.ABIs(proxy.ProxyMetaData, proxy.IAccountDidMetaData)...), o.Fee),
+	}, nil
+}
+
+// Close closes the client dialed by relayer, the backend passed to NewRecoveryRelayerWithBackend is not closed
+func (r *RecoveryRelayer) Close() {
+	r.resolver.Close()
+	if r.client != nil {
+		r.client.Close()
+	}
+}
+
+// Threshold returns the number of recovery dids which should approve a recovery
+func (r *RecoveryRelayer) Threshold() int {
+	return r.threshold
+}
+
+// Recover replaces the master key of the did of request with its new key, after checking it is
+// approved by the threshold of recovery dids. The master key is checked again after the transaction.
+func (r *RecoveryRelayer) Recover(request *RecoveryRequest, sigs []*types.Signature) error {
+	return r.RecoverContext(context.Background(), request, sigs)
+}
+
+func (r *RecoveryRelayer) RecoverContext(ctx context.Context, request *RecoveryRequest, sigs []*types.Signature) error {
+	tx, err := r.RecoverAsync(ctx, request, sigs)
+	if err != nil {
+		return err
+	}
+	err = tx.Check(ctx)
+	if err != nil {
+		return err
+	}
+
+	masterKey, err := r.resolver.GetMasterKeyContext(ctx, request.DID)
+	if err != nil {
+		return err
+	}
+	publicKey, err := decodePublicKey(types.EcdsaSecp256k1VerificationKey2019, request.NewMasterKey)
+	if err != nil {
+		return err
+	}
+	newMasterKey, err := crypto.DecompressPubkey(publicKey)
+	if err != nil {
+		return err
+	}
+	if masterKey != crypto.PubkeyToAddress(*newMasterKey).Hex() {
+		return xerrors.Errorf("master key of %s is %s after recovery, not %s", request.DID, masterKey, crypto.PubkeyToAddress(*newMasterKey))
+	}
+	return nil
+}
+
+func (r *RecoveryRelayer) RecoverAsync(ctx context.Context, request *RecoveryRequest, sigs []*types.Signature) (*evm.PendingTx, error) {
+	did, err := types.ParseMemoDID(request.DID)
+	if err != nil {
+		return nil, err
+	}
+	if did.ChainID() != "" && did.ChainID() != r.chain {
+		return nil, xerrors.Errorf("did on chain %s cannot be recovered by relayer on chain %s", did.ChainID(), r.chain)
+	}
+
+	_, err = r.resolver.VerifyRecoveryContext(ctx, request, sigs, r.threshold)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := decodePublicKey(types.EcdsaSecp256k1VerificationKey2019, request.NewMasterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	proxyIns, err := proxy.NewProxy(r.addrs.ProxyAddr, r.backend)
+	if err != nil {
+		return nil, err
+	}
+	return r.sender.Send(ctx, "RecoverMasterKey", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.UpdateVeri(opts, did.Identifier, big.NewInt(0), types.EcdsaSecp256k1VerificationKey2019, publicKey)
+	})
+}
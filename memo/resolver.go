@@ -3,98 +3,233 @@ package memo
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/ethclient"
 	com "github.com/memoio/contractsv2/common"
-	inst "github.com/memoio/contractsv2/go_contracts/instance"
 	"github.com/memoio/did-solidity/go-contracts/proxy"
+	"github.com/memoio/go-did/evm"
 	"github.com/memoio/go-did/types"
 	"golang.org/x/xerrors"
 )
 
 var DefaultContext = "https://www.w3.org/ns/did/v1"
 
+// Chains are the chains that the DIDs qualified with other chain id are routed to, the DIDs on
+// chains not listed are invalid, so that a resolver does not dial for any chain id it is given.
+var Chains = []string{com.DevChain, "megrez"}
+
+// ErrChainNotSupported is returned if a DID is on a chain not in Chains
+var ErrChainNotSupported = xerrors.New("chain is not supported")
+
 type MemoDIDResolver struct {
-	endpoint    string
+	chain       string
+	backend     bind.ContractBackend
 	accountAddr common.Address
+
+	// resolvers of other chains, used by chain-qualified DIDs
+	lk     sync.Mutex
+	chains map[string]*MemoDIDResolver
+
+	// options of dialing the resolvers of other chains
+	opts []evm.Options
+	// client dialed by resolver, nil if backend is passed in
+	client *evm.Client
 }
 
 var _ DIDResolver = &MemoDIDResolver{}
 
-func NewMemoDIDResolver(chain string) (*MemoDIDResolver, error) {
+func NewMemoDIDResolver(chain string, opts ...evm.Options) (*MemoDIDResolver, error) {
+	return NewMemoDIDResolverContext(context.Background(), chain, opts...)
+}
+
+func NewMemoDIDResolverContext(ctx context.Context, chain string, opts ...evm.Options) (*MemoDIDResolver, error) {
 	if chain == "" {
 		chain = com.DevChain
 	}
 
-	instanceAddr, endpoint := com.GetInsEndPointByChain(chain)
-
-	client, err := ethclient.DialContext(context.TODO(), endpoint)
+	client, addrs, err := dialChain(ctx, chain, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	// new instanceIns
-	instanceIns, err := inst.NewInstance(instanceAddr, client)
+	resolver, err := NewMemoDIDResolverWithBackend(chain, client, addrs, opts...)
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
+	resolver.client = client
+	return resolver, nil
+}
 
-	accountAddr, err := instanceIns.Instances(&bind.CallOpts{}, com.TypeAccountDid)
-	if err != nil {
-		return nil, err
+// NewMemoDIDResolverWithBackend creates a resolver which reads DID documents through backend,
+// DIDs qualified with other chain id are routed to the resolver of that chain, which is dialed with opts.
+func NewMemoDIDResolverWithBackend(chain string, backend bind.ContractBackend, addrs *ContractAddress, opts ...evm.Options) (*MemoDIDResolver, error) {
+	if chain == "" {
+		chain = com.DevChain
 	}
 
 	return &MemoDIDResolver{
-		endpoint:    endpoint,
-		accountAddr: accountAddr,
+		chain:       chain,
+		backend:     backend,
+		accountAddr: addrs.AccountDidAddr,
+		opts:        opts,
 	}, nil
 }
 
+// Close closes the resolvers of other chains and the client dialed by resolver,
+// the backend passed to NewMemoDIDResolverWithBackend is not closed.
+func (r *MemoDIDResolver) Close() {
+	r.lk.Lock()
+	chains := r.chains
+	r.chains = nil
+	r.lk.Unlock()
+
+	for _, resolver := range chains {
+		resolver.Close()
+	}
+	if r.client != nil {
+		r.client.Close()
+	}
+}
+
+// Chain returns the default chain of the resolver, DIDs without chain id are resolved on it
+func (r *MemoDIDResolver) Chain() string {
+	return r.chain
+}
+
+// route returns the resolver of the chain that chainID refers to
+func (r *MemoDIDResolver) route(chainID string) (*MemoDIDResolver, error) {
+	if chainID == "" || chainID == r.chain {
+		return r, nil
+	}
+
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	if resolver, ok := r.chains[chainID]; ok {
+		return resolver, nil
+	}
+	if !supportsChain(chainID) {
+		return nil, xerrors.Errorf("%w: %s", ErrChainNotSupported, chainID)
+	}
+
+	resolver, err := NewMemoDIDResolver(chainID, r.opts...)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot resolve did on chain %s: %w", chainID, err)
+	}
+	if r.chains == nil {
+		r.chains = make(map[string]*MemoDIDResolver)
+	}
+	r.chains[chainID] = resolver
+
+	return resolver, nil
+}
+
+// supportsChain reports whether chainID is in Chains
+func supportsChain(chainID string) bool {
+	for _, chain := range Chains {
+		if chain == chainID {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *MemoDIDResolver) GetMasterKey(didString string) (string, error) {
+	return r.GetMasterKeyContext(context.Background(), didString)
+}
+
+func (r *MemoDIDResolver) GetMasterKeyContext(ctx context.Context, didString string) (string, error) {
 	did, err := types.ParseMemoDID(didString)
 	if err != nil {
 		return "", err
 	}
-
-	client, err := ethclient.DialContext(context.TODO(), r.endpoint)
-	if err != nil {
+	if resolver, err := r.route(did.ChainID()); err != nil {
 		return "", err
+	} else if resolver != r {
+		return resolver.GetMasterKeyContext(ctx, didString)
 	}
-	defer client.Close()
 
-	accountIns, err := proxy.NewIAccountDid(r.accountAddr, client)
+	accountIns, err := proxy.NewIAccountDid(r.accountAddr, r.backend)
 	if err != nil {
 		return "", err
 	}
 
-	address, err := accountIns.GetMasterKeyAddr(&bind.CallOpts{}, did.Identifier)
+	address, err := accountIns.GetMasterKeyAddr(&bind.CallOpts{Context: ctx}, did.Identifier)
 
 	return address.Hex(), err
 }
 
-func (r *MemoDIDResolver) Resolve(didString string) (*types.MemoDIDDocument, error) {
+// IsRegistered reports whether did is registered, a deactivated did is still registered
+// and cannot be registered again
+func (r *MemoDIDResolver) IsRegistered(didString string) (bool, error) {
+	return r.IsRegisteredContext(context.Background(), didString)
+}
+
+func (r *MemoDIDResolver) IsRegisteredContext(ctx context.Context, didString string) (bool, error) {
 	did, err := types.ParseMemoDID(didString)
 	if err != nil {
+		return false, err
+	}
+	if resolver, err := r.route(did.ChainID()); err != nil {
+		return false, err
+	} else if resolver != r {
+		return resolver.IsRegisteredContext(ctx, didString)
+	}
+
+	accountIns, err := proxy.NewIAccountDid(r.accountAddr, r.backend)
+	if err != nil {
+		return false, err
+	}
+
+	size, err := accountIns.GetVeriLen(&bind.CallOpts{Context: ctx}, did.Identifier)
+	if err != nil {
+		return false, err
+	}
+	if size.Sign() > 0 {
+		return true, nil
+	}
+	return accountIns.IsDeactivated(&bind.CallOpts{Context: ctx}, did.Identifier)
+}
+
+func (r *MemoDIDResolver) Resolve(didString string) (*types.MemoDIDDocument, error) {
+	return r.ResolveContext(context.Background(), didString)
+}
+
+// ResolveContext resolves the document of did, the did may select a historical
+// version by the versionId (block number) or versionTime parameter.
+func (r *MemoDIDResolver) ResolveContext(ctx context.Context, didString string) (*types.MemoDIDDocument, error) {
+	plain, version, err := types.SplitVersion(didString)
+	if err != nil {
+		return nil, err
+	}
+	did, err := types.ParseMemoDID(plain)
+	if err != nil {
+		return nil, err
+	}
+	if resolver, err := r.route(did.ChainID()); err != nil {
 		return nil, err
+	} else if resolver != r {
+		return resolver.ResolveContext(ctx, didString)
 	}
 
-	client, err := ethclient.DialContext(context.TODO(), r.endpoint)
+	block, err := evm.VersionBlock(ctx, r.backend, version)
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
 
-	accountIns, err := proxy.NewIAccountDid(r.accountAddr, client)
+	accountIns, err := proxy.NewIAccountDid(r.accountAddr, r.backend)
 	if err != nil {
 		return nil, err
 	}
 
-	dactivated, err := accountIns.IsDeactivated(&bind.CallOpts{}, did.Identifier)
+	dactivated, err := accountIns.IsDeactivated(&bind.CallOpts{Context: ctx, BlockNumber: block}, did.Identifier)
 	if err != nil {
 		return nil, err
 	}
@@ -102,23 +237,163 @@ func (r *MemoDIDResolver) Resolve(didString string) (*types.MemoDIDDocument, err
 		return &types.MemoDIDDocument{}, nil
 	}
 
-	verificationMethods, err := QueryAllVerificationMethod(accountIns, *did)
+	return r.document(ctx, accountIns, did, block, nil)
+}
+
+// Relationships are the ids of the verification methods added to the relationships of a did by
+// the AddAuth, AddAssertion, AddDelegation and AddRecovery events, such as the ones saved by an
+// indexer. Methods removed or deactivated after they are added are still in them.
+type Relationships struct {
+	Authentication       []string
+	AssertionMethod      []string
+	CapabilityDelegation []string
+	Recovery             []string
+}
+
+// DocumentAt reads the document of did at block, the latest if block is nil, with the relationships
+// added up to block, instead of filtering the events from the genesis block. The did should be on the
+// chain of resolver, and the document is empty if it is deactivated.
+func (r *MemoDIDResolver) DocumentAt(ctx context.Context, did *types.MemoDID, block *big.Int, relationships *Relationships) (*types.MemoDIDDocument, error) {
+	if relationships == nil {
+		relationships = &Relationships{}
+	}
+
+	accountIns, err := proxy.NewIAccountDid(r.accountAddr, r.backend)
 	if err != nil {
 		return nil, err
 	}
-	authentications, _, err := QueryAllAuthtication(accountIns, *did)
+
+	dactivated, err := accountIns.IsDeactivated(&bind.CallOpts{Context: ctx, BlockNumber: block}, did.Identifier)
 	if err != nil {
 		return nil, err
 	}
-	assertions, _, err := QueryAllAssertion(accountIns, *did)
+	if dactivated {
+		return &types.MemoDIDDocument{}, nil
+	}
+
+	return r.document(ctx, accountIns, did, block, relationships)
+}
+
+// ResolveWithMetadata resolves DID as W3C DID Resolution, the errors of resolving,
+// such as invalid or deactivated DID, are set in the resolution metadata of result.
+// An error is returned only if the chain cannot be read.
+func (r *MemoDIDResolver) ResolveWithMetadata(didString string) (*types.MemoDIDResolutionResult, error) {
+	return r.ResolveWithMetadataContext(context.Background(), didString)
+}
+
+func (r *MemoDIDResolver) ResolveWithMetadataContext(ctx context.Context, didString string) (*types.MemoDIDResolutionResult, error) {
+	result := &types.MemoDIDResolutionResult{Context: types.ResolutionContext}
+
+	plain, version, err := types.SplitVersion(didString)
+	if err != nil {
+		result.DIDResolutionMetadata.Error = types.ErrorInvalidDid
+		return result, nil
+	}
+	did, err := types.ParseMemoDID(plain)
+	if err != nil {
+		result.DIDResolutionMetadata.Error = types.ParseErrorCode(plain, "memo")
+		return result, nil
+	}
+	resolver, err := r.route(did.ChainID())
+	if errors.Is(err, ErrChainNotSupported) {
+		result.DIDResolutionMetadata.Error = types.ErrorInvalidDid
+		return result, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	delegation, _, err := QueryAllDelagation(accountIns, *did)
+	if resolver != r {
+		return resolver.ResolveWithMetadataContext(ctx, didString)
+	}
+
+	block, err := evm.VersionBlock(ctx, r.backend, version)
+	if errors.Is(err, evm.ErrVersionNotFound) {
+		result.DIDResolutionMetadata.Error = types.ErrorNotFound
+		return result, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	recovery, _, err := QueryAllRecovery(accountIns, *did)
+
+	accountIns, err := proxy.NewIAccountDid(r.accountAddr, r.backend)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	dactivated, err := accountIns.IsDeactivated(opts, did.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	size, err := accountIns.GetVeriLen(opts, did.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	if !dactivated && size.Sign() == 0 {
+		result.DIDResolutionMetadata.Error = types.ErrorNotFound
+		return result, nil
+	}
+
+	result.DIDDocumentMetadata, err = evm.ReadMetadataAt(ctx, r.backend, r.accountAddr, did.Identifier, block)
+	if err != nil {
+		return nil, err
+	}
+	if dactivated {
+		result.DIDDocumentMetadata.Deactivated = true
+		result.DIDResolutionMetadata.Error = types.ErrorDeactivated
+		return result, nil
+	}
+
+	result.DIDDocument, err = r.document(ctx, accountIns, did, block, nil)
+	if err != nil {
+		return nil, err
+	}
+	result.DIDResolutionMetadata.ContentType = types.ContentTypeDIDLDJSON
+	return result, nil
+}
+
+// document reads the document of an activated did at block, the latest if block is nil,
+// relationships are filtered from events if they are nil
+func (r *MemoDIDResolver) document(ctx context.Context, accountIns *proxy.IAccountDid, did *types.MemoDID, block *big.Int, relationships *Relationships) (*types.MemoDIDDocument, error) {
+	// delegations are checked against the time of block
+	now := time.Now()
+	if block != nil {
+		var err error
+		now, err = evm.BlockTime(ctx, r.backend, block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if relationships == nil {
+		var err error
+		relationships, err = filterRelationships(ctx, accountIns, *did, block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	verificationMethods, err := QueryAllVerificationMethodAt(ctx, accountIns, *did, block)
+	if err != nil {
+		return nil, err
+	}
+	authentications, _, err := authenticationsAt(ctx, accountIns, *did, block, relationships.Authentication)
+	if err != nil {
+		return nil, err
+	}
+	assertions, _, err := assertionsAt(ctx, accountIns, *did, block, relationships.AssertionMethod)
+	if err != nil {
+		return nil, err
+	}
+	delegation, _, err := delegationsAt(ctx, accountIns, *did, block, now, relationships.CapabilityDelegation)
+	if err != nil {
+		return nil, err
+	}
+	recovery, _, err := recoveryAt(ctx, accountIns, *did, block, relationships.Recovery)
+	if err != nil {
+		return nil, err
+	}
+	services, err := QueryAllServiceAt(ctx, accountIns, *did, block)
 	if err != nil {
 		return nil, err
 	}
@@ -131,41 +406,94 @@ func (r *MemoDIDResolver) Resolve(didString string) (*types.MemoDIDDocument, err
 		AssertionMethod:      assertions,
 		CapabilityDelegation: delegation,
 		Recovery:             recovery,
+		Service:              services,
 	}, nil
 }
 
+// DereferenceService returns the service selected by didUrlString with #id or ?service=id
+func (r *MemoDIDResolver) DereferenceService(didUrlString string) (*types.Service, error) {
+	return r.DereferenceServiceContext(context.Background(), didUrlString)
+}
+
+func (r *MemoDIDResolver) DereferenceServiceContext(ctx context.Context, didUrlString string) (*types.Service, error) {
+	did, id, err := types.ParseServiceUrl(didUrlString)
+	if err != nil {
+		return nil, err
+	}
+	if resolver, err := r.route(did.ChainID()); err != nil {
+		return nil, err
+	} else if resolver != r {
+		return resolver.DereferenceServiceContext(ctx, didUrlString)
+	}
+
+	accountIns, err := proxy.NewIAccountDid(r.accountAddr, r.backend)
+	if err != nil {
+		return nil, err
+	}
+	services, err := QueryAllServiceContext(ctx, accountIns, *did)
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range services {
+		if service.ID == did.String()+"#"+id {
+			return &service, nil
+		}
+	}
+	return nil, xerrors.Errorf("service %s is not found in %s", id, did.String())
+}
+
 func (r *MemoDIDResolver) Dereference(didUrlString string) ([]types.PublicKey, error) {
+	return r.DereferenceContext(context.Background(), didUrlString)
+}
+
+func (r *MemoDIDResolver) DereferenceContext(ctx context.Context, didUrlString string) ([]types.PublicKey, error) {
 	didUrl, err := types.ParseMemoDIDUrl(didUrlString)
 	if err != nil {
 		return nil, err
 	}
+	if resolver, err := r.route(didUrl.ChainID()); err != nil {
+		return nil, err
+	} else if resolver != r {
+		return resolver.DereferenceContext(ctx, didUrlString)
+	}
 
-	client, err := ethclient.DialContext(context.TODO(), r.endpoint)
+	accountIns, err := proxy.NewIAccountDid(r.accountAddr, r.backend)
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
 
-	accountIns, err := proxy.NewIAccountDid(r.accountAddr, client)
+	// a deactivated did has no keys, as its document resolved is empty
+	deactivated, err := accountIns.IsDeactivated(&bind.CallOpts{Context: ctx}, didUrl.Identifier)
 	if err != nil {
 		return nil, err
 	}
+	if deactivated {
+		did := didUrl.DID()
+		return nil, xerrors.Errorf("%s is deactivated", did.String())
+	}
 
 	var keys []types.PublicKey
 	switch didUrl.Fragment {
 	case "authentication":
-		_, keys, err = QueryAllAuthtication(accountIns, didUrl.DID())
+		_, keys, err = QueryAllAuthticationContext(ctx, accountIns, didUrl.DID())
 	case "assertion":
-		_, keys, err = QueryAllAssertion(accountIns, didUrl.DID())
+		_, keys, err = QueryAllAssertionContext(ctx, accountIns, didUrl.DID())
 	case "delegation":
-		_, keys, err = QueryAllDelagation(accountIns, didUrl.DID())
+		_, keys, err = QueryAllDelagationContext(ctx, accountIns, didUrl.DID())
 	case "recovery":
-		_, keys, err = QueryAllRecovery(accountIns, didUrl.DID())
+		_, keys, err = QueryAllRecoveryContext(ctx, accountIns, didUrl.DID())
 	default:
-		verifyMethod, err := accountIns.GetVeri(&bind.CallOpts{}, didUrl.Identifier, big.NewInt(int64(didUrl.GetMethodIndex())))
-		if err == nil && verifyMethod.Deactivated {
+		var verifyMethod proxy.IAccountDidPublicKey
+		verifyMethod, err = accountIns.GetVeri(&bind.CallOpts{Context: ctx}, didUrl.Identifier, big.NewInt(int64(didUrl.GetMethodIndex())))
+		if err != nil {
+			return nil, err
+		}
+		if verifyMethod.Deactivated {
 			return nil, xerrors.Errorf("The Verify Method(%s) is Deactivated", didUrl.String())
 		}
+		if verifyMethod.MethodType == types.ServiceType {
+			return nil, xerrors.Errorf("%s is a service, not a verification method", didUrl.String())
+		}
 
 		keys = append(keys, types.PublicKey{
 			Type:         verifyMethod.MethodType,
@@ -177,18 +505,28 @@ func (r *MemoDIDResolver) Dereference(didUrlString string) ([]types.PublicKey, e
 }
 
 func QueryAllVerificationMethod(accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.VerificationMethod, error) {
-	size, err := accountIns.GetVeriLen(&bind.CallOpts{}, did.Identifier)
+	return QueryAllVerificationMethodContext(context.Background(), accountIns, did)
+}
+
+func QueryAllVerificationMethodContext(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.VerificationMethod, error) {
+	return QueryAllVerificationMethodAt(ctx, accountIns, did, nil)
+}
+
+// QueryAllVerificationMethodAt queries the verification methods of did at block, the latest if block is nil
+func QueryAllVerificationMethodAt(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) ([]types.VerificationMethod, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	size, err := accountIns.GetVeriLen(opts, did.Identifier)
 	if err != nil {
 		return nil, err
 	}
 
 	var verificationMethods []types.VerificationMethod
 	for i := int64(0); i < size.Int64(); i++ {
-		verificationMethodSol, err := accountIns.GetVeri(&bind.CallOpts{}, did.Identifier, big.NewInt(i))
+		verificationMethodSol, err := accountIns.GetVeri(opts, did.Identifier, big.NewInt(i))
 		if err != nil {
 			return nil, err
 		}
-		if !verificationMethodSol.Deactivated {
+		if !verificationMethodSol.Deactivated && verificationMethodSol.MethodType != types.ServiceType {
 			verificationMethod, err := types.FromSolityData(did, i, &verificationMethodSol)
 			if err != nil {
 				return nil, err
@@ -200,14 +538,98 @@ func QueryAllVerificationMethod(accountIns *proxy.IAccountDid, did types.MemoDID
 	return verificationMethods, nil
 }
 
+func QueryAllService(accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.Service, error) {
+	return QueryAllServiceContext(context.Background(), accountIns, did)
+}
+
+func QueryAllServiceContext(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.Service, error) {
+	return QueryAllServiceAt(ctx, accountIns, did, nil)
+}
+
+// QueryAllServiceAt queries the services of did at block, the latest if block is nil
+func QueryAllServiceAt(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) ([]types.Service, error) {
+	slots, err := queryServiceSlots(ctx, accountIns, did, block)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []types.Service
+	for _, slot := range slots {
+		services = append(services, slot.service)
+	}
+	return services, nil
+}
+
+// serviceSlot is a service saved in the verification method slot of index
+type serviceSlot struct {
+	index   int64
+	service types.Service
+}
+
+// queryServiceSlots queries the activated slots saving services of did at block,
+// slots which cannot be decoded are skipped
+func queryServiceSlots(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) ([]serviceSlot, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	size, err := accountIns.GetVeriLen(opts, did.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var slots []serviceSlot
+	for i := int64(0); i < size.Int64(); i++ {
+		verificationMethodSol, err := accountIns.GetVeri(opts, did.Identifier, big.NewInt(i))
+		if err != nil {
+			return nil, err
+		}
+		if verificationMethodSol.Deactivated || verificationMethodSol.MethodType != types.ServiceType {
+			continue
+		}
+		service, err := types.DecodeService(did, verificationMethodSol.PubKeyData)
+		if err != nil {
+			continue
+		}
+		slots = append(slots, serviceSlot{index: i, service: *service})
+	}
+	return slots, nil
+}
+
 func QueryAllAuthtication(accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.MemoDIDUrl, []types.PublicKey, error) {
-	authIter, err := accountIns.FilterAddAuth(&bind.FilterOpts{}, []string{did.Identifier})
+	return QueryAllAuthticationContext(context.Background(), accountIns, did)
+}
+
+func QueryAllAuthticationContext(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	return QueryAllAuthticationAt(ctx, accountIns, did, nil)
+}
+
+// QueryAllAuthticationAt queries the authentications of did at block, the latest if block is nil
+func QueryAllAuthticationAt(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	ids, err := filterAuthentication(ctx, accountIns, did, block)
 	if err != nil {
 		return nil, nil, err
 	}
+	return authenticationsAt(ctx, accountIns, did, block, ids)
+}
+
+// filterAuthentication returns the method ids of the AddAuth events of did up to block
+func filterAuthentication(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) ([]string, error) {
+	authIter, err := accountIns.FilterAddAuth(evm.FilterOpts(ctx, block), []string{did.Identifier})
+	if err != nil {
+		return nil, err
+	}
 	defer authIter.Close()
 
-	verifyMethod, _ := accountIns.GetVeri(&bind.CallOpts{}, did.Identifier, big.NewInt(0))
+	var ids []string
+	for authIter.Next() {
+		ids = append(ids, authIter.Event.Id)
+	}
+	return ids, authIter.Error()
+}
+
+// authenticationsAt returns the master key and the methods of ids which are authentications of did at block
+func authenticationsAt(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int, ids []string) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+
+	verifyMethod, _ := accountIns.GetVeri(opts, did.Identifier, big.NewInt(0))
 	var masterID, _ = did.DIDUrl(0)
 	var masterKey = types.PublicKey{
 		Type:         verifyMethod.MethodType,
@@ -216,28 +638,27 @@ func QueryAllAuthtication(accountIns *proxy.IAccountDid, did types.MemoDID) ([]t
 
 	var authentications []types.MemoDIDUrl = []types.MemoDIDUrl{masterID}
 	var keys []types.PublicKey = []types.PublicKey{masterKey}
-	for authIter.Next() {
-		if authIter.Event.Id == masterID.String() {
-			continue
-		}
-
+	for _, id := range ids {
 		// parse method id
-		didUrl, err := types.ParseMemoDIDUrl(authIter.Event.Id)
+		didUrl, err := types.ParseMemoDIDUrl(id)
 		if err != nil {
 			return nil, nil, err
 		}
+		if didUrl.Identifier == masterID.Identifier && didUrl.Fragment == masterID.Fragment {
+			continue
+		}
 
 		// check method id is activated or not
-		activated, err := accountIns.InAuth(&bind.CallOpts{}, did.Identifier, didUrl.String())
+		activated, err := accountIns.InAuth(opts, did.Identifier, id)
 		if err != nil {
 			return nil, nil, err
 		}
-		verificationMethod, err := accountIns.GetVeri(&bind.CallOpts{}, didUrl.Identifier, big.NewInt(int64(didUrl.GetMethodIndex())))
+		verificationMethod, err := accountIns.GetVeri(opts, didUrl.Identifier, big.NewInt(int64(didUrl.GetMethodIndex())))
 		if err != nil {
 			return nil, nil, err
 		}
-		if activated && !verificationMethod.Deactivated {
-			authentications = append(authentications, *didUrl)
+		if activated && !verificationMethod.Deactivated && verificationMethod.MethodType != types.ServiceType {
+			authentications = append(authentications, onChain(*didUrl, did.ChainID()))
 			keys = append(keys, types.PublicKey{
 				Type:         verificationMethod.MethodType,
 				PublicKeyHex: hex.EncodeToString(verificationMethod.PubKeyData),
@@ -249,32 +670,61 @@ func QueryAllAuthtication(accountIns *proxy.IAccountDid, did types.MemoDID) ([]t
 }
 
 func QueryAllAssertion(accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.MemoDIDUrl, []types.PublicKey, error) {
-	assertionIter, err := accountIns.FilterAddAssertion(&bind.FilterOpts{}, []string{did.Identifier})
+	return QueryAllAssertionContext(context.Background(), accountIns, did)
+}
+
+func QueryAllAssertionContext(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	return QueryAllAssertionAt(ctx, accountIns, did, nil)
+}
+
+// QueryAllAssertionAt queries the assertion methods of did at block, the latest if block is nil
+func QueryAllAssertionAt(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	ids, err := filterAssertion(ctx, accountIns, did, block)
 	if err != nil {
 		return nil, nil, err
 	}
+	return assertionsAt(ctx, accountIns, did, block, ids)
+}
+
+// filterAssertion returns the method ids of the AddAssertion events of did up to block
+func filterAssertion(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) ([]string, error) {
+	assertionIter, err := accountIns.FilterAddAssertion(evm.FilterOpts(ctx, block), []string{did.Identifier})
+	if err != nil {
+		return nil, err
+	}
 	defer assertionIter.Close()
 
+	var ids []string
+	for assertionIter.Next() {
+		ids = append(ids, assertionIter.Event.Id)
+	}
+	return ids, assertionIter.Error()
+}
+
+// assertionsAt returns the methods of ids which are assertion methods of did at block
+func assertionsAt(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int, ids []string) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+
 	var assertions []types.MemoDIDUrl
 	var keys []types.PublicKey
-	for assertionIter.Next() {
+	for _, id := range ids {
 		// parse method id
-		didUrl, err := types.ParseMemoDIDUrl(assertionIter.Event.Id)
+		didUrl, err := types.ParseMemoDIDUrl(id)
 		if err != nil {
 			return nil, nil, err
 		}
 
 		// check method id is activated or not
-		activated, err := accountIns.InAssertion(&bind.CallOpts{}, did.Identifier, didUrl.String())
+		activated, err := accountIns.InAssertion(opts, did.Identifier, id)
 		if err != nil {
 			return nil, nil, err
 		}
-		verificationMethod, err := accountIns.GetVeri(&bind.CallOpts{}, didUrl.DID().Identifier, big.NewInt(int64(didUrl.GetMethodIndex())))
+		verificationMethod, err := accountIns.GetVeri(opts, didUrl.DID().Identifier, big.NewInt(int64(didUrl.GetMethodIndex())))
 		if err != nil {
 			return nil, nil, err
 		}
-		if activated && !verificationMethod.Deactivated {
-			assertions = append(assertions, *didUrl)
+		if activated && !verificationMethod.Deactivated && verificationMethod.MethodType != types.ServiceType {
+			assertions = append(assertions, onChain(*didUrl, did.ChainID()))
 			keys = append(keys, types.PublicKey{
 				Type:         verificationMethod.MethodType,
 				PublicKeyHex: hex.EncodeToString(verificationMethod.PubKeyData),
@@ -286,32 +736,62 @@ func QueryAllAssertion(accountIns *proxy.IAccountDid, did types.MemoDID) ([]type
 }
 
 func QueryAllDelagation(accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.MemoDIDUrl, []types.PublicKey, error) {
-	delegationIter, err := accountIns.FilterAddDelegation(&bind.FilterOpts{}, []string{did.Identifier})
+	return QueryAllDelagationContext(context.Background(), accountIns, did)
+}
+
+func QueryAllDelagationContext(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	return QueryAllDelagationAt(ctx, accountIns, did, nil, time.Now())
+}
+
+// QueryAllDelagationAt queries the delegations of did at block, the latest if block is nil,
+// delegations expired before now are skipped
+func QueryAllDelagationAt(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int, now time.Time) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	ids, err := filterDelegation(ctx, accountIns, did, block)
 	if err != nil {
 		return nil, nil, err
 	}
+	return delegationsAt(ctx, accountIns, did, block, now, ids)
+}
+
+// filterDelegation returns the method ids of the AddDelegation events of did up to block
+func filterDelegation(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) ([]string, error) {
+	delegationIter, err := accountIns.FilterAddDelegation(evm.FilterOpts(ctx, block), []string{did.Identifier})
+	if err != nil {
+		return nil, err
+	}
 	defer delegationIter.Close()
 
+	var ids []string
+	for delegationIter.Next() {
+		ids = append(ids, delegationIter.Event.Id)
+	}
+	return ids, delegationIter.Error()
+}
+
+// delegationsAt returns the methods of ids which are delegations of did at block and not expired before now
+func delegationsAt(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int, now time.Time, ids []string) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+
 	var delegations []types.MemoDIDUrl
 	var keys []types.PublicKey
-	for delegationIter.Next() {
+	for _, id := range ids {
 		// parse method id
-		didUrl, err := types.ParseMemoDIDUrl(delegationIter.Event.Id)
+		didUrl, err := types.ParseMemoDIDUrl(id)
 		if err != nil {
 			return nil, nil, err
 		}
 
 		// check delegation id is expired or not
-		expiration, err := accountIns.InDelegation(&bind.CallOpts{}, did.Identifier, didUrl.String())
+		expiration, err := accountIns.InDelegation(opts, did.Identifier, id)
 		if err != nil {
 			return nil, nil, err
 		}
-		verificationMethod, err := accountIns.GetVeri(&bind.CallOpts{}, didUrl.DID().Identifier, big.NewInt(int64(didUrl.GetMethodIndex())))
+		verificationMethod, err := accountIns.GetVeri(opts, didUrl.DID().Identifier, big.NewInt(int64(didUrl.GetMethodIndex())))
 		if err != nil {
 			return nil, nil, err
 		}
-		if expiration.Int64() >= time.Now().Unix() && !verificationMethod.Deactivated {
-			delegations = append(delegations, *didUrl)
+		if expiration.Int64() >= now.Unix() && !verificationMethod.Deactivated && verificationMethod.MethodType != types.ServiceType {
+			delegations = append(delegations, onChain(*didUrl, did.ChainID()))
 			keys = append(keys, types.PublicKey{
 				Type:         verificationMethod.MethodType,
 				PublicKeyHex: hex.EncodeToString(verificationMethod.PubKeyData),
@@ -323,32 +803,61 @@ func QueryAllDelagation(accountIns *proxy.IAccountDid, did types.MemoDID) ([]typ
 }
 
 func QueryAllRecovery(accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.MemoDIDUrl, []types.PublicKey, error) {
-	recoveryIter, err := accountIns.FilterAddRecovery(&bind.FilterOpts{}, []string{did.Identifier})
+	return QueryAllRecoveryContext(context.Background(), accountIns, did)
+}
+
+func QueryAllRecoveryContext(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	return QueryAllRecoveryAt(ctx, accountIns, did, nil)
+}
+
+// QueryAllRecoveryAt queries the recovery methods of did at block, the latest if block is nil
+func QueryAllRecoveryAt(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	ids, err := filterRecovery(ctx, accountIns, did, block)
 	if err != nil {
 		return nil, nil, err
 	}
+	return recoveryAt(ctx, accountIns, did, block, ids)
+}
+
+// filterRecovery returns the method ids of the AddRecovery events of did up to block
+func filterRecovery(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) ([]string, error) {
+	recoveryIter, err := accountIns.FilterAddRecovery(evm.FilterOpts(ctx, block), []string{did.Identifier})
+	if err != nil {
+		return nil, err
+	}
 	defer recoveryIter.Close()
 
+	var ids []string
+	for recoveryIter.Next() {
+		ids = append(ids, recoveryIter.Event.Recovery)
+	}
+	return ids, recoveryIter.Error()
+}
+
+// recoveryAt returns the methods of ids which are recovery methods of did at block
+func recoveryAt(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int, ids []string) ([]types.MemoDIDUrl, []types.PublicKey, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+
 	var recovery []types.MemoDIDUrl
 	var keys []types.PublicKey
-	for recoveryIter.Next() {
+	for _, id := range ids {
 		// parse method id
-		didUrl, err := types.ParseMemoDIDUrl(recoveryIter.Event.Recovery)
+		didUrl, err := types.ParseMemoDIDUrl(id)
 		if err != nil {
 			return nil, nil, err
 		}
 
 		// check method id is activated or not
-		activated, err := accountIns.InRecovery(&bind.CallOpts{}, did.Identifier, didUrl.String())
+		activated, err := accountIns.InRecovery(opts, did.Identifier, id)
 		if err != nil {
 			return nil, nil, err
 		}
-		verificationMethod, err := accountIns.GetVeri(&bind.CallOpts{}, didUrl.DID().Identifier, big.NewInt(int64(didUrl.GetMethodIndex())))
+		verificationMethod, err := accountIns.GetVeri(opts, didUrl.DID().Identifier, big.NewInt(int64(didUrl.GetMethodIndex())))
 		if err != nil {
 			return nil, nil, err
 		}
-		if activated && !verificationMethod.Deactivated {
-			recovery = append(recovery, *didUrl)
+		if activated && !verificationMethod.Deactivated && verificationMethod.MethodType != types.ServiceType {
+			recovery = append(recovery, onChain(*didUrl, did.ChainID()))
 			keys = append(keys, types.PublicKey{
 				Type:         verificationMethod.MethodType,
 				PublicKeyHex: hex.EncodeToString(verificationMethod.PubKeyData),
@@ -358,3 +867,36 @@ func QueryAllRecovery(accountIns *proxy.IAccountDid, did types.MemoDID) ([]types
 
 	return recovery, keys, nil
 }
+
+// filterRelationships returns the method ids added to the relationships of did up to block by events
+func filterRelationships(ctx context.Context, accountIns *proxy.IAccountDid, did types.MemoDID, block *big.Int) (*Relationships, error) {
+	var relationships Relationships
+	var err error
+	relationships.Authentication, err = filterAuthentication(ctx, accountIns, did, block)
+	if err != nil {
+		return nil, err
+	}
+	relationships.AssertionMethod, err = filterAssertion(ctx, accountIns, did, block)
+	if err != nil {
+		return nil, err
+	}
+	relationships.CapabilityDelegation, err = filterDelegation(ctx, accountIns, did, block)
+	if err != nil {
+		return nil, err
+	}
+	relationships.Recovery, err = filterRecovery(ctx, accountIns, did, block)
+	if err != nil {
+		return nil, err
+	}
+	return &relationships, nil
+}
+
+// onChain qualifies didUrl with chain if it has no chain id, such as the did urls saved in contract
+// without chain id, which are on the chain of the did they are saved in. chain is the chain where
+// didUrl is resolved, the unqualified form is kept if it is empty.
+func onChain(didUrl types.MemoDIDUrl, chain string) types.MemoDIDUrl {
+	if didUrl.ChainID() != "" || chain == "" {
+		return didUrl
+	}
+	return didUrl.WithChainID(chain)
+}
@@ -0,0 +1,331 @@
+package memo
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/did-solidity/go-contracts/proxy"
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// RotationError is returned by RotateKey if the new key is added but the rotation is not finished,
+// such as a transaction moving a relationship fails. The rotation is finished by ResumeRotateKey with
+// OldKey and NewKey.
+type RotationError struct {
+	OldKey types.MemoDIDUrl
+	NewKey types.MemoDIDUrl
+	Err    error
+}
+
+func (e *RotationError) Error() string {
+	return fmt.Sprintf("rotation of %s to %s is not finished: %s", e.OldKey.String(), e.NewKey.String(), e.Err)
+}
+
+func (e *RotationError) Unwrap() error {
+	return e.Err
+}
+
+// RotateKey replaces the verification method oldKey of the did with a new key of vtype. The new key
+// is added with the controller of oldKey, the authentication, assertion and delegation of oldKey are
+// moved to it, and oldKey is deactivated. The document is resolved afterwards to check the new state.
+// The did url of the new key is returned.
+//
+// The rotation is not atomic, it sends up to eight transactions: adding the new key, adding and removing
+// each relationship moved, and deactivating oldKey. If one of them fails after the new key is added,
+// both keys are activated for a while, and a *RotationError is returned to resume the rotation.
+//
+// The master key signs the transactions of the did, so it is rotated by RotateMasterKey instead.
+func (c *MemoDIDController) RotateKey(oldKey types.MemoDIDUrl, vtype string, publicKeyHex string) (types.MemoDIDUrl, error) {
+	return c.RotateKeyContext(context.Background(), oldKey, vtype, publicKeyHex)
+}
+
+func (c *MemoDIDController) RotateKeyContext(ctx context.Context, oldKey types.MemoDIDUrl, vtype string, publicKeyHex string) (types.MemoDIDUrl, error) {
+	if err := c.checkRotatedKey(oldKey); err != nil {
+		return types.MemoDIDUrl{}, err
+	}
+	publicKey, err := decodePublicKey(vtype, publicKeyHex)
+	if err != nil {
+		return types.MemoDIDUrl{}, err
+	}
+	oldKey = oldKey.WithChainID("")
+
+	resolver, err := NewMemoDIDResolverWithBackend(c.chain, c.backend, &c.addrs)
+	if err != nil {
+		return types.MemoDIDUrl{}, err
+	}
+	document, err := resolver.ResolveContext(ctx, c.did.String())
+	if err != nil {
+		return types.MemoDIDUrl{}, err
+	}
+	var old *types.VerificationMethod
+	for i := range document.VerificationMethod {
+		if sameMethod(document.VerificationMethod[i].ID, oldKey, c.chain) {
+			old = &document.VerificationMethod[i]
+		}
+	}
+	if old == nil {
+		return types.MemoDIDUrl{}, xerrors.Errorf("%s is not an activated verification method", oldKey.String())
+	}
+
+	accountIns, err := proxy.NewIAccountDid(c.addrs.AccountDidAddr, c.backend)
+	if err != nil {
+		return types.MemoDIDUrl{}, err
+	}
+	// the new key is saved in the next slot
+	size, err := accountIns.GetVeriLen(&bind.CallOpts{Context: ctx}, c.did.Identifier)
+	if err != nil {
+		return types.MemoDIDUrl{}, err
+	}
+	did := c.did.WithChainID("")
+	newKey, err := did.DIDUrl(size.Int64())
+	if err != nil {
+		return types.MemoDIDUrl{}, err
+	}
+	err = c.AddVerificationMethodContext(ctx, vtype, old.Controller, publicKeyHex)
+	if err != nil {
+		return types.MemoDIDUrl{}, xerrors.Errorf("add new key: %w", err)
+	}
+	added, err := accountIns.GetVeri(&bind.CallOpts{Context: ctx}, c.did.Identifier, size)
+	if err != nil {
+		return types.MemoDIDUrl{}, err
+	}
+	if !bytes.Equal(added.PubKeyData, publicKey) {
+		return types.MemoDIDUrl{}, xerrors.Errorf("%s is not the new key, other keys are added at the same time", newKey.String())
+	}
+
+	oldKey, newKey = oldKey.WithChainID(c.did.ChainID()), newKey.WithChainID(c.did.ChainID())
+	document, err = c.finishRotation(ctx, resolver, accountIns, oldKey.WithChainID(""), newKey.WithChainID(""))
+	if err != nil {
+		return newKey, &RotationError{OldKey: oldKey, NewKey: newKey, Err: err}
+	}
+	for _, method := range document.VerificationMethod {
+		if sameMethod(method.ID, newKey, c.chain) {
+			key, err := method.PublicKey.Bytes()
+			if err != nil {
+				return newKey, err
+			}
+			if method.Type != vtype || !bytes.Equal(key, publicKey) {
+				return newKey, xerrors.Errorf("%s is not the new key after rotation", newKey.String())
+			}
+		}
+	}
+	return newKey, nil
+}
+
+// ResumeRotateKey finishes the rotation of oldKey to newKey returned by RotateKey in a *RotationError.
+// The relationships of oldKey which are not moved yet are moved to newKey, and oldKey is deactivated
+// if it is still activated.
+func (c *MemoDIDController) ResumeRotateKey(oldKey, newKey types.MemoDIDUrl) error {
+	return c.ResumeRotateKeyContext(context.Background(), oldKey, newKey)
+}
+
+func (c *MemoDIDController) ResumeRotateKeyContext(ctx context.Context, oldKey, newKey types.MemoDIDUrl) error {
+	for _, key := range []types.MemoDIDUrl{oldKey, newKey} {
+		if err := c.checkRotatedKey(key); err != nil {
+			return err
+		}
+	}
+
+	resolver, err := NewMemoDIDResolverWithBackend(c.chain, c.backend, &c.addrs)
+	if err != nil {
+		return err
+	}
+	accountIns, err := proxy.NewIAccountDid(c.addrs.AccountDidAddr, c.backend)
+	if err != nil {
+		return err
+	}
+	_, err = c.finishRotation(ctx, resolver, accountIns, oldKey.WithChainID(""), newKey.WithChainID(""))
+	return err
+}
+
+// checkRotatedKey checks key is a verification method of the did other than the master key
+func (c *MemoDIDController) checkRotatedKey(key types.MemoDIDUrl) error {
+	if err := c.checkChain(key.ChainID()); err != nil {
+		return err
+	}
+	if key.Identifier != c.did.Identifier {
+		return xerrors.Errorf("%s is not a verification method of %s", key.String(), c.did.String())
+	}
+	if key.GetMethodIndex() == 0 {
+		return xerrors.Errorf("master key is rotated by RotateMasterKey")
+	}
+	if key.GetMethodIndex() < 0 {
+		return xerrors.Errorf("%s is not a verification method", key.String())
+	}
+	return nil
+}
+
+// finishRotation moves the relationships of oldKey to newKey and deactivates oldKey, the steps done
+// already are skipped so that a rotation failed is resumed. The document is resolved afterwards to
+// check the new state, and returned.
+func (c *MemoDIDController) finishRotation(ctx context.Context, resolver *MemoDIDResolver, accountIns *proxy.IAccountDid, oldKey, newKey types.MemoDIDUrl) (*types.MemoDIDDocument, error) {
+	document, err := resolver.ResolveContext(ctx, c.did.String())
+	if err != nil {
+		return nil, err
+	}
+	oldActivated, newActivated := false, false
+	for _, method := range document.VerificationMethod {
+		oldActivated = oldActivated || sameMethod(method.ID, oldKey, c.chain)
+		newActivated = newActivated || sameMethod(method.ID, newKey, c.chain)
+	}
+	if !newActivated {
+		return nil, xerrors.Errorf("%s is not an activated verification method", newKey.String())
+	}
+
+	// a relationship is moved if either key is in it
+	relationships := []struct {
+		relationType int
+		didUrls      []types.MemoDIDUrl
+		moved        bool
+	}{
+		{types.Authentication, document.Authentication, false},
+		{types.AssertionMethod, document.AssertionMethod, false},
+		{types.CapabilityDelegation, document.CapabilityDelegation, false},
+	}
+	for i := range relationships {
+		relationship := &relationships[i]
+		inOld := containsDIDUrl(relationship.didUrls, oldKey, c.chain)
+		relationship.moved = inOld || containsDIDUrl(relationship.didUrls, newKey, c.chain)
+		if !inOld {
+			continue
+		}
+		if !containsDIDUrl(relationship.didUrls, newKey, c.chain) {
+			// the new key keeps the rest of the delegation of old key
+			var expireTime int64
+			if relationship.relationType == types.CapabilityDelegation {
+				expiration, err := accountIns.InDelegation(&bind.CallOpts{Context: ctx}, c.did.Identifier, oldKey.String())
+				if err != nil {
+					return nil, err
+				}
+				expireTime = expiration.Int64() - time.Now().Unix()
+			}
+			err = c.AddRelationShipContext(ctx, relationship.relationType, newKey, expireTime)
+			if err != nil {
+				return nil, xerrors.Errorf("move relationship %d to %s: %w", relationship.relationType, newKey.String(), err)
+			}
+		}
+		err = c.DeactivateRelationShipContext(ctx, relationship.relationType, oldKey)
+		if err != nil {
+			return nil, xerrors.Errorf("remove relationship %d of %s: %w", relationship.relationType, oldKey.String(), err)
+		}
+	}
+
+	if oldActivated {
+		err = c.DeactivateVerificationMethodContext(ctx, oldKey)
+		if err != nil {
+			return nil, xerrors.Errorf("deactivate old key: %w", err)
+		}
+	}
+
+	// check the rotation by resolving the document again
+	document, err = resolver.ResolveContext(ctx, c.did.String())
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, method := range document.VerificationMethod {
+		if sameMethod(method.ID, oldKey, c.chain) {
+			return nil, xerrors.Errorf("%s is still activated after rotation", oldKey.String())
+		}
+		found = found || sameMethod(method.ID, newKey, c.chain)
+	}
+	if !found {
+		return nil, xerrors.Errorf("%s is not activated after rotation", newKey.String())
+	}
+	if containsDIDUrl(document.Authentication, newKey, c.chain) != relationships[0].moved ||
+		containsDIDUrl(document.AssertionMethod, newKey, c.chain) != relationships[1].moved ||
+		containsDIDUrl(document.CapabilityDelegation, newKey, c.chain) != relationships[2].moved {
+		return nil, xerrors.Errorf("relationships of %s are not moved to %s", oldKey.String(), newKey.String())
+	}
+	return document, nil
+}
+
+// RotateMasterKey replaces the master key of the did with the public key of newPrivateKey, then the
+// controller signs transactions with newPrivateKey. The master key is updated in place, so the
+// relationships of #masterKey are kept. The controller switches to newPrivateKey as soon as the master
+// key is updated, then the document is resolved to check the new state.
+//
+// It should not be called at the same time as other operations of the controller.
+func (c *MemoDIDController) RotateMasterKey(newPrivateKey *ecdsa.PrivateKey) error {
+	return c.RotateMasterKeyContext(context.Background(), newPrivateKey)
+}
+
+func (c *MemoDIDController) RotateMasterKeyContext(ctx context.Context, newPrivateKey *ecdsa.PrivateKey) error {
+	accountIns, err := proxy.NewIAccountDid(c.addrs.AccountDidAddr, c.backend)
+	if err != nil {
+		return err
+	}
+	masterAddr, err := accountIns.GetMasterKeyAddr(&bind.CallOpts{Context: ctx}, c.did.Identifier)
+	if err != nil {
+		return err
+	}
+	if masterAddr != c.sender.From() {
+		return xerrors.Errorf("controller key %s is not the master key %s of %s", c.sender.From(), masterAddr, c.did.String())
+	}
+
+	auth, err := bind.NewKeyedTransactorWithChainID(newPrivateKey, c.chainID)
+	if err != nil {
+		return err
+	}
+	auth.Value = big.NewInt(0)
+
+	masterKey, err := c.did.DIDUrl(0)
+	if err != nil {
+		return err
+	}
+	publicKey := crypto.CompressPubkey(&newPrivateKey.PublicKey)
+	err = c.UpdateVerificationMethodContext(ctx, masterKey, types.EcdsaSecp256k1VerificationKey2019, hex.EncodeToString(publicKey))
+	if err != nil {
+		return xerrors.Errorf("update master key: %w", err)
+	}
+	// the old key cannot sign transactions of the did once the master key is updated
+	c.privateKey = newPrivateKey
+	c.didTransactor = auth
+	c.sender = c.sender.ForAccount(auth)
+
+	// check the rotation by resolving the document again
+	masterAddr, err = accountIns.GetMasterKeyAddr(&bind.CallOpts{Context: ctx}, c.did.Identifier)
+	if err != nil {
+		return err
+	}
+	if masterAddr != auth.From {
+		return xerrors.Errorf("master key of %s is %s after rotation, not %s", c.did.String(), masterAddr, auth.From)
+	}
+	resolver, err := NewMemoDIDResolverWithBackend(c.chain, c.backend, &c.addrs)
+	if err != nil {
+		return err
+	}
+	document, err := resolver.ResolveContext(ctx, c.did.String())
+	if err != nil {
+		return err
+	}
+	if len(document.VerificationMethod) == 0 || !sameMethod(document.VerificationMethod[0].ID, masterKey, c.chain) {
+		return xerrors.Errorf("master key of %s is not found after rotation", c.did.String())
+	}
+	key, err := document.VerificationMethod[0].PublicKey.Bytes()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(key, publicKey) {
+		return xerrors.Errorf("master key of %s is not the new key after rotation", c.did.String())
+	}
+	return nil
+}
+
+// containsDIDUrl reports whether didUrls contains didUrl, the urls without chain id are on chain
+func containsDIDUrl(didUrls []types.MemoDIDUrl, didUrl types.MemoDIDUrl, chain string) bool {
+	for _, u := range didUrls {
+		if sameMethod(u, didUrl, chain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1114 @@
+package memo_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/did-solidity/go-contracts/proxy"
+	"github.com/memoio/go-did/evm"
+	"github.com/memoio/go-did/jws"
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/mfile"
+	"github.com/memoio/go-did/simulated"
+	mtypes "github.com/memoio/go-did/types"
+)
+
+func TestDIDLifecycle(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 2)
+	defer chain.Close()
+
+	did, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := memo.NewMemoDIDResolverWithBackend("dev", chain, &contracts.Memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := resolver.ResolveWithMetadata(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DIDResolutionMetadata.Error != mtypes.ErrorNotFound {
+		t.Fatalf("unexpected resolution metadata before register: %v", result.DIDResolutionMetadata)
+	}
+
+	err = controller.RegisterDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = resolver.ResolveWithMetadata(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DIDResolutionMetadata.Error != "" || result.DIDDocument == nil || result.DIDDocumentMetadata.Created == "" {
+		t.Fatalf("unexpected resolution result after register: %v", result)
+	}
+
+	// the did on a chain not supported is invalid, and no resolver is dialed for it
+	unknown := did.WithChainID("unknown")
+	result, err = resolver.ResolveWithMetadata(unknown.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DIDResolutionMetadata.Error != mtypes.ErrorInvalidDid {
+		t.Fatalf("unexpected resolution metadata on unknown chain: %v", result.DIDResolutionMetadata)
+	}
+	_, err = resolver.Resolve(unknown.String())
+	if !errors.Is(err, memo.ErrChainNotSupported) {
+		t.Fatalf("did on unknown chain should not be resolved: %v", err)
+	}
+
+	document, err := resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.VerificationMethod) != 1 || len(document.Authentication) != 1 {
+		t.Fatalf("unexpected document after register: %v", document)
+	}
+	registered, err := chain.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pk := crypto.CompressPubkey(&chain.Keys[1].PublicKey)
+	err = controller.AddVerificationMethod("EcdsaSecp256k1VerificationKey2019", *did, hex.EncodeToString(pk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddRelationShip(mtypes.AssertionMethod, document.VerificationMethod[0].ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	document, err = resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.VerificationMethod) != 2 || !reflect.DeepEqual(document.AssertionMethod, []mtypes.MemoDIDUrl{document.VerificationMethod[0].ID}) {
+		t.Fatalf("unexpected document after update: %v", document)
+	}
+
+	// the version before update
+	result, err = resolver.ResolveWithMetadata(did.String() + "?versionId=" + registered.Number.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DIDDocument == nil || len(result.DIDDocument.VerificationMethod) != 1 || len(result.DIDDocument.AssertionMethod) != 0 ||
+		result.DIDDocumentMetadata.NextVersionID == "" {
+		t.Fatalf("unexpected resolution result of version %s: %v", registered.Number, result)
+	}
+
+	// mfile did controlled by the memo did
+	cid := "bafkreiaay2fxn7gplx6a2i47djwfrelwwrd7wcd6ih5ssspzjm3gb3dxna"
+	mfileController, err := mfile.NewMfileDIDControllerWithBackend(chain.Keys[0], chain, chain.ChainID, &contracts.Mfile, "did:mfile:"+cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfileResolver, err := mfile.NewMfileDIDResolverWithBackend(chain, &contracts.Mfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mfileController.RegisterDID("cid", 0, big.NewInt(10), []string{"test"}, *did)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mfileDocument, err := mfileResolver.Resolve("did:mfile:" + cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mfileDocument.Controller.String() != did.String() || mfileDocument.Price != 10 {
+		t.Fatalf("unexpected mfile document: %v", mfileDocument)
+	}
+
+	err = controller.DeactivateDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddRelationShip(mtypes.Authentication, document.VerificationMethod[1].ID, 0)
+	if err == nil {
+		t.Fatal("should report an error when trying to update deactivated did")
+	}
+
+	result, err = resolver.ResolveWithMetadata(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DIDResolutionMetadata.Error != mtypes.ErrorDeactivated || !result.DIDDocumentMetadata.Deactivated {
+		t.Fatalf("unexpected resolution result after deactivate: %v", result)
+	}
+}
+
+func TestDeriveMemoDID(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 1)
+	defer chain.Close()
+
+	publicKey := &chain.Keys[0].PublicKey
+	did := memo.DeriveMemoDID(publicKey, []byte("wallet"))
+	if other := memo.DeriveMemoDID(publicKey, []byte("wallet")); other.String() != did.String() {
+		t.Fatalf("derivation is not deterministic: %s %s", did, other)
+	}
+	if other := memo.DeriveMemoDID(publicKey, []byte("other")); other.String() == did.String() {
+		t.Fatal("different salts derive the same did")
+	}
+
+	nonce, err := chain.PendingNonceAt(context.Background(), crypto.PubkeyToAddress(*publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if derived := memo.DeriveMemoDID(publicKey, binary.AppendUvarint(nil, nonce)); derived.String() != created.String() {
+		t.Fatalf("unexpected did derived from nonce: %s, want %s", derived, created)
+	}
+
+	resolver, err := memo.NewMemoDIDResolverWithBackend("dev", chain, &contracts.Memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered, err := resolver.IsRegistered(created.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registered {
+		t.Fatal("did should not be registered before register")
+	}
+
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, created.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.RegisterDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered, err = resolver.IsRegistered(created.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !registered {
+		t.Fatal("did should be registered after register")
+	}
+
+	err = controller.DeactivateDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered, err = resolver.IsRegistered(created.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !registered {
+		t.Fatal("deactivated did should still be registered")
+	}
+}
+
+func TestVerificationMethodTypes(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 1)
+	defer chain.Close()
+
+	did, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := memo.NewMemoDIDResolverWithBackend("dev", chain, &contracts.Memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.RegisterDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, g2 := bls12381.Generators()
+	var blsPublicKey bls12381.G2Affine
+	blsPublicKey.ScalarMultiplication(&g2, big.NewInt(42))
+	blsPublicKeyBytes := blsPublicKey.Bytes()
+
+	err = controller.AddVerificationMethod(mtypes.Ed25519VerificationKey2020, *did, hex.EncodeToString(edPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddVerificationMethod(mtypes.X25519KeyAgreementKey2020, *did, hex.EncodeToString(edPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddVerificationMethod(mtypes.Bls12381G2Key2020, *did, hex.EncodeToString(blsPublicKeyBytes[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddVerificationMethod(mtypes.Bls12381G2Key2020, *did, hex.EncodeToString(edPublicKey))
+	if err == nil {
+		t.Fatal("should report an error when adding an invalid bls key")
+	}
+
+	document, err := resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.VerificationMethod) != 4 {
+		t.Fatalf("unexpected verification methods: %v", document.VerificationMethod)
+	}
+	for i, vtype := range []string{mtypes.EcdsaSecp256k1VerificationKey2019, mtypes.Ed25519VerificationKey2020, mtypes.X25519KeyAgreementKey2020, mtypes.Bls12381G2Key2020} {
+		if document.VerificationMethod[i].Type != vtype {
+			t.Fatalf("unexpected type of verification method %d: %s", i, document.VerificationMethod[i].Type)
+		}
+	}
+
+	message := []byte("hello")
+	ok, err := document.VerificationMethod[1].VerifySignature(ed25519.Sign(edPrivateKey, message), message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("signature of ed25519 verification method is not verified")
+	}
+}
+
+func TestServices(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 1)
+	defer chain.Close()
+
+	did, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := memo.NewMemoDIDResolverWithBackend("dev", chain, &contracts.Memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.RegisterDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gateway := mtypes.Service{ID: "gateway", Type: "StorageGateway", ServiceEndpoint: "https://gateway.example.com"}
+	err = controller.AddService(gateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddService(mtypes.Service{ID: "#inbox", Type: "DIDCommMessaging", ServiceEndpoint: "https://inbox.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddService(gateway)
+	if err == nil {
+		t.Fatal("should report an error when adding an existing service")
+	}
+
+	document, err := resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.VerificationMethod) != 1 || len(document.Service) != 2 || document.Service[0].ID != did.String()+"#gateway" {
+		t.Fatalf("unexpected document with services: %v", document)
+	}
+
+	for _, didUrl := range []string{did.String() + "#inbox", did.String() + "?service=inbox"} {
+		service, err := resolver.DereferenceService(didUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if service.Type != "DIDCommMessaging" || service.ServiceEndpoint != "https://inbox.example.com" {
+			t.Fatalf("unexpected service of %s: %v", didUrl, service)
+		}
+	}
+	// services share the slots of verification methods, but they are not keys
+	for _, didUrl := range []string{did.String() + "#key-1", did.String() + "#key-9"} {
+		if _, err := resolver.Dereference(didUrl); err == nil {
+			t.Fatalf("should report an error when dereferencing %s as a key", didUrl)
+		}
+	}
+
+	// the slots of services are not changed as verification methods
+	gatewaySlot, err := did.DIDUrl(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.UpdateVerificationMethod(gatewaySlot, mtypes.EcdsaSecp256k1VerificationKey2019, hex.EncodeToString(crypto.CompressPubkey(&chain.Keys[0].PublicKey)))
+	if err == nil {
+		t.Fatal("should report an error when updating a service as a verification method")
+	}
+	err = controller.DeactivateVerificationMethod(gatewaySlot)
+	if err == nil {
+		t.Fatal("should report an error when deactivating a service as a verification method")
+	}
+	for _, relationType := range []int{mtypes.Authentication, mtypes.AssertionMethod, mtypes.CapabilityDelegation, mtypes.Recovery} {
+		err = controller.AddRelationShip(relationType, gatewaySlot, 100)
+		if err == nil {
+			t.Fatalf("should report an error when adding a service to relationship %d", relationType)
+		}
+	}
+	// a service added to a relationship by the contract directly is not a key of it
+	proxyIns, err := proxy.NewProxy(contracts.Memo.ProxyAddr, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := chain.Transactor(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = proxyIns.AddAuth(opts, did.Identifier, gatewaySlot.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err = resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Authentication) != 1 {
+		t.Fatalf("service is resolved as an authentication: %v", document.Authentication)
+	}
+	if keys, err := resolver.Dereference(did.String() + "#authentication"); err != nil || len(keys) != 1 {
+		t.Fatalf("service is dereferenced as an authentication: %v %v", keys, err)
+	}
+	// services of other dids are not changed
+	other := "did:memo:" + strings.Repeat("ab", 32)
+	err = controller.UpdateService(mtypes.Service{ID: other + "#gateway", Type: "StorageGateway", ServiceEndpoint: "https://gateway2.example.com"})
+	if err == nil {
+		t.Fatal("should report an error when updating a service of another did")
+	}
+	err = controller.RemoveService(other + "#inbox")
+	if err == nil {
+		t.Fatal("should report an error when removing a service of another did")
+	}
+
+	gateway.ID = did.String() + "#gateway"
+	gateway.ServiceEndpoint = "https://gateway2.example.com"
+	err = controller.UpdateService(gateway)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.RemoveService("inbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	document, err = resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Service) != 1 || document.Service[0].ServiceEndpoint != "https://gateway2.example.com" {
+		t.Fatalf("unexpected services after update: %v", document.Service)
+	}
+	if _, err := resolver.DereferenceService(did.String() + "#inbox"); err == nil {
+		t.Fatal("should report an error when dereferencing a removed service")
+	}
+}
+
+func TestDereference(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 2)
+	defer chain.Close()
+
+	did, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := memo.NewMemoDIDResolverWithBackend("dev", chain, &contracts.Memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.RegisterDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered, err := chain.HeaderByNumber(context.TODO(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pk := crypto.CompressPubkey(&chain.Keys[1].PublicKey)
+	err = controller.AddVerificationMethod(mtypes.EcdsaSecp256k1VerificationKey2019, *did, hex.EncodeToString(pk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddService(mtypes.Service{ID: "files", Type: "StorageGateway", ServiceEndpoint: "https://example.com/storage/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := resolver.DereferenceWithMetadata(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document, ok := result.ContentStream.(*mtypes.MemoDIDDocument); !ok || len(document.Service) != 1 ||
+		result.DereferencingMetadata.ContentType != mtypes.ContentTypeDIDLDJSON || result.ContentMetadata.Created == "" {
+		t.Fatalf("unexpected result of did: %v", result)
+	}
+
+	result, err = resolver.DereferenceWithMetadata(did.String() + "#key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, ok := result.ContentStream.(*mtypes.VerificationMethod)
+	if !ok || method.ID.String() != did.String()+"#key-1" || method.Controller.String() != did.String() {
+		t.Fatalf("unexpected result of verification method: %v", result)
+	}
+
+	result, err = resolver.DereferenceWithMetadata(did.String() + "#files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service, ok := result.ContentStream.(*mtypes.Service); !ok || service.Type != "StorageGateway" {
+		t.Fatalf("unexpected result of service: %v", result)
+	}
+
+	result, err = resolver.DereferenceWithMetadata(did.String() + "?service=files&relativeRef=resume.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ContentStream != "https://example.com/storage/resume.pdf" || result.DereferencingMetadata.ContentType != mtypes.ContentTypeURIList {
+		t.Fatalf("unexpected result of service endpoint: %v", result)
+	}
+
+	// the key is added after the version
+	result, err = resolver.DereferenceWithMetadata(did.String() + "?versionId=" + registered.Number.String() + "#key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DereferencingMetadata.Error != mtypes.ErrorNotFound {
+		t.Fatalf("unexpected result of historical verification method: %v", result)
+	}
+
+	for didUrl, code := range map[string]string{
+		did.String() + "#nothing":    mtypes.ErrorNotFound,
+		did.String() + "?service=no": mtypes.ErrorNotFound,
+		did.String() + "?unknown=1":  mtypes.ErrorInvalidDidUrl,
+		"did:memo:1234#key-1":        mtypes.ErrorInvalidDidUrl,
+	} {
+		result, err = resolver.DereferenceWithMetadata(didUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.DereferencingMetadata.Error != code {
+			t.Fatalf("unexpected error of %s: %v", didUrl, result.DereferencingMetadata)
+		}
+	}
+
+	// the keys of a deactivated did are not dereferenced, so what it signed is not verified
+	masterKey, err := did.DIDUrl(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jws.Sign("JWT", []byte(`{"iss":"`+did.String()+`"}`), jws.NewES256KSigner(masterKey, chain.Keys[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := jws.NewVerifier(resolver)
+	_, err = verifier.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.DeactivateDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = resolver.Dereference(masterKey.String())
+	if err == nil {
+		t.Fatal("keys of a deactivated did should not be dereferenced")
+	}
+	_, err = verifier.Verify(token)
+	if err == nil {
+		t.Fatal("jws signed by a deactivated did should not be verified")
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 1)
+	defer chain.Close()
+
+	did, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := memo.NewMemoDIDResolverWithBackend("dev", chain, &contracts.Memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.RegisterDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddVerificationMethod(mtypes.Ed25519VerificationKey2020, *did, hex.EncodeToString(edPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey, err := did.DIDUrl(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deviceKey, err := did.DIDUrl(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddRelationShip(mtypes.AssertionMethod, deviceKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := memo.NewVerifier(resolver)
+	message := []byte("hello")
+
+	masterSig, err := mtypes.NewSecp256k1Signer(masterKey, chain.Keys[0]).Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, err := verifier.Verify(did.String(), masterSig.Signature, message, mtypes.PurposeAuthentication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method.ID.String() != masterKey.String() {
+		t.Fatalf("unexpected verification method matched: %s", method.ID.String())
+	}
+
+	deviceSig, err := mtypes.NewEd25519Signer(deviceKey, edPrivateKey).Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, err = verifier.VerifySignature(deviceSig, message, mtypes.PurposeAssertionMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method.ID.String() != deviceKey.String() {
+		t.Fatalf("unexpected verification method matched: %s", method.ID.String())
+	}
+
+	// the device key is not used for authentication
+	_, err = verifier.VerifySignature(deviceSig, message, mtypes.PurposeAuthentication)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("unexpected error of verifying with key not in relationship: %v", err)
+	}
+	// the master key does not sign
+	_, err = verifier.Verify(masterKey.String(), deviceSig.Signature, message, mtypes.PurposeAssertionMethod)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("unexpected error of verifying with another key: %v", err)
+	}
+	_, err = verifier.Verify(did.String(), masterSig.Signature, []byte("hello!"), mtypes.PurposeAuthentication)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("unexpected error of verifying another message: %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 3)
+	defer chain.Close()
+
+	did, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := memo.NewMemoDIDResolverWithBackend("dev", chain, &contracts.Memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.RegisterDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldPublicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddVerificationMethod(mtypes.Ed25519VerificationKey2020, *did, hex.EncodeToString(oldPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey, err := did.DIDUrl(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddRelationShip(mtypes.Authentication, oldKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddRelationShip(mtypes.AssertionMethod, oldKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddRelationShip(mtypes.CapabilityDelegation, oldKey, 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newPublicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := controller.RotateKey(oldKey, mtypes.Ed25519VerificationKey2020, hex.EncodeToString(newPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newKey.String() != did.String()+"#key-2" {
+		t.Fatalf("unexpected new key %s", newKey.String())
+	}
+
+	document, err := resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.VerificationMethod) != 2 || document.VerificationMethod[1].ID.String() != newKey.String() ||
+		!reflect.DeepEqual(document.AssertionMethod, []mtypes.MemoDIDUrl{newKey}) ||
+		!reflect.DeepEqual(document.CapabilityDelegation, []mtypes.MemoDIDUrl{newKey}) ||
+		len(document.Authentication) != 2 || document.Authentication[1].String() != newKey.String() {
+		t.Fatalf("unexpected document after rotation: %v", document)
+	}
+	if _, err := resolver.Dereference(oldKey.String()); err == nil {
+		t.Fatal("should report an error when dereferencing a rotated key")
+	}
+
+	_, err = controller.RotateKey(oldKey, mtypes.Ed25519VerificationKey2020, hex.EncodeToString(newPublicKey))
+	if err == nil {
+		t.Fatal("should report an error when rotating a deactivated key")
+	}
+	masterKey, err := did.DIDUrl(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = controller.RotateKey(masterKey, mtypes.EcdsaSecp256k1VerificationKey2019, hex.EncodeToString(crypto.CompressPubkey(&chain.Keys[1].PublicKey)))
+	if err == nil {
+		t.Fatal("should report an error when rotating master key by RotateKey")
+	}
+
+	// a rotation interrupted after the assertion is moved is resumed
+	resumedPublicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddVerificationMethod(mtypes.Ed25519VerificationKey2020, *did, hex.EncodeToString(resumedPublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumedKey, err := did.DIDUrl(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddRelationShip(mtypes.AssertionMethod, resumedKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.DeactivateRelationShip(mtypes.AssertionMethod, newKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.ResumeRotateKey(newKey, resumedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err = resolver.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.VerificationMethod) != 2 || document.VerificationMethod[1].ID.String() != resumedKey.String() ||
+		!reflect.DeepEqual(document.AssertionMethod, []mtypes.MemoDIDUrl{resumedKey}) ||
+		!reflect.DeepEqual(document.CapabilityDelegation, []mtypes.MemoDIDUrl{resumedKey}) ||
+		len(document.Authentication) != 2 || document.Authentication[1].String() != resumedKey.String() {
+		t.Fatalf("unexpected document after resuming rotation: %v", document)
+	}
+	// resuming a finished rotation sends no transaction
+	err = controller.ResumeRotateKey(newKey, resumedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new master key signs the following transactions
+	err = controller.RotateMasterKey(chain.Keys[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := resolver.GetMasterKey(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if master != chain.Address(1).Hex() {
+		t.Fatalf("master key is %s after rotation, should be %s", master, chain.Address(1).Hex())
+	}
+	err = controller.AddService(mtypes.Service{ID: "inbox", Type: "DIDCommMessaging", ServiceEndpoint: "https://inbox.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldController, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = oldController.RotateMasterKey(chain.Keys[2])
+	if err == nil {
+		t.Fatal("should report an error when rotating master key with an old key")
+	}
+}
+
+func TestRotateInvalidKey(t *testing.T) {
+	chain, err := simulated.NewChain(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Close()
+
+	did, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keys are checked before reading contracts
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &memo.ContractAddress{}, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := did.DIDUrl(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalidPoint := make([]byte, 33)
+	invalidPoint[0] = 2
+	for _, publicKeyHex := range []string{
+		"not hex",
+		"0x02",
+		hex.EncodeToString(invalidPoint),
+	} {
+		_, err = controller.RotateKey(key, mtypes.EcdsaSecp256k1VerificationKey2019, publicKeyHex)
+		if err == nil {
+			t.Fatalf("should report an error when rotating to %q", publicKeyHex)
+		}
+		err = controller.UpdateVerificationMethod(key, mtypes.EcdsaSecp256k1VerificationKey2019, publicKeyHex)
+		if err == nil {
+			t.Fatalf("should report an error when updating to %q", publicKeyHex)
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 4)
+	defer chain.Close()
+
+	resolver, err := memo.NewMemoDIDResolverWithBackend("dev", chain, &contracts.Memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// dids of the user and two friends recovering it, the first account deploying the contracts relays
+	// the recovery
+	dids := make([]*mtypes.MemoDID, 3)
+	controllers := make([]*memo.MemoDIDController, 3)
+	for i := range dids {
+		dids[i], err = memo.CreatMemoDIDWithBackend(chain.Keys[i+1], chain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		controllers[i], err = memo.NewMemoDIDControllerWithBackend(chain.Keys[i+1], "dev", chain, chain.ChainID, &contracts.Memo, dids[i].String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = controllers[i].RegisterDID()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	did := dids[0]
+
+	err = resolver.CheckRecoveryEligibility(did.String(), 1)
+	if !errors.Is(err, memo.ErrRecoveryThreshold) {
+		t.Fatalf("did without recovery should not be recovered: %v", err)
+	}
+	signers := make([]mtypes.Signer, 3)
+	for i := 1; i < 3; i++ {
+		masterKey, err := dids[i].DIDUrl(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = controllers[0].AddRelationShip(mtypes.Recovery, masterKey, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers[i] = mtypes.NewSecp256k1Signer(masterKey, chain.Keys[i+1])
+	}
+	err = resolver.CheckRecoveryEligibility(did.String(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the key of the user itself is not counted as a recovery did
+	ownKey, err := did.DIDUrl(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controllers[0].AddRelationShip(mtypes.Recovery, ownKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = resolver.CheckRecoveryEligibility(did.String(), 3)
+	if !errors.Is(err, memo.ErrRecoveryThreshold) {
+		t.Fatalf("did with 2 recovery dids should not be recovered by 3: %v", err)
+	}
+
+	// the master key of the user is lost, it is only used below to show that it cannot approve the recovery
+	newSk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := resolver.NewRecoveryRequest(did.String(), &newSk.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigs := make([]*mtypes.Signature, 3)
+	for i := 1; i < 3; i++ {
+		sigs[i], err = request.Approve(signers[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the new master key of the user is not a recovery key
+	masterKey, err := did.DIDUrl(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigs[0], err = request.Approve(mtypes.NewSecp256k1Signer(masterKey, newSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = resolver.VerifyRecovery(request, []*mtypes.Signature{sigs[0], sigs[1], sigs[1]}, 2)
+	if !errors.Is(err, memo.ErrRecoveryThreshold) {
+		t.Fatalf("recovery approved by one recovery did should not be verified: %v", err)
+	}
+	forged := *request
+	forged.NewMasterKey = hex.EncodeToString(crypto.CompressPubkey(&chain.Keys[2].PublicKey))
+	_, err = resolver.VerifyRecovery(&forged, sigs, 2)
+	if !errors.Is(err, memo.ErrRecoveryThreshold) {
+		t.Fatalf("recovery of another key should not be verified: %v", err)
+	}
+	expired := *request
+	expired.ExpirationTime = time.Now().Add(-time.Second)
+	_, err = resolver.VerifyRecovery(&expired, sigs, 2)
+	if !errors.Is(err, memo.ErrRecoveryExpired) {
+		t.Fatalf("expired recovery should not be verified: %v", err)
+	}
+	ownSig, err := request.Approve(mtypes.NewSecp256k1Signer(ownKey, chain.Keys[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = resolver.VerifyRecovery(request, []*mtypes.Signature{ownSig, sigs[1]}, 2)
+	if !errors.Is(err, memo.ErrRecoveryThreshold) {
+		t.Fatalf("recovery approved by the did itself should not be verified: %v", err)
+	}
+	approved, err := resolver.VerifyRecovery(request, append(sigs, ownSig), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(approved) != 2 {
+		t.Fatalf("unexpected recovery keys approving: %v", approved)
+	}
+
+	_, err = memo.NewRecoveryRelayerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, 0)
+	if err == nil {
+		t.Fatal("relayer without threshold should not be created")
+	}
+	relayer, err := memo.NewRecoveryRelayerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer relayer.Close()
+	// the threshold of relayer is required whatever approvals are passed
+	err = relayer.Recover(request, sigs[:2])
+	if !errors.Is(err, memo.ErrRecoveryThreshold) {
+		t.Fatalf("recovery approved by one recovery did should not be relayed: %v", err)
+	}
+	err = relayer.Recover(request, sigs[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := resolver.GetMasterKey(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if master != crypto.PubkeyToAddress(newSk.PublicKey).Hex() {
+		t.Fatalf("master key is %s after recovery, should be %s", master, crypto.PubkeyToAddress(newSk.PublicKey).Hex())
+	}
+
+	// the new master key controls the did, and the lost one does not
+	recovered, err := memo.NewMemoDIDControllerWithBackend(newSk, "dev", chain, chain.ChainID, &contracts.Memo, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = recovered.AddService(mtypes.Service{ID: "inbox", Type: "DIDCommMessaging", ServiceEndpoint: "https://inbox.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controllers[0].AddService(mtypes.Service{ID: "profile", Type: "LinkedDomains", ServiceEndpoint: "https://example.com"})
+	if err == nil {
+		t.Fatal("lost master key should not update the did after recovery")
+	}
+
+	// the request is bound to the master key replaced
+	_, err = resolver.VerifyRecovery(request, sigs, 2)
+	if err == nil {
+		t.Fatal("recovery should not be verified again")
+	}
+}
+
+func TestCachingResolver(t *testing.T) {
+	chain, contracts := simulated.DeployDID(t, 1)
+	defer chain.Close()
+
+	did, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", chain, chain.ChainID, &contracts.Memo, did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := memo.NewMemoDIDResolverWithBackend("dev", chain, &contracts.Memo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := memo.NewCachingResolver(resolver, evm.CacheOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cached.Close()
+
+	result, err := cached.ResolveWithMetadata(did.String())
+	if err != nil || result.DIDResolutionMetadata.Error != mtypes.ErrorNotFound {
+		t.Fatal(result, err)
+	}
+
+	err = controller.RegisterDID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the event of registering invalidates the cached result
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result, err = cached.ResolveWithMetadata(did.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.DIDResolutionMetadata.Error == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cached result is not invalidated after register")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// documents with delegations are not cached, so the delegations are dropped once they expire
+	masterKey, err := did.DIDUrl(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = controller.AddRelationShip(mtypes.CapabilityDelegation, masterKey, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err := cached.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.CapabilityDelegation) != 1 {
+		t.Fatalf("delegation added is not resolved: %v", document.CapabilityDelegation)
+	}
+	time.Sleep(4 * time.Second)
+	document, err = cached.Resolve(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = cached.ResolveWithMetadata(did.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(document.CapabilityDelegation) != 0 || len(result.DIDDocument.CapabilityDelegation) != 0 {
+		t.Fatalf("expired delegation is resolved from cache: %v", document.CapabilityDelegation)
+	}
+}
+
+// pendingChain never packages the transactions sent to it
+type pendingChain struct {
+	*simulated.Chain
+}
+
+func (c pendingChain) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return c.SimulatedBackend.SendTransaction(ctx, tx)
+}
+
+func TestContext(t *testing.T) {
+	chain, err := simulated.NewChain(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Close()
+
+	did, err := memo.CreatMemoDIDWithBackend(chain.Keys[0], chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// there is no contract to estimate gas on
+	opts := evm.Options{Fee: evm.FeePolicy{GasLimit: 300000}}
+	controller, err := memo.NewMemoDIDControllerWithBackend(chain.Keys[0], "dev", pendingChain{chain}, chain.ChainID, &memo.ContractAddress{}, did.String(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = controller.RegisterDIDContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("should stop waiting for receipt when deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("waiting for receipt does not respect deadline")
+	}
+}
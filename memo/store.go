@@ -13,6 +13,10 @@ type DIDController interface {
 	// Relation ship include: authentication; assertionMethod; capabilityDelegation; recovery
 	AddRelationShip(relationType int, didUrl types.MemoDIDUrl, expireTime int64) error
 	DeactivateRelationShip(relationType int, didUrl types.MemoDIDUrl) error
+	// Service endpoints
+	AddService(service types.Service) error
+	UpdateService(service types.Service) error
+	RemoveService(id string) error
 
 	// Update mfile-did
 	BuyReadPermission(did types.MfileDID) error
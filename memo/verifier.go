@@ -0,0 +1,147 @@
+package memo
+
+import (
+	"context"
+
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// ErrNotVerified is returned if a signature is not signed by any key for the proof purpose
+var ErrNotVerified = xerrors.New("signature is not verified")
+
+// DocumentResolver resolves the documents of memo dids, such as MemoDIDResolver and CachingResolver
+type DocumentResolver interface {
+	ResolveContext(ctx context.Context, didString string) (*types.MemoDIDDocument, error)
+}
+
+// Verifier verifies signatures with the keys currently in the verification
+// relationships of memo dids
+type Verifier struct {
+	resolver DocumentResolver
+}
+
+func NewVerifier(resolver DocumentResolver) *Verifier {
+	return &Verifier{resolver: resolver}
+}
+
+// Chain returns the chain that the resolver of verifier resolves dids without chain id on, such as
+// MemoDIDResolver.Chain, or "" if the resolver does not tell it. Such dids are only the same as the
+// ones qualified with the chain if it is known.
+func (v *Verifier) Chain() string {
+	if resolver, ok := v.resolver.(interface{ Chain() string }); ok {
+		return resolver.Chain()
+	}
+	return ""
+}
+
+// Verify checks sig of message is signed by a key of didOrUrl, which is in the relationship of purpose,
+// such as types.PurposeAuthentication. Any key of the relationship may match a did, while only the
+// key of a verification method url matches it. The verification method matched is returned,
+// ErrNotVerified is returned if no one matches.
+func (v *Verifier) Verify(didOrUrl string, sig, message []byte, purpose string) (*types.VerificationMethod, error) {
+	return v.VerifyContext(context.Background(), didOrUrl, sig, message, purpose)
+}
+
+func (v *Verifier) VerifyContext(ctx context.Context, didOrUrl string, sig, message []byte, purpose string) (*types.VerificationMethod, error) {
+	methods, err := v.MethodsContext(ctx, didOrUrl, purpose)
+	if err != nil {
+		return nil, err
+	}
+	for i := range methods {
+		// keys of other types, such as key agreement keys, cannot verify
+		if ok, err := methods[i].VerifySignature(sig, message); err == nil && ok {
+			return &methods[i], nil
+		}
+	}
+	return nil, ErrNotVerified
+}
+
+// Methods returns the verification methods of didOrUrl currently in the relationship
+// of purpose, all of the relationship for a did and at most one for a verification method url.
+func (v *Verifier) Methods(didOrUrl string, purpose string) ([]types.VerificationMethod, error) {
+	return v.MethodsContext(context.Background(), didOrUrl, purpose)
+}
+
+func (v *Verifier) MethodsContext(ctx context.Context, didOrUrl string, purpose string) ([]types.VerificationMethod, error) {
+	didUrl, err := types.ParseDIDURL(didOrUrl)
+	if err != nil {
+		return nil, err
+	}
+	if didUrl.Path != "" || len(didUrl.Query) > 0 {
+		return nil, xerrors.Errorf("%s is not a did or verification method url", didOrUrl)
+	}
+	chain := v.Chain()
+	selected := onChain(types.MemoDIDUrl{Method: didUrl.DID.Method, Identifier: didUrl.DID.Identifier, Identidiers: didUrl.DID.Identifiers, Fragment: didUrl.Fragment}, chain)
+
+	document, err := v.resolver.ResolveContext(ctx, didUrl.DID.String())
+	if err != nil {
+		return nil, err
+	}
+	relationship, err := document.Relationship(purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []types.VerificationMethod
+	documents := map[string]*types.MemoDIDDocument{documentKey(selected): document}
+	for _, id := range relationship {
+		// the relationships without chain id are on the chain of the did
+		id = onChain(id, selected.ChainID())
+		if didUrl.Fragment != "" && !sameMethod(id, selected, "") {
+			continue
+		}
+
+		method, err := v.method(ctx, documents, id)
+		if err != nil {
+			return nil, err
+		}
+		if method != nil {
+			methods = append(methods, *method)
+		}
+	}
+	return methods, nil
+}
+
+// VerifySignature checks sig signed by a Signer is signed by its verification method for purpose
+func (v *Verifier) VerifySignature(sig *types.Signature, message []byte, purpose string) (*types.VerificationMethod, error) {
+	return v.VerifySignatureContext(context.Background(), sig, message, purpose)
+}
+
+func (v *Verifier) VerifySignatureContext(ctx context.Context, sig *types.Signature, message []byte, purpose string) (*types.VerificationMethod, error) {
+	return v.VerifyContext(ctx, sig.VerificationMethod.String(), sig.Signature, message, purpose)
+}
+
+// method returns the verification method of id, the documents of other dids are
+// resolved and saved in documents by documentKey. nil is returned if it is deactivated.
+func (v *Verifier) method(ctx context.Context, documents map[string]*types.MemoDIDDocument, id types.MemoDIDUrl) (*types.VerificationMethod, error) {
+	document, ok := documents[documentKey(id)]
+	if !ok {
+		did := id.DID()
+		var err error
+		document, err = v.resolver.ResolveContext(ctx, did.String())
+		if err != nil {
+			return nil, err
+		}
+		documents[documentKey(id)] = document
+	}
+
+	for i := range document.VerificationMethod {
+		if sameMethod(document.VerificationMethod[i].ID, id, id.ChainID()) {
+			return &document.VerificationMethod[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// documentKey is the key of the document of the did of id, which is qualified by onChain
+func documentKey(id types.MemoDIDUrl) string {
+	return id.ChainID() + ":" + id.Identifier
+}
+
+// sameMethod reports whether a and b refer to the same verification method on the same chain, the
+// urls without chain id are on chain
+func sameMethod(a, b types.MemoDIDUrl, chain string) bool {
+	a, b = onChain(a, chain), onChain(b, chain)
+	return a.Identifier == b.Identifier && a.ChainID() == b.ChainID() && a.GetMethodIndex() >= 0 && a.GetMethodIndex() == b.GetMethodIndex()
+}
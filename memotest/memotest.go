@@ -0,0 +1,85 @@
+// Package memotest holds memo did documents in memory, so that the packages verifying
+// signatures of memo dids can be tested without a chain.
+package memotest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// Documents resolves the documents in memory by their identifiers
+type Documents map[string]*types.MemoDIDDocument
+
+func (d Documents) ResolveContext(ctx context.Context, didString string) (*types.MemoDIDDocument, error) {
+	did, err := types.ParseMemoDID(didString)
+	if err != nil {
+		return nil, err
+	}
+	document, ok := d[did.Identifier]
+	if !ok {
+		return nil, xerrors.Errorf("%s is not found", didString)
+	}
+	return document, nil
+}
+
+// Identity is a memo did whose keys 0 (secp256k1), 1 (ed25519) and 2 (bls) are
+// assertion methods, and key 3 (ed25519) is only an authentication method.
+type Identity struct {
+	DID      types.MemoDID
+	Sk       *ecdsa.PrivateKey
+	EdSk     ed25519.PrivateKey
+	BlsSk    *big.Int
+	AuthEdSk ed25519.PrivateKey
+	// Methods are the urls of the keys
+	Methods [4]types.MemoDIDUrl
+}
+
+// NewIdentity generates the keys of identifier and adds its document to d
+func (d Documents) NewIdentity(t testing.TB, identifier string) *Identity {
+	t.Helper()
+
+	did := types.MemoDID{Method: "memo", Identifier: identifier, Identifiers: []string{identifier}}
+	i := &Identity{DID: did, BlsSk: big.NewInt(123456789)}
+
+	var err error
+	i.Sk, err = crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, i.EdSk, err = ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, i.AuthEdSk, err = ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	document := &types.MemoDIDDocument{ID: did}
+	for index, key := range []types.PublicKey{
+		types.NewPublicKey(types.EcdsaSecp256k1VerificationKey2019, crypto.CompressPubkey(&i.Sk.PublicKey), types.PublicKeyHexFormat),
+		types.NewPublicKey(types.Ed25519VerificationKey2020, i.EdSk.Public().(ed25519.PublicKey), types.PublicKeyMultibaseFormat),
+		types.NewPublicKey(types.Bls12381G2Key2020, types.BlsPublicKey(i.BlsSk), types.PublicKeyHexFormat),
+		types.NewPublicKey(types.Ed25519VerificationKey2020, i.AuthEdSk.Public().(ed25519.PublicKey), types.PublicKeyJwkFormat),
+	} {
+		i.Methods[index], err = did.DIDUrl(int64(index))
+		if err != nil {
+			t.Fatal(err)
+		}
+		document.VerificationMethod = append(document.VerificationMethod, types.VerificationMethod{ID: i.Methods[index], Controller: did, PublicKey: key})
+		if index < 3 {
+			document.AssertionMethod = append(document.AssertionMethod, i.Methods[index])
+		}
+	}
+	document.Authentication = []types.MemoDIDUrl{i.Methods[3]}
+
+	d[identifier] = document
+	return i
+}
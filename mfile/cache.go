@@ -0,0 +1,112 @@
+package mfile
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	etypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/memoio/go-did/evm"
+	"github.com/memoio/go-did/types"
+)
+
+// CachingResolver caches the documents resolved by a MfileDIDResolver. An entry is
+// invalidated when the file did contract emits an event of its DID, or it expires.
+//
+// The documents returned are shared by callers, and should not be modified.
+type CachingResolver struct {
+	*MfileDIDResolver
+
+	documents *evm.Cache[*types.MfileDIDDocument]
+	results   *evm.Cache[*types.MfileDIDResolutionResult]
+	watcher   *evm.Watcher
+}
+
+// NewCachingResolver caches the documents resolved by resolver, the resolver is
+// closed by Close of the caching resolver.
+func NewCachingResolver(resolver *MfileDIDResolver, opts evm.CacheOptions) (*CachingResolver, error) {
+	c := &CachingResolver{
+		MfileDIDResolver: resolver,
+		documents:        evm.NewCache[*types.MfileDIDDocument](opts),
+		results:          evm.NewCache[*types.MfileDIDResolutionResult](opts),
+	}
+
+	watcher, err := evm.NewWatcher(resolver.backend, []common.Address{resolver.accountAddr}, opts.PollInterval, c.invalidate)
+	if err != nil {
+		return nil, err
+	}
+	c.watcher = watcher
+
+	return c, nil
+}
+
+// invalidate removes the entries of the did of log, which is the first indexed argument
+func (c *CachingResolver) invalidate(log etypes.Log) {
+	if len(log.Topics) < 2 {
+		return
+	}
+	c.documents.Invalidate(log.Topics[1])
+	c.results.Invalidate(log.Topics[1])
+}
+
+// Close stops watching events and closes the resolver
+func (c *CachingResolver) Close() {
+	c.watcher.Close()
+	c.MfileDIDResolver.Close()
+}
+
+func (c *CachingResolver) Resolve(didString string) (*types.MfileDIDDocument, error) {
+	return c.ResolveContext(context.Background(), didString)
+}
+
+func (c *CachingResolver) ResolveContext(ctx context.Context, didString string) (*types.MfileDIDDocument, error) {
+	did, ok := cacheable(didString)
+	if !ok {
+		return c.MfileDIDResolver.ResolveContext(ctx, didString)
+	}
+	if document, ok := c.documents.Get(didString); ok {
+		return document, nil
+	}
+
+	generation := c.documents.Generation()
+	document, err := c.MfileDIDResolver.ResolveContext(ctx, didString)
+	if err != nil {
+		return nil, err
+	}
+	c.documents.Add(generation, didString, document, evm.Topic(did.Identifier))
+	return document, nil
+}
+
+func (c *CachingResolver) ResolveWithMetadata(didString string) (*types.MfileDIDResolutionResult, error) {
+	return c.ResolveWithMetadataContext(context.Background(), didString)
+}
+
+func (c *CachingResolver) ResolveWithMetadataContext(ctx context.Context, didString string) (*types.MfileDIDResolutionResult, error) {
+	did, ok := cacheable(didString)
+	if !ok {
+		return c.MfileDIDResolver.ResolveWithMetadataContext(ctx, didString)
+	}
+	if result, ok := c.results.Get(didString); ok {
+		return result, nil
+	}
+
+	generation := c.results.Generation()
+	result, err := c.MfileDIDResolver.ResolveWithMetadataContext(ctx, didString)
+	if err != nil {
+		return nil, err
+	}
+	c.results.Add(generation, didString, result, evm.Topic(did.Identifier))
+	return result, nil
+}
+
+// cacheable parses didString and reports whether it is valid
+func cacheable(didString string) (*types.MfileDID, bool) {
+	plain, _, err := types.SplitVersion(didString)
+	if err != nil {
+		return nil, false
+	}
+	did, err := types.ParseMfileDID(plain)
+	if err != nil {
+		return nil, false
+	}
+	return did, true
+}
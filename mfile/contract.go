@@ -0,0 +1,67 @@
+package mfile
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/memoio/go-did/evm"
+
+	com "github.com/memoio/contractsv2/common"
+	inst "github.com/memoio/contractsv2/go_contracts/instance"
+)
+
+// ContractAddress is the address of the contracts that mfile did interacts with
+type ContractAddress struct {
+	// did proxy, all transactions are sent to it
+	ProxyAddr common.Address
+	// file did, resolver reads did documents from it
+	FileDidAddr common.Address
+}
+
+// GetContractAddress reads the contract address from the instance contract
+func GetContractAddress(backend bind.ContractBackend, instanceAddr common.Address) (*ContractAddress, error) {
+	return GetContractAddressContext(context.Background(), backend, instanceAddr)
+}
+
+func GetContractAddressContext(ctx context.Context, backend bind.ContractBackend, instanceAddr common.Address) (*ContractAddress, error) {
+	instanceIns, err := inst.NewInstance(instanceAddr, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	// get proxyAddr
+	proxyAddr, err := instanceIns.Instances(&bind.CallOpts{Context: ctx}, com.TypeDidProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	// get fileDidAddr
+	fileDidAddr, err := instanceIns.Instances(&bind.CallOpts{Context: ctx}, com.TypeFileDid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContractAddress{
+		ProxyAddr:   proxyAddr,
+		FileDidAddr: fileDidAddr,
+	}, nil
+}
+
+// dialChain connects to the endpoint of chain and reads contract address from its instance contract
+func dialChain(ctx context.Context, chain string, opts ...evm.Options) (*evm.Client, *ContractAddress, error) {
+	instanceAddr, endpoint := com.GetInsEndPointByChain(chain)
+
+	client, err := evm.GetOptions(opts...).Dial(ctx, endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	addrs, err := GetContractAddressContext(ctx, client, instanceAddr)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+
+	return client, addrs, nil
+}
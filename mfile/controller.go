@@ -4,55 +4,53 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"math/big"
-	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	etypes "github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
-	com "github.com/memoio/contractsv2/common"
-	inst "github.com/memoio/contractsv2/go_contracts/instance"
 	"github.com/memoio/did-solidity/go-contracts/proxy"
+	"github.com/memoio/go-did/evm"
 	"github.com/memoio/go-did/types"
 	"golang.org/x/xerrors"
 )
 
-var (
-	checkTxSleepTime = 6 // wait 6s(blocktime + 1s)
-	nextBlockTime    = 5 // blocktime
-)
-
 type MfileDIDController struct {
 	did           *types.MfileDID
-	endpoint      string
+	backend       evm.Backend
 	privateKey    *ecdsa.PrivateKey
 	didTransactor *bind.TransactOpts
+	sender        *evm.Sender
 	proxyAddr     common.Address
+
+	// client dialed by controller, nil if backend is passed in
+	client *evm.Client
 }
 
 var _ MfileStore = &MfileDIDController{}
 
-func NewMfileDIDController(privateKey *ecdsa.PrivateKey, chain, didString string) (*MfileDIDController, error) {
-	instanceAddr, endpoint := com.GetInsEndPointByChain(chain)
+func NewMfileDIDController(privateKey *ecdsa.PrivateKey, chain, didString string, opts ...evm.Options) (*MfileDIDController, error) {
+	return NewMfileDIDControllerContext(context.Background(), privateKey, chain, didString, opts...)
+}
 
-	client, err := ethclient.DialContext(context.TODO(), endpoint)
+func NewMfileDIDControllerContext(ctx context.Context, privateKey *ecdsa.PrivateKey, chain, didString string, opts ...evm.Options) (*MfileDIDController, error) {
+	client, addrs, err := dialChain(ctx, chain, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	chainID, err := client.NetworkID(context.Background())
-	if err != nil {
-		chainID = big.NewInt(985)
-	}
-
-	// new instanceIns
-	instanceIns, err := inst.NewInstance(instanceAddr, client)
+	controller, err := NewMfileDIDControllerWithBackend(privateKey, client, client.ChainID(), addrs, didString, opts...)
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
+	controller.client = client
+	return controller, nil
+}
 
-	// get proxyAddr
-	proxyAddr, err := instanceIns.Instances(&bind.CallOpts{}, com.TypeDidProxy)
+// NewMfileDIDControllerWithBackend creates a controller which sends transactions through backend,
+// such as a simulated backend, rather than dialing the endpoint of chain.
+func NewMfileDIDControllerWithBackend(privateKey *ecdsa.PrivateKey, backend evm.Backend, chainID *big.Int, addrs *ContractAddress, didString string, opts ...evm.Options) (*MfileDIDController, error) {
+	did, err := types.ParseMfileDID(didString)
 	if err != nil {
 		return nil, err
 	}
@@ -62,219 +60,235 @@ func NewMfileDIDController(privateKey *ecdsa.PrivateKey, chain, didString string
 	if err != nil {
 		return nil, err
 	}
-	auth.Value = big.NewInt(0)     // in wei
-	auth.GasLimit = uint64(300000) // in units
-	auth.GasPrice = big.NewInt(1000)
+	auth.Value = big.NewInt(0) // in wei
+	// gas limit and fees are decided by fee policy of options
+	o := evm.GetOptions(opts...)
 
-	did, err := types.ParseMfileDID(didString)
 	return &MfileDIDController{
 		did:           did,
-		endpoint:      endpoint,
+		backend:       backend,
 		privateKey:    privateKey,
 		didTransactor: auth,
-		proxyAddr:     proxyAddr,
-	}, err
+		sender:        evm.NewSender(auth, o.NewWaiter(backend, evm.ABIs(proxy.ProxyMetaData, proxy.IFileDidMetaData)...), o.Fee),
+		proxyAddr:     addrs.ProxyAddr,
+	}, nil
+}
+
+// Close closes the client dialed by controller, the backend passed to
+// NewMfileDIDControllerWithBackend is not closed.
+func (c *MfileDIDController) Close() {
+	if c.client != nil {
+		c.client.Close()
+	}
 }
 
 func (c *MfileDIDController) DID() *types.MfileDID {
 	return c.did
 }
 
+// Waiter returns the waiter of transactions sent by controller,
+// its confirmations and timeout can be changed before sending transactions.
+func (c *MfileDIDController) Waiter() *evm.Waiter {
+	return c.sender.Waiter()
+}
+
 func (c *MfileDIDController) RegisterDID(encode string, ftype uint8, price *big.Int, keywords []string, controller types.MemoDID) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return c.RegisterDIDContext(context.Background(), encode, ftype, price, keywords, controller)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MfileDIDController) RegisterDIDContext(ctx context.Context, encode string, ftype uint8, price *big.Int, keywords []string, controller types.MemoDID) error {
+	tx, err := c.RegisterDIDAsync(ctx, encode, ftype, price, keywords, controller)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proxyIns.RegisterMfileDid(c.didTransactor, c.did.Identifier, encode, ftype, controller.Identifier, price, keywords)
+func (c *MfileDIDController) RegisterDIDAsync(ctx context.Context, encode string, ftype uint8, price *big.Int, keywords []string, controller types.MemoDID) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.proxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "RegisterDID")
+	return c.sender.Send(ctx, "RegisterDID", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.RegisterMfileDid(opts, c.did.Identifier, encode, ftype, controller.Identifier, price, keywords)
+	})
 }
 
 func (c *MfileDIDController) ChangeController(controller types.MemoDID) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return c.ChangeControllerContext(context.Background(), controller)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MfileDIDController) ChangeControllerContext(ctx context.Context, controller types.MemoDID) error {
+	tx, err := c.ChangeControllerAsync(ctx, controller)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proxyIns.ChangeController(c.didTransactor, c.did.Identifier, controller.Identifier)
+func (c *MfileDIDController) ChangeControllerAsync(ctx context.Context, controller types.MemoDID) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.proxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "ChangeController")
+	return c.sender.Send(ctx, "ChangeController", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.ChangeController(opts, c.did.Identifier, controller.Identifier)
+	})
 }
 
 func (c *MfileDIDController) ChangeFileType(ftype uint8) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return c.ChangeFileTypeContext(context.Background(), ftype)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MfileDIDController) ChangeFileTypeContext(ctx context.Context, ftype uint8) error {
+	tx, err := c.ChangeFileTypeAsync(ctx, ftype)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proxyIns.ChangeFtype(c.didTransactor, c.did.Identifier, ftype)
+func (c *MfileDIDController) ChangeFileTypeAsync(ctx context.Context, ftype uint8) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.proxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "ChangeFileType")
+	return c.sender.Send(ctx, "ChangeFileType", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.ChangeFtype(opts, c.did.Identifier, ftype)
+	})
 }
 
 func (c *MfileDIDController) ChangePrice(price *big.Int) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return c.ChangePriceContext(context.Background(), price)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MfileDIDController) ChangePriceContext(ctx context.Context, price *big.Int) error {
+	tx, err := c.ChangePriceAsync(ctx, price)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proxyIns.ChangePrice(c.didTransactor, c.did.Identifier, price)
+func (c *MfileDIDController) ChangePriceAsync(ctx context.Context, price *big.Int) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.proxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "ChangePrice")
+	return c.sender.Send(ctx, "ChangePrice", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.ChangePrice(opts, c.did.Identifier, price)
+	})
 }
 
 func (c *MfileDIDController) ChangeKeywords(keywords []string) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return c.ChangeKeywordsContext(context.Background(), keywords)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MfileDIDController) ChangeKeywordsContext(ctx context.Context, keywords []string) error {
+	tx, err := c.ChangeKeywordsAsync(ctx, keywords)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proxyIns.ChangeKeywords(c.didTransactor, c.did.Identifier, keywords)
+func (c *MfileDIDController) ChangeKeywordsAsync(ctx context.Context, keywords []string) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.proxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "ChangeKeywords")
+	return c.sender.Send(ctx, "ChangeKeywords", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.ChangeKeywords(opts, c.did.Identifier, keywords)
+	})
 }
 
 func (c *MfileDIDController) AddRelationShip(relationType int, did types.MemoDID) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return c.AddRelationShipContext(context.Background(), relationType, did)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MfileDIDController) AddRelationShipContext(ctx context.Context, relationType int, did types.MemoDID) error {
+	tx, err := c.AddRelationShipAsync(ctx, relationType, did)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	var tx *etypes.Transaction
-	switch relationType {
-	case types.Read:
-		tx, err = proxyIns.GrantRead(c.didTransactor, c.did.Identifier, did.Identifier)
-	default:
-		return xerrors.Errorf("unsupported relation ships")
-	}
+func (c *MfileDIDController) AddRelationShipAsync(ctx context.Context, relationType int, did types.MemoDID) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.proxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "AddRelationShip")
+	return c.sender.Send(ctx, "AddRelationShip", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		switch relationType {
+		case types.Read:
+			return proxyIns.GrantRead(opts, c.did.Identifier, did.Identifier)
+		default:
+			return nil, xerrors.Errorf("unsupported relation ships")
+		}
+	})
 }
 
 func (c *MfileDIDController) DeactivateRelationShip(relationType int, didUrl types.MemoDID) error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return c.DeactivateRelationShipContext(context.Background(), relationType, didUrl)
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MfileDIDController) DeactivateRelationShipContext(ctx context.Context, relationType int, didUrl types.MemoDID) error {
+	tx, err := c.DeactivateRelationShipAsync(ctx, relationType, didUrl)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	var tx *etypes.Transaction
-	switch relationType {
-	case types.Read:
-		tx, err = proxyIns.DeactivateRead(c.didTransactor, c.did.Identifier, didUrl.Identifier)
-	default:
-		return xerrors.Errorf("unsupported relation ships")
-	}
+func (c *MfileDIDController) DeactivateRelationShipAsync(ctx context.Context, relationType int, didUrl types.MemoDID) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.proxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "DactivateRelationShip")
+	return c.sender.Send(ctx, "DactivateRelationShip", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		switch relationType {
+		case types.Read:
+			return proxyIns.DeactivateRead(opts, c.did.Identifier, didUrl.Identifier)
+		default:
+			return nil, xerrors.Errorf("unsupported relation ships")
+		}
+	})
 }
 
 func (c *MfileDIDController) DeactivateDID() error {
-	client, err := ethclient.DialContext(context.TODO(), c.endpoint)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
+	return c.DeactivateDIDContext(context.Background())
+}
 
-	proxyIns, err := proxy.NewProxy(c.proxyAddr, client)
+func (c *MfileDIDController) DeactivateDIDContext(ctx context.Context) error {
+	tx, err := c.DeactivateDIDAsync(ctx)
 	if err != nil {
 		return err
 	}
+	return tx.Check(ctx)
+}
 
-	tx, err := proxyIns.DeactivateMfileDid(c.didTransactor, c.did.Identifier, true)
+func (c *MfileDIDController) DeactivateDIDAsync(ctx context.Context) (*evm.PendingTx, error) {
+	proxyIns, err := proxy.NewProxy(c.proxyAddr, c.backend)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return CheckTx(c.endpoint, tx.Hash(), "DeactivateDID")
+	return c.sender.Send(ctx, "DeactivateDID", func(opts *bind.TransactOpts) (*etypes.Transaction, error) {
+		return proxyIns.DeactivateMfileDid(opts, c.did.Identifier, true)
+	})
 }
 
+// CheckTx check whether transaction is successful through receipt
+//
+// Deprecated: use evm.Waiter, the error of which can be inspected.
 func CheckTx(endPoint string, txHash common.Hash, name string) error {
-	var receipt *etypes.Receipt
-
-	t := checkTxSleepTime
-	for i := 0; i < 10; i++ {
-		time.Sleep(time.Duration(t) * time.Second)
-		receipt = com.GetTransactionReceipt(endPoint, txHash)
-		if receipt != nil {
-			break
-		}
-		t = nextBlockTime
-	}
-
-	if receipt == nil {
-		return xerrors.Errorf("%s: cann't get transaction(%s) receipt, not packaged", name, txHash)
-	}
-
-	// 0 means fail
-	if receipt.Status == 0 {
-		if receipt.GasUsed != receipt.CumulativeGasUsed {
-			return xerrors.Errorf("%s: transaction(%s) exceed gas limit", name, txHash)
-		}
-		return xerrors.Errorf("%s: transaction(%s) mined but execution failed, please check your tx input", name, txHash)
-	}
-	return nil
+	return evm.CheckTx(context.TODO(), endPoint, txHash, name, evm.ABIs(proxy.ProxyMetaData, proxy.IFileDidMetaData)...)
 }
@@ -2,83 +2,204 @@ package mfile
 
 import (
 	"context"
+	"errors"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	com "github.com/memoio/contractsv2/common"
-	inst "github.com/memoio/contractsv2/go_contracts/instance"
 	"github.com/memoio/did-solidity/go-contracts/proxy"
+	"github.com/memoio/go-did/evm"
 	"github.com/memoio/go-did/types"
 )
 
 var DefaultContext = "https://www.w3.org/ns/did/v1"
 
 type MfileDIDResolver struct {
-	endpoint    string
+	backend     bind.ContractBackend
 	accountAddr common.Address
+
+	// client dialed by resolver, nil if backend is passed in
+	client *evm.Client
+}
+
+func NewMfileDIDResolver(chain string, opts ...evm.Options) (*MfileDIDResolver, error) {
+	return NewMfileDIDResolverContext(context.Background(), chain, opts...)
 }
 
-func NewMfileDIDResolver(chain string) (*MfileDIDResolver, error) {
+func NewMfileDIDResolverContext(ctx context.Context, chain string, opts ...evm.Options) (*MfileDIDResolver, error) {
 	if chain == "" {
 		chain = com.DevChain
 	}
 
-	instanceAddr, endpoint := com.GetInsEndPointByChain(chain)
+	client, addrs, err := dialChain(ctx, chain, opts...)
+	if err != nil {
+		return nil, err
+	}
 
-	client, err := ethclient.DialContext(context.TODO(), endpoint)
+	resolver, err := NewMfileDIDResolverWithBackend(client, addrs)
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
+	resolver.client = client
+	return resolver, nil
+}
 
-	// new instanceIns
-	instanceIns, err := inst.NewInstance(instanceAddr, client)
+// NewMfileDIDResolverWithBackend creates a resolver which reads DID documents through backend
+func NewMfileDIDResolverWithBackend(backend bind.ContractBackend, addrs *ContractAddress) (*MfileDIDResolver, error) {
+	return &MfileDIDResolver{
+		backend:     backend,
+		accountAddr: addrs.FileDidAddr,
+	}, nil
+}
+
+// Close closes the client dialed by resolver, the backend passed to
+// NewMfileDIDResolverWithBackend is not closed.
+func (r *MfileDIDResolver) Close() {
+	if r.client != nil {
+		r.client.Close()
+	}
+}
+
+func (r *MfileDIDResolver) Resolve(didString string) (*types.MfileDIDDocument, error) {
+	return r.ResolveContext(context.Background(), didString)
+}
+
+// ResolveContext resolves the document of did, the did may select a historical
+// version by the versionId (block number) or versionTime parameter.
+func (r *MfileDIDResolver) ResolveContext(ctx context.Context, didString string) (*types.MfileDIDDocument, error) {
+	plain, version, err := types.SplitVersion(didString)
+	if err != nil {
+		return nil, err
+	}
+	did, err := types.ParseMfileDID(plain)
 	if err != nil {
 		return nil, err
 	}
 
-	accountAddr, err := instanceIns.Instances(&bind.CallOpts{}, com.TypeFileDid)
+	block, err := evm.VersionBlock(ctx, r.backend, version)
 	if err != nil {
 		return nil, err
 	}
 
-	return &MfileDIDResolver{
-		endpoint:    endpoint,
-		accountAddr: accountAddr,
-	}, nil
+	accountIns, err := proxy.NewIFileDid(r.accountAddr, r.backend)
+	if err != nil {
+		return nil, err
+	}
+
+	deactivated, err := accountIns.Deactivated(&bind.CallOpts{Context: ctx, BlockNumber: block}, did.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	if deactivated {
+		return &types.MfileDIDDocument{}, nil
+	}
+
+	return r.document(ctx, accountIns, did, block, nil)
 }
 
-func (r *MfileDIDResolver) Resolve(didString string) (*types.MfileDIDDocument, error) {
-	did, err := types.ParseMfileDID(didString)
+// DocumentAt reads the document of did at block, the latest if block is nil, with the memo dids of
+// the BuyRead and GrantRead events up to block, instead of filtering the events from the genesis block.
+// The read permissions bought are before the ones granted in reads, such as the ones saved by an
+// indexer. The document is empty if did is deactivated.
+func (r *MfileDIDResolver) DocumentAt(ctx context.Context, did *types.MfileDID, block *big.Int, reads []string) (*types.MfileDIDDocument, error) {
+	accountIns, err := proxy.NewIFileDid(r.accountAddr, r.backend)
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := ethclient.DialContext(context.TODO(), r.endpoint)
+	deactivated, err := accountIns.Deactivated(&bind.CallOpts{Context: ctx, BlockNumber: block}, did.Identifier)
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
+	if deactivated {
+		return &types.MfileDIDDocument{}, nil
+	}
 
-	accountIns, err := proxy.NewIFileDid(r.accountAddr, client)
+	if reads == nil {
+		reads = []string{}
+	}
+	return r.document(ctx, accountIns, did, block, reads)
+}
+
+// ResolveWithMetadata resolves DID as W3C DID Resolution, the errors of resolving,
+// such as invalid or deactivated DID, are set in the resolution metadata of result.
+// An error is returned only if the chain cannot be read.
+func (r *MfileDIDResolver) ResolveWithMetadata(didString string) (*types.MfileDIDResolutionResult, error) {
+	return r.ResolveWithMetadataContext(context.Background(), didString)
+}
+
+func (r *MfileDIDResolver) ResolveWithMetadataContext(ctx context.Context, didString string) (*types.MfileDIDResolutionResult, error) {
+	result := &types.MfileDIDResolutionResult{Context: types.ResolutionContext}
+
+	plain, version, err := types.SplitVersion(didString)
+	if err != nil {
+		result.DIDResolutionMetadata.Error = types.ErrorInvalidDid
+		return result, nil
+	}
+	did, err := types.ParseMfileDID(plain)
+	if err != nil {
+		result.DIDResolutionMetadata.Error = types.ParseErrorCode(plain, "mfile")
+		return result, nil
+	}
+
+	block, err := evm.VersionBlock(ctx, r.backend, version)
+	if errors.Is(err, evm.ErrVersionNotFound) {
+		result.DIDResolutionMetadata.Error = types.ErrorNotFound
+		return result, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	deactivated, err := accountIns.Deactivated(&bind.CallOpts{}, did.Identifier)
+	accountIns, err := proxy.NewIFileDid(r.accountAddr, r.backend)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	deactivated, err := accountIns.Deactivated(opts, did.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	controller, err := accountIns.GetController(opts, did.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	if !deactivated && controller == "" {
+		result.DIDResolutionMetadata.Error = types.ErrorNotFound
+		return result, nil
+	}
+
+	result.DIDDocumentMetadata, err = evm.ReadMetadataAt(ctx, r.backend, r.accountAddr, did.Identifier, block)
 	if err != nil {
 		return nil, err
 	}
 	if deactivated {
-		return &types.MfileDIDDocument{}, nil
+		result.DIDDocumentMetadata.Deactivated = true
+		result.DIDResolutionMetadata.Error = types.ErrorDeactivated
+		return result, nil
 	}
 
-	encode, err := accountIns.GetEncode(&bind.CallOpts{}, did.Identifier)
+	result.DIDDocument, err = r.document(ctx, accountIns, did, block, nil)
 	if err != nil {
 		return nil, err
 	}
+	result.DIDResolutionMetadata.ContentType = types.ContentTypeDIDLDJSON
+	return result, nil
+}
 
-	ftype, err := accountIns.GetFtype(&bind.CallOpts{}, did.Identifier)
+// document reads the document of an activated did at block, the latest if block is nil,
+// the memo dids reading did are filtered from events if reads is nil
+func (r *MfileDIDResolver) document(ctx context.Context, accountIns *proxy.IFileDid, did *types.MfileDID, block *big.Int, reads []string) (*types.MfileDIDDocument, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	encode, err := accountIns.GetEncode(opts, did.Identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	ftype, err := accountIns.GetFtype(opts, did.Identifier)
 	if err != nil {
 		return nil, err
 	}
@@ -89,17 +210,17 @@ func (r *MfileDIDResolver) Resolve(didString string) (*types.MfileDIDDocument, e
 		ftypeString = "public"
 	}
 
-	price, err := accountIns.GetPrice(&bind.CallOpts{}, did.Identifier)
+	price, err := accountIns.GetPrice(opts, did.Identifier)
 	if err != nil {
 		return nil, err
 	}
 
-	keywords, err := accountIns.GetKeywords(&bind.CallOpts{}, did.Identifier)
+	keywords, err := accountIns.GetKeywords(opts, did.Identifier)
 	if err != nil {
 		return nil, err
 	}
 
-	controller, err := accountIns.GetController(&bind.CallOpts{}, did.Identifier)
+	controller, err := accountIns.GetController(opts, did.Identifier)
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +229,13 @@ func (r *MfileDIDResolver) Resolve(didString string) (*types.MfileDIDDocument, e
 		ctr = &types.MemoDID{Method: "memo"}
 	}
 
-	read, err := QueryAllRead(accountIns, did)
+	if reads == nil {
+		reads, err = filterReads(ctx, accountIns, did, block)
+		if err != nil {
+			return nil, err
+		}
+	}
+	read, err := readsAt(ctx, accountIns, did, block, reads)
 	if err != nil {
 		return nil, err
 	}
@@ -126,53 +253,74 @@ func (r *MfileDIDResolver) Resolve(didString string) (*types.MfileDIDDocument, e
 }
 
 func QueryAllRead(accountIns *proxy.IFileDid, did *types.MfileDID) ([]types.MemoDID, error) {
-	var reads []types.MemoDID
+	return QueryAllReadContext(context.Background(), accountIns, did)
+}
+
+func QueryAllReadContext(ctx context.Context, accountIns *proxy.IFileDid, did *types.MfileDID) ([]types.MemoDID, error) {
+	return QueryAllReadAt(ctx, accountIns, did, nil)
+}
+
+// QueryAllReadAt queries the memo dids which can read did at block, the latest if block is nil
+func QueryAllReadAt(ctx context.Context, accountIns *proxy.IFileDid, did *types.MfileDID, block *big.Int) ([]types.MemoDID, error) {
+	reads, err := filterReads(ctx, accountIns, did, block)
+	if err != nil {
+		return nil, err
+	}
+	return readsAt(ctx, accountIns, did, block, reads)
+}
+
+// filterReads returns the memo dids of the BuyRead events of did up to block, followed by the
+// ones of the GrantRead events
+func filterReads(ctx context.Context, accountIns *proxy.IFileDid, did *types.MfileDID, block *big.Int) ([]string, error) {
+	var reads []string
 
 	// query paid access permissions
-	readIter, err := accountIns.FilterBuyRead(&bind.FilterOpts{}, []string{did.Identifier})
+	readIter, err := accountIns.FilterBuyRead(evm.FilterOpts(ctx, block), []string{did.Identifier})
 	if err != nil {
 		return nil, err
 	}
+	defer readIter.Close()
 	for readIter.Next() {
-		// currently, the controller only supports did:memo, so there is no need to save the prefix.
-		read, err := types.ParseMemoDID("did:memo:" + readIter.Event.MemoDid)
-		if err != nil {
-			return nil, err
-			// continue
-		}
-
-		// check controller is activated or not
-		activated, err := accountIns.Read(&bind.CallOpts{}, did.Identifier, read.Identifier)
-		if err != nil {
-			return nil, err
-		}
-		if activated > 0 {
-			reads = append(reads, *read)
-		}
+		reads = append(reads, readIter.Event.MemoDid)
+	}
+	if err := readIter.Error(); err != nil {
+		return nil, err
 	}
 
 	// query the read permissions granted by the controller for free
-	freeReadIter, err := accountIns.FilterGrantRead(&bind.FilterOpts{}, []string{did.Identifier})
+	freeReadIter, err := accountIns.FilterGrantRead(evm.FilterOpts(ctx, block), []string{did.Identifier})
 	if err != nil {
 		return nil, err
 	}
-
+	defer freeReadIter.Close()
 	for freeReadIter.Next() {
+		reads = append(reads, freeReadIter.Event.MemoDid)
+	}
+
+	return reads, freeReadIter.Error()
+}
+
+// readsAt returns the memo dids in reads which can read did at block
+func readsAt(ctx context.Context, accountIns *proxy.IFileDid, did *types.MfileDID, block *big.Int, reads []string) ([]types.MemoDID, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: block}
+	var result []types.MemoDID
+
+	for _, memoDid := range reads {
 		// currently, the controller only supports did:memo, so there is no need to save the prefix.
-		read, err := types.ParseMemoDID("did:memo:" + freeReadIter.Event.MemoDid)
+		read, err := types.ParseMemoDID("did:memo:" + memoDid)
 		if err != nil {
 			return nil, err
 		}
 
 		// check controller is activated or not
-		activated, err := accountIns.Read(&bind.CallOpts{}, did.Identifier, freeReadIter.Event.MemoDid)
+		activated, err := accountIns.Read(opts, did.Identifier, read.Identifier)
 		if err != nil {
 			return nil, err
 		}
 		if activated > 0 {
-			reads = append(reads, *read)
+			result = append(result, *read)
 		}
 	}
 
-	return reads, nil
+	return result, nil
 }
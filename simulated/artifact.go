@@ -0,0 +1,90 @@
+package simulated
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"golang.org/x/xerrors"
+)
+
+// Artifact is a compiled contract
+type Artifact struct {
+	Name     string
+	ABI      abi.ABI
+	Bytecode []byte
+}
+
+// artifactJSON is the artifact format written by hardhat and truffle
+type artifactJSON struct {
+	ContractName string          `json:"contractName"`
+	ABI          json.RawMessage `json:"abi"`
+	Bytecode     string          `json:"bytecode"`
+}
+
+// LoadArtifact reads a hardhat or truffle artifact from path
+func LoadArtifact(path string) (*Artifact, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var a artifactJSON
+	err = json.Unmarshal(content, &a)
+	if err != nil {
+		return nil, xerrors.Errorf("%s is not a contract artifact: %w", path, err)
+	}
+
+	parsed, err := abi.JSON(strings.NewReader(string(a.ABI)))
+	if err != nil {
+		return nil, xerrors.Errorf("%s: %w", path, err)
+	}
+
+	var bytecode []byte
+	if a.Bytecode != "" && a.Bytecode != "0x" {
+		bytecode, err = hexutil.Decode(a.Bytecode)
+		if err != nil {
+			return nil, xerrors.Errorf("%s: invalid bytecode: %w", path, err)
+		}
+	}
+
+	name := a.ContractName
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	return &Artifact{
+		Name:     name,
+		ABI:      parsed,
+		Bytecode: bytecode,
+	}, nil
+}
+
+// LoadArtifacts reads all deployable artifacts under dir, keyed by contract name.
+// Debug files and interfaces without bytecode are skipped.
+func LoadArtifacts(dir string) (map[string]*Artifact, error) {
+	artifacts := make(map[string]*Artifact)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".json" || strings.HasSuffix(path, ".dbg.json") {
+			return nil
+		}
+
+		artifact, err := LoadArtifact(path)
+		if err != nil || len(artifact.Bytecode) == 0 {
+			return nil
+		}
+		artifacts[artifact.Name] = artifact
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return artifacts, nil
+}
@@ -0,0 +1,85 @@
+// Package simulated runs memo, mfile and file-proof against an in-memory chain,
+// so that the DID and proof lifecycle can be tested without a live chain.
+package simulated
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/memoio/go-did/evm"
+	"golang.org/x/xerrors"
+)
+
+var (
+	// DefaultBalance is the balance of every account in a new chain
+	DefaultBalance = new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e6))
+	// DefaultGasLimit is the gas limit of every block
+	DefaultGasLimit = uint64(30000000)
+)
+
+// Chain is an in-memory chain that mines a block for every transaction,
+// so receipts are available as soon as a transaction is sent.
+type Chain struct {
+	*backends.SimulatedBackend
+
+	ChainID *big.Int
+	// funded accounts, Keys[0] deploys contracts
+	Keys []*ecdsa.PrivateKey
+}
+
+var _ evm.Backend = &Chain{}
+
+// NewChain creates a chain with accounts funded accounts
+func NewChain(accounts int) (*Chain, error) {
+	if accounts <= 0 {
+		return nil, xerrors.Errorf("at least one account is needed")
+	}
+
+	alloc := make(core.GenesisAlloc)
+	keys := make([]*ecdsa.PrivateKey, 0, accounts)
+	for i := 0; i < accounts; i++ {
+		sk, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		alloc[crypto.PubkeyToAddress(sk.PublicKey)] = core.GenesisAccount{Balance: DefaultBalance}
+		keys = append(keys, sk)
+	}
+
+	return &Chain{
+		SimulatedBackend: backends.NewSimulatedBackend(alloc, DefaultGasLimit),
+		ChainID:          params.AllEthashProtocolChanges.ChainID,
+		Keys:             keys,
+	}, nil
+}
+
+// SendTransaction sends tx and mines it into a new block
+func (c *Chain) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	err := c.SimulatedBackend.SendTransaction(ctx, tx)
+	if err != nil {
+		return err
+	}
+	c.Commit()
+	return nil
+}
+
+// Address returns the address of the i-th account
+func (c *Chain) Address(i int) common.Address {
+	return crypto.PubkeyToAddress(c.Keys[i].PublicKey)
+}
+
+// Transactor returns the transact options of the i-th account
+func (c *Chain) Transactor(i int) (*bind.TransactOpts, error) {
+	if i < 0 || i >= len(c.Keys) {
+		return nil, xerrors.Errorf("account %d not exist", i)
+	}
+	return bind.NewKeyedTransactorWithChainID(c.Keys[i], c.ChainID)
+}
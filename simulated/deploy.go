@@ -0,0 +1,243 @@
+package simulated
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	proof "github.com/memoio/go-did/file-proof"
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/mfile"
+	"golang.org/x/xerrors"
+)
+
+// contract names used by DefaultPlan, they are the names of did-solidity artifacts
+const (
+	Token            = "ERC20"
+	Auth             = "Auth"
+	AccountDid       = "AccountDid"
+	FileDid          = "FileDid"
+	FileDidControl   = "FileDidControl"
+	Proxy            = "Proxy"
+	Pledge           = "Pledge"
+	FileProof        = "FileProof"
+	FileProofControl = "FileProofControl"
+	ProxyProof       = "ProxyProof"
+)
+
+// Deployed is the address of contracts deployed so far, keyed by contract name
+type Deployed map[string]common.Address
+
+// Step deploys one contract
+type Step struct {
+	// Contract is the artifact name
+	Contract string
+	// Args returns the constructor arguments, nil means no argument
+	Args func(c *Chain, deployed Deployed) []interface{}
+	// Setup is called after the contract is deployed, such as granting permission to the proxy
+	Setup func(c *Chain, deployed Deployed, artifacts map[string]*Artifact) error
+}
+
+// Grant lets contract To call the permissioned methods of Contract, by calling Method
+// of Contract with the address of To from the deployer.
+type Grant struct {
+	Contract string
+	Method   string
+	To       string
+}
+
+// GrantMethod is the owner-only method the did-solidity contracts use to trust the
+// contract calling them
+const GrantMethod = "setProxy"
+
+// Plan deploys contracts step by step
+type Plan []Step
+
+// DefaultPlan deploys the DID and file proof contracts in dependency order, every
+// contract takes the address of the contracts it depends on. Once a contract calling
+// others is deployed, the contracts it writes grant it permission with GrantMethod:
+// the proxy writes the account did and file did control contracts, which writes the
+// file did contract, and the proof proxy writes the proof control contract, which
+// writes the proof and pledge contracts.
+var DefaultPlan = Plan{
+	{Contract: Token},
+	{Contract: Auth, Args: func(c *Chain, deployed Deployed) []interface{} {
+		var signers [5]common.Address
+		for i := range signers {
+			signers[i] = c.Address(i % len(c.Keys))
+		}
+		return []interface{}{signers}
+	}},
+	{Contract: AccountDid},
+	{Contract: FileDid},
+	{Contract: FileDidControl, Args: dependOn(AccountDid, FileDid, Token), Setup: grant(
+		Grant{Contract: FileDid, Method: GrantMethod, To: FileDidControl},
+	)},
+	{Contract: Proxy, Args: dependOn(AccountDid, FileDidControl), Setup: grant(
+		Grant{Contract: AccountDid, Method: GrantMethod, To: Proxy},
+		Grant{Contract: FileDidControl, Method: GrantMethod, To: Proxy},
+	)},
+	{Contract: Pledge, Args: dependOn(Token)},
+	{Contract: FileProof, Args: dependOn(Token, Pledge)},
+	{Contract: FileProofControl, Args: dependOn(FileProof, Pledge, Auth), Setup: grant(
+		Grant{Contract: FileProof, Method: GrantMethod, To: FileProofControl},
+		Grant{Contract: Pledge, Method: GrantMethod, To: FileProofControl},
+	)},
+	{Contract: ProxyProof, Args: dependOn(FileProofControl, Auth), Setup: grant(
+		Grant{Contract: FileProofControl, Method: GrantMethod, To: ProxyProof},
+	)},
+}
+
+func dependOn(names ...string) func(c *Chain, deployed Deployed) []interface{} {
+	return func(c *Chain, deployed Deployed) []interface{} {
+		args := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			args = append(args, deployed[name])
+		}
+		return args
+	}
+}
+
+// grant returns a setup calling every grant in order
+func grant(grants ...Grant) func(c *Chain, deployed Deployed, artifacts map[string]*Artifact) error {
+	return func(c *Chain, deployed Deployed, artifacts map[string]*Artifact) error {
+		for _, g := range grants {
+			artifact, ok := artifacts[g.Contract]
+			if !ok {
+				return xerrors.Errorf("artifact of %s not found", g.Contract)
+			}
+			addr, ok := deployed[g.Contract]
+			if !ok {
+				return xerrors.Errorf("%s is not deployed", g.Contract)
+			}
+			to, ok := deployed[g.To]
+			if !ok {
+				return xerrors.Errorf("%s is not deployed", g.To)
+			}
+			err := c.Transact(artifact, addr, g.Method, to)
+			if err != nil {
+				return xerrors.Errorf("grant %s on %s: %w", g.To, g.Contract, err)
+			}
+		}
+		return nil
+	}
+}
+
+// Deploy deploys artifact from the first account and waits until its code is on chain
+func (c *Chain) Deploy(artifact *Artifact, args ...interface{}) (common.Address, error) {
+	auth, err := c.Transactor(0)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	_, tx, _, err := bind.DeployContract(auth, artifact.ABI, artifact.Bytecode, c, args...)
+	if err != nil {
+		return common.Address{}, xerrors.Errorf("deploy %s: %w", artifact.Name, err)
+	}
+
+	addr, err := bind.WaitDeployed(context.TODO(), c, tx)
+	if err != nil {
+		return common.Address{}, xerrors.Errorf("deploy %s: %w", artifact.Name, err)
+	}
+	return addr, nil
+}
+
+// Transact calls method of the contract artifact deployed at addr from the first account,
+// and waits until it is mined successfully
+func (c *Chain) Transact(artifact *Artifact, addr common.Address, method string, args ...interface{}) error {
+	if _, ok := artifact.ABI.Methods[method]; !ok {
+		return xerrors.Errorf("%s has no method %s", artifact.Name, method)
+	}
+	auth, err := c.Transactor(0)
+	if err != nil {
+		return err
+	}
+
+	contract := bind.NewBoundContract(addr, artifact.ABI, c, c, c)
+	tx, err := contract.Transact(auth, method, args...)
+	if err != nil {
+		return xerrors.Errorf("%s.%s: %w", artifact.Name, method, err)
+	}
+
+	receipt, err := bind.WaitMined(context.TODO(), c, tx)
+	if err != nil {
+		return xerrors.Errorf("%s.%s: %w", artifact.Name, method, err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return xerrors.Errorf("%s.%s is reverted", artifact.Name, method)
+	}
+	return nil
+}
+
+// Run deploys every step of plan
+func (c *Chain) Run(plan Plan, artifacts map[string]*Artifact) (Deployed, error) {
+	deployed := make(Deployed)
+	for _, step := range plan {
+		artifact, ok := artifacts[step.Contract]
+		if !ok {
+			return nil, xerrors.Errorf("artifact of %s not found", step.Contract)
+		}
+
+		var args []interface{}
+		if step.Args != nil {
+			args = step.Args(c, deployed)
+		}
+
+		addr, err := c.Deploy(artifact, args...)
+		if err != nil {
+			return nil, err
+		}
+		deployed[step.Contract] = addr
+
+		if step.Setup != nil {
+			err = step.Setup(c, deployed, artifacts)
+			if err != nil {
+				return nil, xerrors.Errorf("setup %s: %w", step.Contract, err)
+			}
+		}
+	}
+
+	return deployed, nil
+}
+
+// Contracts is the contract address used by memo, mfile and file-proof
+type Contracts struct {
+	Memo  memo.ContractAddress
+	Mfile mfile.ContractAddress
+	Proof proof.ContractAddress
+}
+
+// DeployContracts deploys the proxy, account did, file did and file proof contracts with DefaultPlan
+func (c *Chain) DeployContracts(artifacts map[string]*Artifact) (*Contracts, error) {
+	deployed, err := c.Run(DefaultPlan, artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	return ContractsOf(deployed), nil
+}
+
+// ContractsOf picks the contract address used by memo, mfile and file-proof from deployed
+func ContractsOf(deployed Deployed) *Contracts {
+	return &Contracts{
+		Memo: memo.ContractAddress{
+			ProxyAddr:          deployed[Proxy],
+			AccountDidAddr:     deployed[AccountDid],
+			FileDidControlAddr: deployed[FileDidControl],
+			TokenAddr:          deployed[Token],
+		},
+		Mfile: mfile.ContractAddress{
+			ProxyAddr:   deployed[Proxy],
+			FileDidAddr: deployed[FileDid],
+		},
+		Proof: proof.ContractAddress{
+			PledgeAddr:       deployed[Pledge],
+			ProofAddr:        deployed[FileProof],
+			ProofControlAddr: deployed[FileProofControl],
+			ProofProxyAddr:   deployed[ProxyProof],
+			TokenAddr:        deployed[Token],
+			AuthAddr:         deployed[Auth],
+		},
+	}
+}
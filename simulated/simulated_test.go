@@ -0,0 +1,103 @@
+package simulated
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// a contract whose get() always returns 1
+const oneArtifact = `{
+	"contractName": "One",
+	"abi": [{"inputs":[],"name":"get","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"pure","type":"function"}],
+	"bytecode": "0x600a600c600039600a6000f3600160005260206000f3"
+}`
+
+func TestChain(t *testing.T) {
+	chain, err := NewChain(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Close()
+
+	nonce, err := chain.PendingNonceAt(context.TODO(), chain.Address(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gasPrice, err := chain.SuggestGasPrice(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := big.NewInt(1000)
+	tx := types.NewTransaction(nonce, chain.Address(1), value, 21000, gasPrice, nil)
+	tx, err = types.SignTx(tx, types.LatestSignerForChainID(chain.ChainID), chain.Keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = chain.SendTransaction(context.TODO(), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// receipt is available without committing
+	receipt, err := chain.TransactionReceipt(context.TODO(), tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatal("transfer failed")
+	}
+
+	balance, err := chain.BalanceAt(context.TODO(), chain.Address(1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Cmp(new(big.Int).Add(DefaultBalance, value)) != 0 {
+		t.Fatalf("unexpected balance %s", balance)
+	}
+}
+
+func TestDeploy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "One.json")
+	err := os.WriteFile(path, []byte(oneArtifact), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts, err := LoadArtifacts(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := NewChain(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Close()
+
+	deployed, err := chain.Run(Plan{{Contract: "One"}}, artifacts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	one := bind.NewBoundContract(deployed["One"], artifacts["One"].ABI, chain, chain, chain)
+	var res []interface{}
+	err = one.Call(&bind.CallOpts{}, &res, "get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0].(*big.Int).Int64() != 1 {
+		t.Fatalf("unexpected result %v", res[0])
+	}
+
+	_, err = chain.Run(Plan{{Contract: "Two"}}, artifacts)
+	if err == nil {
+		t.Fatal("should report an error when artifact is missing")
+	}
+}
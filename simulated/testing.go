@@ -0,0 +1,54 @@
+package simulated
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// ArtifactsEnv is the environment variable holding the directory of did-solidity artifacts
+const ArtifactsEnv = "DID_SOLIDITY_ARTIFACTS"
+
+// ArtifactsDir returns $DID_SOLIDITY_ARTIFACTS, or the artifacts of the did-solidity
+// checkout next to go-did, which is where go.mod replaces did-solidity from.
+func ArtifactsDir() string {
+	if dir := os.Getenv(ArtifactsEnv); dir != "" {
+		return dir
+	}
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "did-solidity", "artifacts")
+}
+
+// DeployDID creates a chain with accounts funded accounts, and deploys the did-solidity
+// artifacts in ArtifactsDir on it with DefaultPlan. If the artifacts are not found, t is
+// skipped, or fails when $CI is set so that the tests on chain are never skipped silently.
+func DeployDID(t testing.TB, accounts int) (*Chain, *Contracts) {
+	t.Helper()
+
+	dir := ArtifactsDir()
+	if _, err := os.Stat(dir); err != nil {
+		if os.Getenv("CI") != "" {
+			t.Fatalf("did-solidity artifacts not found in %s, set %s", dir, ArtifactsEnv)
+		}
+		t.Skipf("did-solidity artifacts not found in %s", dir)
+	}
+
+	artifacts, err := LoadArtifacts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := NewChain(accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contracts, err := chain.DeployContracts(artifacts)
+	if err != nil {
+		chain.Close()
+		t.Fatal(err)
+	}
+
+	return chain, contracts
+}
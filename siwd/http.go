@@ -0,0 +1,101 @@
+package siwd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// AuthorizationScheme is the scheme of the Authorization header carrying a signed message
+const AuthorizationScheme = "SIWD"
+
+type contextKey struct{}
+
+// Authorization returns the value of the Authorization header carrying message and its signature,
+// which is the scheme followed by base64url(message) "." base64url(json(sig)).
+func Authorization(message *Message, sig *types.Signature) (string, error) {
+	data, err := json.Marshal(sig)
+	if err != nil {
+		return "", err
+	}
+	return AuthorizationScheme + " " + base64.RawURLEncoding.EncodeToString([]byte(message.String())) + "." + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// parseAuthorization returns the message and signature in the Authorization header
+func parseAuthorization(header string) (string, *types.Signature, error) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || scheme != AuthorizationScheme {
+		return "", nil, xerrors.Errorf("authorization scheme should be %s", AuthorizationScheme)
+	}
+	encodedMessage, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", nil, xerrors.Errorf("authorization should be message.signature")
+	}
+
+	message, err := base64.RawURLEncoding.DecodeString(encodedMessage)
+	if err != nil {
+		return "", nil, xerrors.Errorf("invalid message: %w", err)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return "", nil, xerrors.Errorf("invalid signature: %w", err)
+	}
+	var sig types.Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return "", nil, xerrors.Errorf("invalid signature: %w", err)
+	}
+	return string(message), &sig, nil
+}
+
+// ChallengeHandler writes a new message for the did in the query, such as /login?did=did:memo:...
+// It is not authenticated, each request saves a nonce until the message expires, so it should be
+// rate-limited for each client, otherwise a MemoryNonceStore can be filled and the handler returns
+// 503 Service Unavailable for a TTL.
+func (s *Server) ChallengeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		message, err := s.ChallengeContext(r.Context(), r.URL.Query().Get("did"))
+		if errors.Is(err, ErrTooManyNonces) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(message.String()))
+	})
+}
+
+// Middleware verifies the signed message in the Authorization header before next serves the
+// request, the did logged in is saved in the request context and returned by FromContext.
+// A message is accepted once, so the middleware usually protects the handler starting a session.
+func (s *Server) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		message, sig, err := parseAuthorization(r.Header.Get("Authorization"))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", AuthorizationScheme)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		did, err := s.VerifyContext(r.Context(), message, sig)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", AuthorizationScheme)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, *did)))
+	})
+}
+
+// FromContext returns the did logged in, which is saved by Middleware
+func FromContext(ctx context.Context) (types.MemoDID, bool) {
+	did, ok := ctx.Value(contextKey{}).(types.MemoDID)
+	return did, ok
+}
@@ -0,0 +1,136 @@
+package siwd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// Version of the message format
+const Version = "1"
+
+const (
+	header        = " wants you to sign in with your DID:"
+	uriTag        = "URI: "
+	versionTag    = "Version: "
+	nonceTag      = "Nonce: "
+	issuedAtTag   = "Issued At: "
+	expirationTag = "Expiration Time: "
+)
+
+// Message is a login challenge issued by a server, it is formatted like EIP-4361 but signed
+// by a key in the authentication of a memo did:
+//
+//	{domain} wants you to sign in with your DID:
+//	{did}
+//
+//	{statement}
+//
+//	URI: {uri}
+//	Version: 1
+//	Nonce: {nonce}
+//	Issued At: {issuedAt}
+//	Expiration Time: {expirationTime}
+//
+// The statement is optional.
+type Message struct {
+	Domain         string
+	DID            string
+	Statement      string
+	URI            string
+	Version        string
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime time.Time
+}
+
+func (m *Message) String() string {
+	var b strings.Builder
+	b.WriteString(m.Domain + header + "\n")
+	b.WriteString(m.DID + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n\n")
+	}
+	b.WriteString(uriTag + m.URI + "\n")
+	b.WriteString(versionTag + m.Version + "\n")
+	b.WriteString(nonceTag + m.Nonce + "\n")
+	b.WriteString(issuedAtTag + m.IssuedAt.UTC().Format(time.RFC3339) + "\n")
+	b.WriteString(expirationTag + m.ExpirationTime.UTC().Format(time.RFC3339))
+	return b.String()
+}
+
+// ParseMessage parses a message formatted by Message.String
+func ParseMessage(message string) (*Message, error) {
+	lines := strings.Split(message, "\n")
+	if len(lines) < 8 {
+		return nil, xerrors.Errorf("message has %d lines, should be at least 8", len(lines))
+	}
+
+	m := &Message{}
+	if !strings.HasSuffix(lines[0], header) {
+		return nil, xerrors.Errorf("invalid message header %q", lines[0])
+	}
+	m.Domain = strings.TrimSuffix(lines[0], header)
+	m.DID = lines[1]
+	if _, err := types.ParseMemoDID(m.DID); err != nil {
+		return nil, xerrors.Errorf("invalid did %s: %w", m.DID, err)
+	}
+	if lines[2] != "" {
+		return nil, xerrors.Errorf("did should be followed by an empty line")
+	}
+
+	fields := lines[3:]
+	switch len(fields) {
+	case 5:
+	case 7:
+		if fields[0] == "" || fields[1] != "" {
+			return nil, xerrors.Errorf("statement should be one line followed by an empty line")
+		}
+		m.Statement = fields[0]
+		fields = fields[2:]
+	default:
+		return nil, xerrors.Errorf("invalid message fields")
+	}
+
+	var err error
+	for i, tag := range []string{uriTag, versionTag, nonceTag, issuedAtTag, expirationTag} {
+		if !strings.HasPrefix(fields[i], tag) {
+			return nil, xerrors.Errorf("field %q should start with %q", fields[i], tag)
+		}
+		value := strings.TrimPrefix(fields[i], tag)
+		switch tag {
+		case uriTag:
+			m.URI = value
+		case versionTag:
+			m.Version = value
+		case nonceTag:
+			m.Nonce = value
+		case issuedAtTag:
+			m.IssuedAt, err = time.Parse(time.RFC3339, value)
+		case expirationTag:
+			m.ExpirationTime, err = time.Parse(time.RFC3339, value)
+		}
+		if err != nil {
+			return nil, xerrors.Errorf("invalid %s: %w", strings.TrimSuffix(tag, ": "), err)
+		}
+	}
+	if m.Version != Version {
+		return nil, xerrors.Errorf("unsupported version %s", m.Version)
+	}
+	return m, nil
+}
+
+// Sign signs message with a key in the authentication of the did of message
+func Sign(message *Message, signer types.Signer) (*types.Signature, error) {
+	did, err := types.ParseMemoDID(message.DID)
+	if err != nil {
+		return nil, err
+	}
+	method := signer.VerificationMethod()
+	if owner := method.DID(); owner.String() != did.String() {
+		return nil, xerrors.Errorf("%s is not a verification method of %s", method.String(), message.DID)
+	}
+	return signer.Sign([]byte(message.String()))
+}
@@ -0,0 +1,243 @@
+package siwd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// DefaultTTL is how long a login message is valid
+var DefaultTTL = 5 * time.Minute
+
+var (
+	// DefaultMaxNonces is the max number of nonces kept by a MemoryNonceStore
+	DefaultMaxNonces = 100000
+	// SweepInterval is how often a MemoryNonceStore removes the nonces expired
+	SweepInterval = time.Minute
+)
+
+var (
+	// ErrNonceNotFound is returned if the nonce of a message is not issued by the server or is used
+	ErrNonceNotFound = xerrors.New("nonce is not issued or is already used")
+	// ErrExpired is returned if a message is expired
+	ErrExpired = xerrors.New("message is expired")
+	// ErrTooManyNonces is returned if a MemoryNonceStore is full of nonces not expired
+	ErrTooManyNonces = xerrors.New("too many nonces are not used")
+	// ErrNotIssued is returned if a message is not the one issued with its nonce
+	ErrNotIssued = xerrors.New("message is not issued by the server")
+)
+
+// NonceStore keeps the nonces issued until they are used or expire, it should be shared
+// by the servers behind the same domain.
+type NonceStore interface {
+	// Add saves nonce which expires at expire, with the hash of the message it is issued in
+	Add(ctx context.Context, nonce string, hash []byte, expire time.Time) error
+	// Use removes nonce and returns the hash saved with it, ErrNonceNotFound is returned if
+	// it is not saved or expires
+	Use(ctx context.Context, nonce string) ([]byte, error)
+}
+
+// MemoryNonceStore keeps at most max nonces in memory, the nonces expired are removed
+// every SweepInterval.
+//
+// The challenges are issued to anyone, so the store can be filled by the clients asking for
+// them without logging in, and Challenge fails with ErrTooManyNonces until the nonces expire.
+// A server open to the internet should rate-limit the challenges of each client.
+type MemoryNonceStore struct {
+	lk        sync.Mutex
+	nonces    map[string]issuedNonce
+	max       int
+	nextSweep time.Time
+}
+
+// issuedNonce is a nonce saved in a MemoryNonceStore
+type issuedNonce struct {
+	hash   []byte
+	expire time.Time
+}
+
+// NewMemoryNonceStore creates a store keeping at most max nonces, DefaultMaxNonces is used if max is 0
+func NewMemoryNonceStore(max int) *MemoryNonceStore {
+	if max <= 0 {
+		max = DefaultMaxNonces
+	}
+	return &MemoryNonceStore{
+		nonces:    make(map[string]issuedNonce),
+		max:       max,
+		nextSweep: time.Now().Add(SweepInterval),
+	}
+}
+
+func (s *MemoryNonceStore) Add(ctx context.Context, nonce string, hash []byte, expire time.Time) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	now := time.Now()
+	if !now.Before(s.nextSweep) {
+		s.sweep(now)
+	}
+	if len(s.nonces) >= s.max {
+		return ErrTooManyNonces
+	}
+	s.nonces[nonce] = issuedNonce{hash: hash, expire: expire}
+	return nil
+}
+
+// sweep removes the nonces expired, it is called with lk held
+func (s *MemoryNonceStore) sweep(now time.Time) {
+	for n, e := range s.nonces {
+		if !now.Before(e.expire) {
+			delete(s.nonces, n)
+		}
+	}
+	s.nextSweep = now.Add(SweepInterval)
+}
+
+func (s *MemoryNonceStore) Use(ctx context.Context, nonce string) ([]byte, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	issued, ok := s.nonces[nonce]
+	if !ok {
+		return nil, ErrNonceNotFound
+	}
+	delete(s.nonces, nonce)
+	if !time.Now().Before(issued.expire) {
+		return nil, ErrNonceNotFound
+	}
+	return issued.hash, nil
+}
+
+// Options configures the messages issued by a server
+type Options struct {
+	// Statement is shown to the user in messages, it must be a single line
+	Statement string
+	// TTL is how long a message is valid, DefaultTTL is used if it is 0
+	TTL time.Duration
+	// Nonces keeps the nonces issued, a MemoryNonceStore is used if it is nil
+	Nonces NonceStore
+}
+
+// Server issues login messages for domain and verifies the signed ones, each message
+// can be used to login once before it expires.
+type Server struct {
+	domain    string
+	uri       string
+	statement string
+	ttl       time.Duration
+	nonces    NonceStore
+	verifier  *memo.Verifier
+}
+
+// NewServer creates a server of domain, uri is the resource users login to. The
+// signatures are verified by the authentication keys resolved by resolver.
+func NewServer(resolver memo.DocumentResolver, domain, uri string, opts Options) (*Server, error) {
+	if strings.ContainsAny(opts.Statement, "\r\n") {
+		return nil, xerrors.Errorf("statement %q should be a single line", opts.Statement)
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.Nonces == nil {
+		opts.Nonces = NewMemoryNonceStore(0)
+	}
+	return &Server{
+		domain:    domain,
+		uri:       uri,
+		statement: opts.Statement,
+		ttl:       opts.TTL,
+		nonces:    opts.Nonces,
+		verifier:  memo.NewVerifier(resolver),
+	}, nil
+}
+
+// Challenge returns a new message for did to sign
+func (s *Server) Challenge(didString string) (*Message, error) {
+	return s.ChallengeContext(context.Background(), didString)
+}
+
+func (s *Server) ChallengeContext(ctx context.Context, didString string) (*Message, error) {
+	if _, err := types.ParseMemoDID(didString); err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	message := &Message{
+		Domain:         s.domain,
+		DID:            didString,
+		Statement:      s.statement,
+		URI:            s.uri,
+		Version:        Version,
+		Nonce:          hex.EncodeToString(nonce),
+		IssuedAt:       now,
+		ExpirationTime: now.Add(s.ttl),
+	}
+	if err := s.nonces.Add(ctx, message.Nonce, messageHash(message.String()), message.ExpirationTime); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// Verify checks message is the one issued by the server, with the same did, statement and times, and
+// it is signed by a key in the authentication of its did, the nonce of message is used so that it cannot
+// login again. The did logged in is returned.
+func (s *Server) Verify(message string, sig *types.Signature) (*types.MemoDID, error) {
+	return s.VerifyContext(context.Background(), message, sig)
+}
+
+func (s *Server) VerifyContext(ctx context.Context, message string, sig *types.Signature) (*types.MemoDID, error) {
+	m, err := ParseMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	if m.Domain != s.domain {
+		return nil, xerrors.Errorf("message is for domain %s, not %s", m.Domain, s.domain)
+	}
+	if m.URI != s.uri {
+		return nil, xerrors.Errorf("message is for uri %s, not %s", m.URI, s.uri)
+	}
+	if !time.Now().Before(m.ExpirationTime) {
+		return nil, ErrExpired
+	}
+
+	did, err := types.ParseMemoDID(m.DID)
+	if err != nil {
+		return nil, err
+	}
+	if signer := sig.VerificationMethod.DID(); signer.String() != did.String() {
+		return nil, xerrors.Errorf("%s is not a verification method of %s", sig.VerificationMethod.String(), m.DID)
+	}
+
+	// the nonce is used after the signature is verified, so that others cannot use it
+	_, err = s.verifier.VerifySignatureContext(ctx, sig, []byte(message), types.PurposeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := s.nonces.Use(ctx, m.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(hash, messageHash(message)) {
+		return nil, ErrNotIssued
+	}
+	return did, nil
+}
+
+// messageHash returns the hash of message saved with its nonce
+func messageHash(message string) []byte {
+	hash := sha256.Sum256([]byte(message))
+	return hash[:]
+}
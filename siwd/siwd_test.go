@@ -0,0 +1,298 @@
+package siwd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/memotest"
+	"github.com/memoio/go-did/types"
+)
+
+func TestMessage(t *testing.T) {
+	for _, statement := range []string{"", "Sign in to the memo gateway"} {
+		message := &Message{
+			Domain:         "gateway.metamemo.one",
+			DID:            "did:memo:megrez:" + strings.Repeat("ab", 32),
+			Statement:      statement,
+			URI:            "https://gateway.metamemo.one/login",
+			Version:        Version,
+			Nonce:          "0123456789abcdef",
+			IssuedAt:       time.Date(2023, 6, 1, 8, 0, 0, 0, time.UTC),
+			ExpirationTime: time.Date(2023, 6, 1, 8, 5, 0, 0, time.UTC),
+		}
+		parsed, err := ParseMessage(message.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.String() != message.String() || parsed.Statement != statement || !parsed.ExpirationTime.Equal(message.ExpirationTime) {
+			t.Fatalf("parsed %+v, should be %+v", parsed, message)
+		}
+	}
+
+	for _, message := range []string{
+		"",
+		"gateway.metamemo.one wants you to sign in with your DID:\ndid:memo:ab\n\nURI: u\nVersion: 1\nNonce: n\nIssued At: 2023-06-01T08:00:00Z\nExpiration Time: 2023-06-01T08:05:00Z",
+		"gateway.metamemo.one wants you to sign in with your DID:\ndid:memo:" + strings.Repeat("ab", 32) + "\n\nURI: u\nVersion: 2\nNonce: n\nIssued At: 2023-06-01T08:00:00Z\nExpiration Time: 2023-06-01T08:05:00Z",
+		"gateway.metamemo.one wants you to sign in with your DID:\ndid:memo:" + strings.Repeat("ab", 32) + "\n\nURI: u\nVersion: 1\nNonce: n\nIssued At: yesterday\nExpiration Time: 2023-06-01T08:05:00Z",
+	} {
+		_, err := ParseMessage(message)
+		if err == nil {
+			t.Fatalf("%q should not be parsed", message)
+		}
+	}
+}
+
+func TestLogin(t *testing.T) {
+	documents := memotest.Documents{}
+	u := documents.NewIdentity(t, strings.Repeat("ab", 32))
+	other := documents.NewIdentity(t, strings.Repeat("cd", 32))
+	server, err := NewServer(documents, "gateway.metamemo.one", "https://gateway.metamemo.one/login", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := server.Challenge(u.DID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := Sign(message, types.NewEd25519Signer(u.Methods[3], u.AuthEdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	did, err := server.Verify(message.String(), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if did.String() != u.DID.String() {
+		t.Fatalf("logged in as %s, should be %s", did.String(), u.DID.String())
+	}
+
+	// the message is replayed
+	_, err = server.Verify(message.String(), sig)
+	if !errors.Is(err, ErrNonceNotFound) {
+		t.Fatalf("message should not be used twice: %v", err)
+	}
+
+	// the key is not an authentication method
+	message, err = server.Challenge(u.DID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err = Sign(message, types.NewEd25519Signer(u.Methods[1], u.EdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.Verify(message.String(), sig)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("message signed by assertion key should not be verified: %v", err)
+	}
+
+	// the message is signed by others
+	_, err = Sign(message, types.NewEd25519Signer(other.Methods[3], other.AuthEdSk))
+	if err == nil {
+		t.Fatal("message should not be signed by the key of others")
+	}
+	sig, err = types.NewEd25519Signer(other.Methods[3], other.AuthEdSk).Sign([]byte(message.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.Verify(message.String(), sig)
+	if err == nil {
+		t.Fatal("message signed by others should not be verified")
+	}
+
+	// the message is for another domain
+	otherServer, err := NewServer(documents, "other.metamemo.one", "https://gateway.metamemo.one/login", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err = Sign(message, types.NewEd25519Signer(u.Methods[3], u.AuthEdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = otherServer.Verify(message.String(), sig)
+	if err == nil {
+		t.Fatal("message should not be verified in another domain")
+	}
+
+	// the message is not issued by the server
+	forged := *message
+	forged.Nonce = "0123456789abcdef"
+	sig, err = Sign(&forged, types.NewEd25519Signer(u.Methods[3], u.AuthEdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.Verify(forged.String(), sig)
+	if !errors.Is(err, ErrNonceNotFound) {
+		t.Fatalf("message not issued should not be verified: %v", err)
+	}
+
+	// the message issued is changed and signed by the user
+	for _, change := range []func(m *Message){
+		func(m *Message) { m.Statement = "Transfer all tokens" },
+		func(m *Message) { m.IssuedAt = m.IssuedAt.Add(-time.Hour) },
+	} {
+		message, err = server.Challenge(u.DID.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed := *message
+		change(&changed)
+		sig, err = Sign(&changed, types.NewEd25519Signer(u.Methods[3], u.AuthEdSk))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = server.Verify(changed.String(), sig)
+		if !errors.Is(err, ErrNotIssued) {
+			t.Fatalf("changed message should not be verified: %v", err)
+		}
+	}
+
+	// the message is expired
+	expired := *message
+	expired.ExpirationTime = time.Now().Add(-time.Second)
+	sig, err = Sign(&expired, types.NewEd25519Signer(u.Methods[3], u.AuthEdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.Verify(expired.String(), sig)
+	if !errors.Is(err, ErrExpired) {
+		t.Fatalf("expired message should not be verified: %v", err)
+	}
+}
+
+func TestNonceStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryNonceStore(2)
+	if err := store.Add(ctx, "a", []byte("a"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(ctx, "b", []byte("b"), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	// the expired nonce is kept until the next sweep
+	if err := store.Add(ctx, "c", []byte("c"), time.Now().Add(time.Minute)); !errors.Is(err, ErrTooManyNonces) {
+		t.Fatalf("full store should not add nonces: %v", err)
+	}
+
+	store.lk.Lock()
+	store.nextSweep = time.Now()
+	store.lk.Unlock()
+	if err := store.Add(ctx, "c", []byte("c"), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Use(ctx, "a"); !errors.Is(err, ErrNonceNotFound) {
+		t.Fatalf("expired nonce should not be used: %v", err)
+	}
+	for _, nonce := range []string{"b", "c"} {
+		hash, err := store.Use(ctx, nonce)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(hash) != nonce {
+			t.Fatalf("hash of nonce %s is %s", nonce, hash)
+		}
+	}
+
+	documents := memotest.Documents{}
+	u := documents.NewIdentity(t, strings.Repeat("ab", 32))
+	server, err := NewServer(documents, "gateway.metamemo.one", "https://gateway.metamemo.one/login", Options{Nonces: NewMemoryNonceStore(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Challenge(u.DID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.Challenge(u.DID.String()); !errors.Is(err, ErrTooManyNonces) {
+		t.Fatalf("challenge should fail if nonces are full: %v", err)
+	}
+
+	for _, statement := range []string{"Sign in\nURI: https://evil.example", "Sign in\r"} {
+		_, err := NewServer(documents, "gateway.metamemo.one", "https://gateway.metamemo.one/login", Options{Statement: statement})
+		if err == nil {
+			t.Fatalf("statement %q should be rejected", statement)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	documents := memotest.Documents{}
+	u := documents.NewIdentity(t, strings.Repeat("ab", 32))
+	server, err := NewServer(documents, "gateway.metamemo.one", "https://gateway.metamemo.one/login", Options{Statement: "Sign in to the memo gateway"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/challenge", server.ChallengeHandler())
+	mux.Handle("/login", server.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		did, ok := FromContext(r.Context())
+		if !ok {
+			t.Error("did is not in context")
+		}
+		w.Write([]byte(did.String()))
+	})))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/challenge?did=" + url.QueryEscape(u.DID.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := ParseMessage(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := Sign(message, types.NewEd25519Signer(u.Methods[3], u.AuthEdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorization, err := Authorization(message, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(authorization string) (int, string) {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/login", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", authorization)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	status, did := login(authorization)
+	if status != http.StatusOK || did != u.DID.String() {
+		t.Fatalf("login returns %d %s", status, did)
+	}
+	status, _ = login(authorization)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("replayed login returns %d", status)
+	}
+	status, _ = login("Bearer token")
+	if status != http.StatusUnauthorized {
+		t.Fatalf("login without signed message returns %d", status)
+	}
+}
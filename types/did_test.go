@@ -182,3 +182,196 @@ func TestParseMemoDIDUrl(t *testing.T) {
 		t.Errorf("Parsing an unsupported did(%s) should report an error", didUrlString7)
 	}
 }
+
+func TestParseChainMemoDID(t *testing.T) {
+	identify := hex.EncodeToString(crypto.Keccak256([]byte("hello")))
+	didString1 := "did:memo:dev:" + identify
+	didString2 := "did:memo:Dev:" + identify
+	didString3 := "did:memo:dev:megrez:" + identify
+	didString4 := "did:memo:dev:" + identify + "#key-1"
+
+	did, err := ParseMemoDID(didString1)
+	if err != nil {
+		t.Fatalf("Parsing %s should not report an error: %s", didString1, err.Error())
+	}
+	if did.ChainID() != "dev" || did.Identifier != identify {
+		t.Fatalf("Unexpected chain id(%s) or identifier(%s)", did.ChainID(), did.Identifier)
+	}
+	if did.String() != didString1 {
+		t.Fatalf("Expected %s, got %s", didString1, did.String())
+	}
+
+	data, err := json.Marshal(did)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var did1 MemoDID
+	err = json.Unmarshal(data, &did1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if did1.String() != didString1 || did1.ChainID() != "dev" {
+		t.Fatalf("Unmarshaled did(%s) is not equal to expected", did1.String())
+	}
+
+	if unqualified := did.WithChainID(""); unqualified.String() != "did:memo:"+identify {
+		t.Fatalf("Unexpected unqualified did %s", unqualified.String())
+	}
+
+	_, err = ParseMemoDID(didString2)
+	if err == nil {
+		t.Errorf("Parsing an unsupported did(%s) should report an error", didString2)
+	}
+
+	_, err = ParseMemoDID(didString3)
+	if err == nil {
+		t.Errorf("Parsing an unsupported did(%s) should report an error", didString3)
+	}
+
+	didUrl, err := ParseMemoDIDUrl(didString4)
+	if err != nil {
+		t.Fatalf("Parsing %s should not report an error: %s", didString4, err.Error())
+	}
+	if didUrl.ChainID() != "dev" || didUrl.String() != didString4 {
+		t.Fatalf("Unexpected did url %s", didUrl.String())
+	}
+	if didUrl.GetMethodIndex() != 1 {
+		t.Fatalf("Unexpected method index %d", didUrl.GetMethodIndex())
+	}
+
+	masterKey, err := did.DIDUrl(0)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if masterKey.String() != didString1+"#masterKey" {
+		t.Fatalf("Unexpected master key id %s", masterKey.String())
+	}
+}
+
+func TestParseErrorCode(t *testing.T) {
+	identify := hex.EncodeToString(crypto.Keccak256([]byte("hello")))
+
+	if code := ParseErrorCode("did:example:"+identify, "memo"); code != ErrorMethodNotSupported {
+		t.Errorf("error code of other method should be %s, got %s", ErrorMethodNotSupported, code)
+	}
+	if code := ParseErrorCode("did:memo:0x"+identify, "memo"); code != ErrorInvalidDid {
+		t.Errorf("error code of invalid id should be %s, got %s", ErrorInvalidDid, code)
+	}
+	if code := ParseErrorCode("dim:memo:"+identify, "memo"); code != ErrorInvalidDid {
+		t.Errorf("error code of invalid scheme should be %s, got %s", ErrorInvalidDid, code)
+	}
+	if code := ParseErrorCode("did:memo:bafkrei", "mfile"); code != ErrorMethodNotSupported {
+		t.Errorf("error code of memo did should be %s, got %s", ErrorMethodNotSupported, code)
+	}
+}
+
+func TestSplitVersion(t *testing.T) {
+	identify := hex.EncodeToString(crypto.Keccak256([]byte("hello")))
+	didString := "did:memo:" + identify
+
+	plain, version, err := SplitVersion(didString)
+	if err != nil || plain != didString || version != nil {
+		t.Fatal(plain, version, err)
+	}
+
+	plain, version, err = SplitVersion(didString + "?versionId=12")
+	if err != nil || plain != didString || version.ID.Int64() != 12 {
+		t.Fatal(plain, version, err)
+	}
+
+	plain, version, err = SplitVersion(didString + "?versionTime=2023-06-01T08:00:00Z")
+	if err != nil || plain != didString || version.Time.Unix() != 1685606400 {
+		t.Fatal(plain, version, err)
+	}
+
+	for _, query := range []string{"?versionId=-1", "?versionId=latest", "?versionTime=yesterday", "?versionId=1&versionTime=2023-06-01T08:00:00Z", "?service=files"} {
+		if _, _, err := SplitVersion(didString + query); err == nil {
+			t.Errorf("split %s should report an error", query)
+		}
+	}
+}
+
+func TestParseServiceUrl(t *testing.T) {
+	identify := hex.EncodeToString(crypto.Keccak256([]byte("hello")))
+	didString := "did:memo:" + identify
+
+	for _, didUrl := range []string{didString + "#gateway", didString + "?service=gateway", didString + "?service=gateway&relativeRef=%2Ffiles"} {
+		did, id, err := ParseServiceUrl(didUrl)
+		if err != nil {
+			t.Fatal(didUrl, err)
+		}
+		if did.String() != didString || id != "gateway" {
+			t.Fatalf("unexpected service of %s: %s %s", didUrl, did, id)
+		}
+	}
+
+	for _, didUrl := range []string{didString, didString + "#masterKey", didString + "#key-1", didString + "#a/b", "did:mfile:" + identify + "#gateway"} {
+		if _, _, err := ParseServiceUrl(didUrl); err == nil {
+			t.Errorf("parse %s should report an error", didUrl)
+		}
+	}
+
+	did, err := ParseMemoDID(didString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncodeService(Service{ID: "#gateway", Type: "LinkedDomains", ServiceEndpoint: "https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service, err := DecodeService(*did, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.ID != didString+"#gateway" || service.Type != "LinkedDomains" || service.ServiceEndpoint != "https://example.com" {
+		t.Fatalf("unexpected service: %v", service)
+	}
+	if _, err := EncodeService(Service{ID: "gateway", Type: "LinkedDomains"}); err == nil {
+		t.Error("encode service without endpoint should report an error")
+	}
+
+	for _, id := range []string{"gateway", "#gateway", didString + "#gateway", "did:memo:985:" + identify + "#gateway"} {
+		if id, err := ServiceIDOf(*did, id); err != nil || id != "gateway" {
+			t.Errorf("unexpected service id %s: %v", id, err)
+		}
+	}
+	other := "did:memo:" + hex.EncodeToString(crypto.Keccak256([]byte("other")))
+	for _, id := range []string{other + "#gateway", "did:mfile:" + identify + "#gateway", "a#gateway"} {
+		if _, err := ServiceIDOf(*did, id); err == nil {
+			t.Errorf("service id of %s should be rejected", id)
+		}
+	}
+	if _, err := ServiceID(other + "#gateway"); err == nil {
+		t.Error("service id of a did url should be rejected without the did")
+	}
+}
+
+func TestParseDIDURL(t *testing.T) {
+	identify := hex.EncodeToString(crypto.Keccak256([]byte("hello")))
+	didString := "did:memo:" + identify
+
+	didUrl, err := ParseDIDURL(didString + "?service=files&relativeRef=%2Fresume.pdf#page-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if didUrl.DID.String() != didString || didUrl.Query.Get("service") != "files" || didUrl.Query.Get("relativeRef") != "/resume.pdf" || didUrl.Fragment != "page-1" {
+		t.Fatalf("unexpected did url: %v", didUrl)
+	}
+	if didUrl.VersionedDID() != didString {
+		t.Fatalf("unexpected versioned did: %s", didUrl.VersionedDID())
+	}
+
+	didUrl, err = ParseDIDURL(didString + "/path?versionTime=2023-06-01T08:00:00Z#key-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if didUrl.Path != "/path" || didUrl.Fragment != "key-1" || didUrl.VersionedDID() != didString+"?versionTime=2023-06-01T08%3A00%3A00Z" {
+		t.Fatalf("unexpected did url: %v %s", didUrl, didUrl.VersionedDID())
+	}
+
+	for _, query := range []string{"?relativeRef=%2Fa", "?a=1", "?versionId=latest", "?service=a&service=b"} {
+		if _, err := ParseDIDURL(didString + query); err == nil {
+			t.Errorf("parse %s should report an error", query)
+		}
+	}
+}
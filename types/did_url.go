@@ -0,0 +1,106 @@
+package types
+
+import (
+	"net/url"
+
+	"github.com/nuts-foundation/did-ockam"
+	"golang.org/x/xerrors"
+)
+
+// parameters of DID URL supported
+var supportParameters = []string{"service", "relativeRef", "versionId", "versionTime"}
+
+// DIDURL is a memo DID URL with any path, query and fragment. MemoDIDUrl only
+// refers to verification methods.
+type DIDURL struct {
+	DID      MemoDID
+	Path     string
+	Query    url.Values
+	Fragment string
+}
+
+// ParseDIDURL parses didUrl, the supported parameters are service, relativeRef,
+// versionId and versionTime.
+func ParseDIDURL(didUrl string) (*DIDURL, error) {
+	parsed, err := did.Parse(didUrl)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Method != "memo" {
+		return nil, xerrors.Errorf("unsupported method %s", parsed.Method)
+	}
+	if err := checkIDStrings(parsed.IDStrings); err != nil {
+		return nil, err
+	}
+
+	query, err := url.ParseQuery(parsed.Query)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range query {
+		if !isSupportParameter(key) {
+			return nil, xerrors.Errorf("unsupported parameter %s", key)
+		}
+		if len(values) != 1 {
+			return nil, xerrors.Errorf("parameter %s should be set once", key)
+		}
+	}
+	if query.Has("relativeRef") && !query.Has("service") {
+		return nil, xerrors.Errorf("relativeRef should be set with service")
+	}
+
+	path := parsed.Path
+	if path != "" {
+		path = "/" + path
+	}
+
+	u := &DIDURL{
+		DID: MemoDID{
+			Method:      parsed.Method,
+			Identifier:  parsed.IDStrings[len(parsed.IDStrings)-1],
+			Identifiers: parsed.IDStrings,
+		},
+		Path:     path,
+		Query:    query,
+		Fragment: parsed.Fragment,
+	}
+	if _, _, err := SplitVersion(u.VersionedDID()); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// VersionedDID returns the DID with the version parameters of u, which is resolved
+// by the resolvers.
+func (u *DIDURL) VersionedDID() string {
+	version := url.Values{}
+	for _, key := range []string{"versionId", "versionTime"} {
+		if u.Query.Has(key) {
+			version.Set(key, u.Query.Get(key))
+		}
+	}
+	if len(version) == 0 {
+		return u.DID.String()
+	}
+	return u.DID.String() + "?" + version.Encode()
+}
+
+func (u *DIDURL) String() string {
+	s := u.DID.String() + u.Path
+	if len(u.Query) > 0 {
+		s += "?" + u.Query.Encode()
+	}
+	if u.Fragment != "" {
+		s += "#" + u.Fragment
+	}
+	return s
+}
+
+func isSupportParameter(key string) bool {
+	for _, parameter := range supportParameters {
+		if parameter == key {
+			return true
+		}
+	}
+	return false
+}
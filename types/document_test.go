@@ -2,11 +2,18 @@ package types
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"encoding/hex"
 	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
 	"testing"
 
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/did-solidity/go-contracts/proxy"
 	"golang.org/x/xerrors"
 )
 
@@ -101,3 +108,292 @@ func TestMarshalMfileDocument(t *testing.T) {
 
 	t.Log(string(data))
 }
+
+func TestVerifySignature(t *testing.T) {
+	message := []byte("hello")
+
+	sk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := crypto.Sign(crypto.Keccak256(message), sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignature(t, PublicKey{
+		Type:         EcdsaSecp256k1VerificationKey2019,
+		PublicKeyHex: hexutil.Encode(crypto.CompressPubkey(&sk.PublicKey)),
+	}, sig, message)
+
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSignature(t, PublicKey{
+		Type:         Ed25519VerificationKey2020,
+		PublicKeyHex: hex.EncodeToString(edPublicKey),
+	}, ed25519.Sign(edPrivateKey, message), message)
+
+	blsPrivateKey := big.NewInt(123456789)
+	_, _, _, g2 := bls12381.Generators()
+	var blsPublicKey bls12381.G2Affine
+	blsPublicKey.ScalarMultiplication(&g2, blsPrivateKey)
+	hash, err := bls12381.HashToG1(message, BlsSignatureDST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blsSig bls12381.G1Affine
+	blsSig.ScalarMultiplication(&hash, blsPrivateKey)
+	blsPublicKeyBytes := blsPublicKey.Bytes()
+	blsSigBytes := blsSig.Bytes()
+	checkSignature(t, PublicKey{
+		Type:         Bls12381G2Key2020,
+		PublicKeyHex: hex.EncodeToString(blsPublicKeyBytes[:]),
+	}, blsSigBytes[:], message)
+
+	x25519 := PublicKey{Type: X25519KeyAgreementKey2020, PublicKeyHex: hex.EncodeToString(edPublicKey)}
+	if _, err := x25519.VerifySignature(sig, message); err == nil {
+		t.Fatal("key agreement key should not verify signature")
+	}
+
+	if err := CheckPublicKey(Ed25519VerificationKey2020, edPublicKey[1:]); err == nil {
+		t.Fatal("should report an error for short ed25519 key")
+	}
+	if err := CheckPublicKey(Bls12381G2Key2020, blsPublicKeyBytes[:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPublicKey(Bls12381G2Key2020, blsSigBytes[:]); err == nil {
+		t.Fatal("should report an error for G1 point as bls public key")
+	}
+}
+
+// checkSignature checks pk verifies sig of message, and not another message
+func checkSignature(t *testing.T, pk PublicKey, sig, message []byte) {
+	t.Helper()
+
+	ok, err := pk.VerifySignature(sig, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("%s signature is not verified", pk.Type)
+	}
+
+	ok, err = pk.VerifySignature(sig, message, []byte("!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatalf("%s signature of another message is verified", pk.Type)
+	}
+}
+
+func TestPublicKeyFormat(t *testing.T) {
+	sk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublicKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, _, g2 := bls12381.Generators()
+	blsPublicKey := g2.Bytes()
+
+	keys := map[string][]byte{
+		EcdsaSecp256k1VerificationKey2019: crypto.CompressPubkey(&sk.PublicKey),
+		Ed25519VerificationKey2020:        edPublicKey,
+		X25519KeyAgreementKey2020:         edPublicKey,
+		Bls12381G2Key2020:                 blsPublicKey[:],
+	}
+
+	_, did, err := CreatSimpleDID("f9729aef404b8c13d06cf888376b04fd17581b9c308f9b4b16c020736ae89cd4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(format PublicKeyFormat) { DefaultPublicKeyFormat = format }(DefaultPublicKeyFormat)
+
+	for _, format := range []PublicKeyFormat{PublicKeyHexFormat, PublicKeyMultibaseFormat, PublicKeyJwkFormat} {
+		DefaultPublicKeyFormat = format
+		for vtype, key := range keys {
+			for _, controller := range []string{"", did.Identifier} {
+				sol := &proxy.IAccountDidPublicKey{MethodType: vtype, Controller: controller, PubKeyData: key}
+				method, err := FromSolityData(*did, 1, sol)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if method.Format() != format {
+					t.Fatalf("%s is represented in %s, not %s", vtype, method.Format(), format)
+				}
+
+				data, err := json.Marshal(method)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var result VerificationMethod
+				err = json.Unmarshal(data, &result)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				resultSol, err := ToSolidityData(&result)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(sol, resultSol) {
+					t.Fatalf("%s in %s is not converted losslessly: %s", vtype, format, data)
+				}
+			}
+		}
+	}
+
+	ed := NewPublicKey(Ed25519VerificationKey2020, edPublicKey, PublicKeyMultibaseFormat)
+	if !strings.HasPrefix(ed.PublicKeyMultibase, "z6Mk") {
+		t.Fatalf("unexpected multibase of ed25519 key: %s", ed.PublicKeyMultibase)
+	}
+	unknown := NewPublicKey("UnknownKey", edPublicKey, PublicKeyJwkFormat)
+	if unknown.Format() != PublicKeyHexFormat {
+		t.Fatalf("unknown key should be represented in hex, not %s", unknown.Format())
+	}
+	if _, err := unknown.WithFormat(PublicKeyMultibaseFormat); err == nil {
+		t.Fatal("unknown key should not be represented in multibase")
+	}
+	ed.Type = X25519KeyAgreementKey2020
+	if _, err := ed.Bytes(); err == nil {
+		t.Fatal("should report an error when multicodec does not match type")
+	}
+
+	bls := NewPublicKey(Bls12381G2Key2020, blsPublicKey[:], PublicKeyJwkFormat)
+	if bls.PublicKeyJwk == nil || bls.PublicKeyJwk.Kty != "OKP" || bls.PublicKeyJwk.Crv != "Bls12381G2" {
+		t.Fatalf("unexpected jwk of bls12381 g2 key: %+v", bls.PublicKeyJwk)
+	}
+	for _, vtype := range []string{Ed25519VerificationKey2020, X25519KeyAgreementKey2020} {
+		for _, key := range [][]byte{edPublicKey[:31], append(edPublicKey, 0)} {
+			for _, format := range []PublicKeyFormat{PublicKeyMultibaseFormat, PublicKeyJwkFormat} {
+				if _, err := NewPublicKey(vtype, key, format).Bytes(); err == nil {
+					t.Fatalf("%s of %d bytes in %s should not be decoded", vtype, len(key), format)
+				}
+			}
+		}
+	}
+}
+
+func TestDocumentJSONLD(t *testing.T) {
+	publicKeyHex, did, err := CreatSimpleDID("f9729aef404b8c13d06cf888376b04fd17581b9c308f9b4b16c020736ae89cd4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	methodID, err := ParseMemoDIDUrl(did.String() + "#masterKey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey := VerificationMethod{
+		ID:         *methodID,
+		Controller: *did,
+		PublicKey:  PublicKey{Type: EcdsaSecp256k1VerificationKey2019, PublicKeyHex: publicKeyHex},
+	}
+	document := MemoDIDDocument{
+		ID:                 *did,
+		Controller:         []MemoDID{*did},
+		AlsoKnownAs:        []string{"https://example.com/alice"},
+		VerificationMethod: []VerificationMethod{masterKey},
+		Authentication:     []MemoDIDUrl{masterKey.ID},
+		Service:            []Service{{ID: did.String() + "#gateway", Type: "LinkedDomains", ServiceEndpoint: "https://example.com"}},
+	}
+
+	data, err := json.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"@context", "id", "controller", "alsoKnownAs", "verificationMethod", "authentication", "service"} {
+		if _, ok := fields[key]; !ok {
+			t.Fatalf("%s is not serialized: %s", key, data)
+		}
+	}
+	var context []string
+	err = json.Unmarshal(fields["@context"], &context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(context, []string{DIDContext, SuiteContexts[EcdsaSecp256k1VerificationKey2019]}) {
+		t.Fatalf("unexpected @context: %v", context)
+	}
+
+	var result MemoDIDDocument
+	err = json.Unmarshal(data, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document.Context = DIDContext
+	if !reflect.DeepEqual(result, document) {
+		t.Fatalf("unexpected document: %v, want %v", result, document)
+	}
+
+	legacy := `{"@context":"https://www.w3.org/ns/did/v1","id":"` + did.String() + `","verifycationMethod":[{"id":"` + methodID.String() +
+		`","controller":"` + did.String() + `","type":"EcdsaSecp256k1VerificationKey2019","publicKeyHex":"` + publicKeyHex + `"}],"authentication":["` + methodID.String() + `"]}`
+	err = json.Unmarshal([]byte(legacy), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.VerificationMethod) != 1 || result.VerificationMethod[0].PublicKeyHex != publicKeyHex || result.Context != DIDContext {
+		t.Fatalf("unexpected legacy document: %v", result)
+	}
+
+	var mfileDocument MfileDIDDocument
+	err = json.Unmarshal([]byte(`{"context":"https://www.w3.org/ns/did/v1","id":"did:mfile:bafkreiaay2fxn7gplx6a2i47djwfrelwwrd7wcd6ih5ssspzjm3gb3dxna","type":"public","controller":"`+did.String()+`"}`), &mfileDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mfileDocument.Context != DIDContext || mfileDocument.Controller.String() != did.String() {
+		t.Fatalf("unexpected legacy mfile document: %v", mfileDocument)
+	}
+	data, err = json.Marshal(mfileDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"@context":["https://www.w3.org/ns/did/v1"]`) {
+		t.Fatalf("unexpected mfile document: %s", data)
+	}
+}
+
+func TestSigners(t *testing.T) {
+	_, did, err := CreatSimpleDID("f9729aef404b8c13d06cf888376b04fd17581b9c308f9b4b16c020736ae89cd4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, err := did.DIDUrl(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("hello")
+
+	sk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blsPrivateKey := big.NewInt(987654321)
+
+	for signer, publicKey := range map[Signer][]byte{
+		NewSecp256k1Signer(method, sk):         crypto.CompressPubkey(&sk.PublicKey),
+		NewEd25519Signer(method, edPrivateKey): edPublicKey,
+		NewBlsSigner(method, blsPrivateKey):    BlsPublicKey(blsPrivateKey),
+	} {
+		sig, err := signer.Sign(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig.VerificationMethod.String() != method.String() {
+			t.Fatalf("unexpected verification method of signature: %s", sig.VerificationMethod.String())
+		}
+		checkSignature(t, NewPublicKey(signer.Type(), publicKey, PublicKeyHexFormat), sig.Signature, message)
+	}
+}
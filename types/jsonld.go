@@ -0,0 +1,197 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// DIDContext is the @context of DID documents
+var DIDContext = "https://www.w3.org/ns/did/v1"
+
+// SuiteContexts are the @context of the security suites defining the verification method types
+var SuiteContexts = map[string]string{
+	EcdsaSecp256k1VerificationKey2019: "https://w3id.org/security/suites/secp256k1-2019/v1",
+	Ed25519VerificationKey2020:        "https://w3id.org/security/suites/ed25519-2020/v1",
+	X25519KeyAgreementKey2020:         "https://w3id.org/security/suites/x25519-2020/v1",
+	Bls12381G2Key2020:                 "https://w3id.org/security/suites/bls12381-2020/v1",
+}
+
+// JwkContext is the @context defining publicKeyJwk
+var JwkContext = "https://w3id.org/security/suites/jws-2020/v1"
+
+// contexts is a @context, which is read from a single value or an array
+// and written as an array
+type contexts []string
+
+func (c *contexts) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*oneOrMany[string])(c))
+}
+
+// oneOrMany is a JSON value which may be a single value or an array
+type oneOrMany[T any] []T
+
+func (o oneOrMany[T]) MarshalJSON() ([]byte, error) {
+	if len(o) == 1 {
+		return json.Marshal(o[0])
+	}
+	return json.Marshal([]T(o))
+}
+
+func (o *oneOrMany[T]) UnmarshalJSON(data []byte) error {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var value T
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*o = oneOrMany[T]{value}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]T)(o))
+}
+
+// documentContext returns the @context of a document using the terms of types
+func documentContext(context string, types []string) contexts {
+	if context == "" {
+		context = DIDContext
+	}
+	result := contexts{context}
+	seen := map[string]bool{context: true}
+	for _, vtype := range types {
+		suite, ok := SuiteContexts[vtype]
+		if vtype == string(PublicKeyJwkFormat) {
+			suite, ok = JwkContext, true
+		}
+		if ok && !seen[suite] {
+			seen[suite] = true
+			result = append(result, suite)
+		}
+	}
+	return result
+}
+
+// firstContext returns the base context of c, the others are derived from document
+func firstContext(c contexts) string {
+	if len(c) == 0 {
+		return ""
+	}
+	return c[0]
+}
+
+// memoDIDDocumentJSON is the JSON-LD representation of MemoDIDDocument
+type memoDIDDocumentJSON struct {
+	Context              contexts             `json:"@context"`
+	ID                   MemoDID              `json:"id"`
+	Controller           oneOrMany[MemoDID]   `json:"controller,omitempty"`
+	AlsoKnownAs          []string             `json:"alsoKnownAs,omitempty"`
+	VerificationMethod   []VerificationMethod `json:"verificationMethod,omitempty"`
+	Authentication       []MemoDIDUrl         `json:"authentication,omitempty"`
+	AssertionMethod      []MemoDIDUrl         `json:"assertionMethod,omitempty"`
+	CapabilityDelegation []MemoDIDUrl         `json:"capabilityDelegation,omitempty"`
+	Recovery             []MemoDIDUrl         `json:"recovery,omitempty"`
+	Service              []Service            `json:"service,omitempty"`
+
+	// the misspelled key of verification methods in legacy documents, only read
+	LegacyVerificationMethod []VerificationMethod `json:"verifycationMethod,omitempty"`
+}
+
+// MarshalJSON returns the JSON-LD representation of document, its @context
+// includes the security suites of its verification methods.
+func (d MemoDIDDocument) MarshalJSON() ([]byte, error) {
+	var types []string
+	for _, method := range d.VerificationMethod {
+		types = append(types, method.Type)
+		if method.PublicKeyJwk != nil {
+			types = append(types, string(PublicKeyJwkFormat))
+		}
+	}
+
+	return json.Marshal(memoDIDDocumentJSON{
+		Context:              documentContext(d.Context, types),
+		ID:                   d.ID,
+		Controller:           d.Controller,
+		AlsoKnownAs:          d.AlsoKnownAs,
+		VerificationMethod:   d.VerificationMethod,
+		Authentication:       d.Authentication,
+		AssertionMethod:      d.AssertionMethod,
+		CapabilityDelegation: d.CapabilityDelegation,
+		Recovery:             d.Recovery,
+		Service:              d.Service,
+	})
+}
+
+// UnmarshalJSON reads the JSON-LD representation of document, and the legacy one
+func (d *MemoDIDDocument) UnmarshalJSON(data []byte) error {
+	var document memoDIDDocumentJSON
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	if document.VerificationMethod == nil {
+		document.VerificationMethod = document.LegacyVerificationMethod
+	}
+
+	*d = MemoDIDDocument{
+		Context:              firstContext(document.Context),
+		ID:                   document.ID,
+		Controller:           document.Controller,
+		AlsoKnownAs:          document.AlsoKnownAs,
+		VerificationMethod:   document.VerificationMethod,
+		Authentication:       document.Authentication,
+		AssertionMethod:      document.AssertionMethod,
+		CapabilityDelegation: document.CapabilityDelegation,
+		Recovery:             document.Recovery,
+		Service:              document.Service,
+	}
+	return nil
+}
+
+// mfileDIDDocumentJSON is the JSON-LD representation of MfileDIDDocument
+type mfileDIDDocumentJSON struct {
+	Context    contexts  `json:"@context"`
+	ID         MfileDID  `json:"id"`
+	Type       string    `json:"type"`
+	Encode     string    `json:"encode"`
+	Price      int64     `json:"price"`
+	Controller MemoDID   `json:"controller"`
+	Keywords   []string  `json:"keywords"`
+	Read       []MemoDID `json:"read,omitempty"`
+
+	// the @context of legacy documents, only read
+	LegacyContext contexts `json:"context,omitempty"`
+}
+
+// MarshalJSON returns the JSON-LD representation of document
+func (d MfileDIDDocument) MarshalJSON() ([]byte, error) {
+	return json.Marshal(mfileDIDDocumentJSON{
+		Context:    documentContext(d.Context, nil),
+		ID:         d.ID,
+		Type:       d.Type,
+		Encode:     d.Encode,
+		Price:      d.Price,
+		Controller: d.Controller,
+		Keywords:   d.Keywords,
+		Read:       d.Read,
+	})
+}
+
+// UnmarshalJSON reads the JSON-LD representation of document, and the legacy one
+func (d *MfileDIDDocument) UnmarshalJSON(data []byte) error {
+	var document mfileDIDDocumentJSON
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	if document.Context == nil {
+		document.Context = document.LegacyContext
+	}
+
+	*d = MfileDIDDocument{
+		Context:    firstContext(document.Context),
+		ID:         document.ID,
+		Type:       document.Type,
+		Encode:     document.Encode,
+		Price:      document.Price,
+		Controller: document.Controller,
+		Keywords:   document.Keywords,
+		Read:       document.Read,
+	}
+	return nil
+}
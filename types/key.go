@@ -0,0 +1,275 @@
+package types
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multicodec"
+	"golang.org/x/xerrors"
+)
+
+// types of verification method
+const (
+	EcdsaSecp256k1VerificationKey2019 = "EcdsaSecp256k1VerificationKey2019"
+	Ed25519VerificationKey2020        = "Ed25519VerificationKey2020"
+	X25519KeyAgreementKey2020         = "X25519KeyAgreementKey2020"
+	Bls12381G2Key2020                 = "Bls12381G2Key2020"
+)
+
+// BlsSignatureDST is the domain separation tag used to hash messages to G1,
+// a bls signature is a compressed G1 point, and its public key is a compressed G2 point.
+var BlsSignatureDST = []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_NUL_")
+
+// PublicKeyFormat is the property representing a public key in documents
+type PublicKeyFormat string
+
+const (
+	PublicKeyHexFormat       PublicKeyFormat = "publicKeyHex"
+	PublicKeyMultibaseFormat PublicKeyFormat = "publicKeyMultibase"
+	PublicKeyJwkFormat       PublicKeyFormat = "publicKeyJwk"
+)
+
+// DefaultPublicKeyFormat is the format of the public keys in documents read from chain,
+// keys cannot be represented in it are represented in hex.
+var DefaultPublicKeyFormat = PublicKeyHexFormat
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+}
+
+// multicodecs prefixed to the multibase public keys
+var keyCodecs = map[string]multicodec.Code{
+	EcdsaSecp256k1VerificationKey2019: multicodec.Secp256k1Pub,
+	Ed25519VerificationKey2020:        multicodec.Ed25519Pub,
+	X25519KeyAgreementKey2020:         multicodec.X25519Pub,
+	Bls12381G2Key2020:                 multicodec.Bls12_381G2Pub,
+}
+
+// key types and curves of the jwk public keys
+var jwkCurves = map[string]struct{ kty, crv string }{
+	EcdsaSecp256k1VerificationKey2019: {"EC", "secp256k1"},
+	Ed25519VerificationKey2020:        {"OKP", "Ed25519"},
+	X25519KeyAgreementKey2020:         {"OKP", "X25519"},
+	Bls12381G2Key2020:                 {"OKP", "Bls12381G2"},
+}
+
+// NewPublicKey represents publicKey of vtype in format, it is represented in hex
+// if it cannot be represented in format
+func NewPublicKey(vtype string, publicKey []byte, format PublicKeyFormat) PublicKey {
+	pk := PublicKey{Type: vtype}
+	switch format {
+	case PublicKeyMultibaseFormat:
+		if multibase, err := encodeMultibase(vtype, publicKey); err == nil {
+			pk.PublicKeyMultibase = multibase
+			return pk
+		}
+	case PublicKeyJwkFormat:
+		if jwk, err := encodeJwk(vtype, publicKey); err == nil {
+			pk.PublicKeyJwk = jwk
+			return pk
+		}
+	}
+	pk.PublicKeyHex = hexutil.Encode(publicKey)
+	return pk
+}
+
+// Format returns the format public key is represented in
+func (v PublicKey) Format() PublicKeyFormat {
+	switch {
+	case v.PublicKeyMultibase != "":
+		return PublicKeyMultibaseFormat
+	case v.PublicKeyJwk != nil:
+		return PublicKeyJwkFormat
+	default:
+		return PublicKeyHexFormat
+	}
+}
+
+// WithFormat returns the public key represented in format
+func (v PublicKey) WithFormat(format PublicKeyFormat) (PublicKey, error) {
+	publicKey, err := v.Bytes()
+	if err != nil {
+		return PublicKey{}, err
+	}
+	pk := NewPublicKey(v.Type, publicKey, format)
+	if pk.Format() != format {
+		return PublicKey{}, xerrors.Errorf("%s cannot be represented in %s", v.Type, format)
+	}
+	return pk, nil
+}
+
+// Bytes decodes public key from hex, with or without 0x prefix, multibase or jwk
+func (v PublicKey) Bytes() ([]byte, error) {
+	switch {
+	case v.PublicKeyHex != "":
+		return decodeHex(v.PublicKeyHex)
+	case v.PublicKeyMultibase != "":
+		return decodeMultibase(v.Type, v.PublicKeyMultibase)
+	case v.PublicKeyJwk != nil:
+		return decodeJwk(v.Type, v.PublicKeyJwk)
+	default:
+		return nil, xerrors.Errorf("public key is empty")
+	}
+}
+
+// CheckPublicKey checks whether publicKey is a valid key of vtype,
+// keys of unknown types are not checked
+func CheckPublicKey(vtype string, publicKey []byte) error {
+	switch vtype {
+	case EcdsaSecp256k1VerificationKey2019:
+		if _, err := crypto.DecompressPubkey(publicKey); err != nil {
+			return xerrors.Errorf("invalid %s public key: %w", vtype, err)
+		}
+	case Ed25519VerificationKey2020, X25519KeyAgreementKey2020:
+		if len(publicKey) != 32 {
+			return xerrors.Errorf("invalid %s public key: length is %d, should be 32", vtype, len(publicKey))
+		}
+	case Bls12381G2Key2020:
+		if len(publicKey) != bls12381.SizeOfG2AffineCompressed {
+			return xerrors.Errorf("invalid %s public key: length is %d, should be %d", vtype, len(publicKey), bls12381.SizeOfG2AffineCompressed)
+		}
+		var pk bls12381.G2Affine
+		if _, err := pk.SetBytes(publicKey); err != nil {
+			return xerrors.Errorf("invalid %s public key: %w", vtype, err)
+		}
+	}
+	return nil
+}
+
+// verifySecp256k1 checks sig is a recoverable signature of keccak256(message)
+func verifySecp256k1(publicKey, sig, message []byte) (bool, error) {
+	pubKey, err := crypto.SigToPub(crypto.Keccak256(message), sig)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(publicKey, crypto.CompressPubkey(pubKey)), nil
+}
+
+func verifyEd25519(publicKey, sig, message []byte) (bool, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return false, xerrors.Errorf("invalid %s public key", Ed25519VerificationKey2020)
+	}
+	return ed25519.Verify(publicKey, message, sig), nil
+}
+
+// verifyBls checks e(sig, g2) == e(H(message), publicKey)
+func verifyBls(publicKey, sig, message []byte) (bool, error) {
+	var pk bls12381.G2Affine
+	if _, err := pk.SetBytes(publicKey); err != nil {
+		return false, xerrors.Errorf("invalid %s public key: %w", Bls12381G2Key2020, err)
+	}
+	var signature bls12381.G1Affine
+	if _, err := signature.SetBytes(sig); err != nil {
+		return false, nil
+	}
+	if pk.IsInfinity() || signature.IsInfinity() {
+		return false, nil
+	}
+
+	hash, err := bls12381.HashToG1(message, BlsSignatureDST)
+	if err != nil {
+		return false, err
+	}
+	_, _, _, g2 := bls12381.Generators()
+	var negSignature bls12381.G1Affine
+	negSignature.Neg(&signature)
+
+	return bls12381.PairingCheck([]bls12381.G1Affine{negSignature, hash}, []bls12381.G2Affine{g2, pk})
+}
+
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+}
+
+// encodeMultibase encodes publicKey prefixed with the multicodec of vtype in base58btc
+func encodeMultibase(vtype string, publicKey []byte) (string, error) {
+	codec, ok := keyCodecs[vtype]
+	if !ok {
+		return "", xerrors.Errorf("%s has no multicodec", vtype)
+	}
+	return multibase.Encode(multibase.Base58BTC, append(binary.AppendUvarint(nil, uint64(codec)), publicKey...))
+}
+
+// decodeMultibase decodes the multibase public key of vtype, the key decoded is checked by CheckPublicKey
+func decodeMultibase(vtype string, s string) ([]byte, error) {
+	_, data, err := multibase.Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	codec, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, xerrors.Errorf("invalid multicodec of public key")
+	}
+	if expected, ok := keyCodecs[vtype]; !ok || multicodec.Code(codec) != expected {
+		return nil, xerrors.Errorf("multicodec %s does not match %s", multicodec.Code(codec), vtype)
+	}
+	if err := CheckPublicKey(vtype, data[n:]); err != nil {
+		return nil, err
+	}
+	return data[n:], nil
+}
+
+func encodeJwk(vtype string, publicKey []byte) (*JWK, error) {
+	curve, ok := jwkCurves[vtype]
+	if !ok {
+		return nil, xerrors.Errorf("%s has no jwk", vtype)
+	}
+	jwk := &JWK{Kty: curve.kty, Crv: curve.crv, X: base64.RawURLEncoding.EncodeToString(publicKey)}
+	if vtype == EcdsaSecp256k1VerificationKey2019 {
+		pk, err := crypto.DecompressPubkey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		jwk.X = base64.RawURLEncoding.EncodeToString(pk.X.FillBytes(make([]byte, 32)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(pk.Y.FillBytes(make([]byte, 32)))
+	}
+	return jwk, nil
+}
+
+// decodeJwk decodes jwk of vtype, secp256k1 keys are returned compressed as they are saved on chain,
+// the others are checked by CheckPublicKey
+func decodeJwk(vtype string, jwk *JWK) ([]byte, error) {
+	curve, ok := jwkCurves[vtype]
+	if !ok {
+		return nil, xerrors.Errorf("%s has no jwk", vtype)
+	}
+	if jwk.Kty != curve.kty || jwk.Crv != curve.crv {
+		return nil, xerrors.Errorf("jwk of %s %s does not match %s", jwk.Kty, jwk.Crv, vtype)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	if vtype != EcdsaSecp256k1VerificationKey2019 {
+		if err := CheckPublicKey(vtype, x); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+	if len(x) != 32 || len(y) != 32 {
+		return nil, xerrors.Errorf("invalid secp256k1 jwk")
+	}
+	pk, err := crypto.UnmarshalPubkey(append(append([]byte{4}, x...), y...))
+	if err != nil {
+		return nil, err
+	}
+	return crypto.CompressPubkey(pk), nil
+}
@@ -23,7 +23,7 @@ type MemoDID struct {
 	// DID Method(memo)
 	Method string
 
-	// The memo-specific-id component of a DID
+	// The memo-specific-id component of a DID without chain id
 	// memo-specific-id = hex(hash(address, nonce))
 	Identifier string
 
@@ -44,29 +44,22 @@ func ParseMemoDID(didString string) (*MemoDID, error) {
 	if did.Method != "memo" {
 		return nil, xerrors.Errorf("unsupported method %s", did.Method)
 	}
-	if len(did.IDStrings) > 1 {
-		// TODO: check didString[2:len(didStrings)-1] ==? {chain id}
-		return nil, xerrors.Errorf("TODO: support chain id")
-	}
-	if isNot32ByteHex(did.IDStrings[len(did.IDStrings)-1]) {
-		return nil, xerrors.Errorf("%s is not 32 byte hex string", did.IDStrings[len(did.IDStrings)-1])
+	if err := checkIDStrings(did.IDStrings); err != nil {
+		return nil, err
 	}
 	return &MemoDID{
 		Method:      "memo",
-		Identifier:  did.ID,
+		Identifier:  did.IDStrings[len(did.IDStrings)-1],
 		Identifiers: did.IDStrings,
 	}, nil
 }
 
 func (d *MemoDID) String() string {
-	return "did:" + d.Method + ":" + d.Identifier
+	return "did:" + d.Method + ":" + d.specificID()
 }
 
 func (d MemoDID) MarshalJSON() ([]byte, error) {
-	if d.Identifier == "" {
-		d.Identifier = strings.Join(d.Identifiers, ":")
-	}
-	didString := "did:" + d.Method + ":" + d.Identifier
+	didString := "did:" + d.Method + ":" + d.specificID()
 	return json.Marshal(didString)
 }
 
@@ -86,6 +79,30 @@ func (d *MemoDID) UnmarshalJSON(data []byte) error {
 	return err
 }
 
+// ChainID returns the chain encoded in a DID such as did:memo:{chainID}:<hex>,
+// or "" if the DID is not chain-qualified.
+func (d MemoDID) ChainID() string {
+	return chainIDOf(d.Identifiers)
+}
+
+// WithChainID returns a copy of the DID qualified with chainID, an empty
+// chainID returns the unqualified form.
+func (d MemoDID) WithChainID(chainID string) MemoDID {
+	return MemoDID{
+		Method:      d.Method,
+		Identifier:  d.Identifier,
+		Identifiers: qualify(d.Identifier, chainID),
+	}
+}
+
+// specific id is {chainID}:<hex> for chain-qualified DID, otherwise <hex>
+func (d *MemoDID) specificID() string {
+	if d.Identifier == "" || len(d.Identifiers) > 1 {
+		return strings.Join(d.Identifiers, ":")
+	}
+	return d.Identifier
+}
+
 func (d *MemoDID) DIDUrl(methodIndex int64) (MemoDIDUrl, error) {
 	var id MemoDIDUrl
 	if methodIndex < 0 {
@@ -113,7 +130,7 @@ type MemoDIDUrl struct {
 	// DID Method(memo)
 	Method string
 
-	// The memo-specific-id component of a DID
+	// The memo-specific-id component of a DID without chain id
 	// memo-specific-id = hex(hash(address, nonce))
 	Identifier string
 
@@ -126,6 +143,8 @@ type MemoDIDUrl struct {
 	Fragment string
 }
 
+// ParseMemoDIDUrl parses the url of a verification method or relationship,
+// use ParseDIDURL for a DID URL with path, query or other fragments.
 func ParseMemoDIDUrl(didUrl string) (*MemoDIDUrl, error) {
 	did, err := did.Parse(didUrl)
 	if err != nil {
@@ -137,12 +156,8 @@ func ParseMemoDIDUrl(didUrl string) (*MemoDIDUrl, error) {
 	if did.Method != "memo" {
 		return nil, xerrors.Errorf("unsupported method %s", did.Method)
 	}
-	if len(did.IDStrings) > 1 {
-		// TODO: check didString[2:len(didStrings)-1] ==? {chain id}
-		return nil, xerrors.Errorf("TODO: support chain id")
-	}
-	if isNot32ByteHex(did.IDStrings[len(did.IDStrings)-1]) {
-		return nil, xerrors.Errorf("%s is not 32 byte hex string", did.IDStrings[len(did.IDStrings)-1])
+	if err := checkIDStrings(did.IDStrings); err != nil {
+		return nil, err
 	}
 	if did.Path != "" || did.Query != "" {
 		return nil, xerrors.Errorf("unsupported path and query in memo did")
@@ -152,14 +167,15 @@ func ParseMemoDIDUrl(didUrl string) (*MemoDIDUrl, error) {
 	}
 	return &MemoDIDUrl{
 		Method:      did.Method,
-		Identifier:  did.ID,
+		Identifier:  did.IDStrings[len(did.IDStrings)-1],
 		Identidiers: did.IDStrings,
 		Fragment:    did.Fragment,
 	}, nil
 }
 
 func (d *MemoDIDUrl) String() string {
-	return "did:" + d.Method + ":" + d.Identifier + "#" + d.Fragment
+	did := d.DID()
+	return did.String() + "#" + d.Fragment
 }
 
 func (d MemoDIDUrl) MarshalJSON() ([]byte, error) {
@@ -187,7 +203,7 @@ func (d *MemoDIDUrl) GetMethodIndex() int {
 	if d.Fragment == "masterKey" {
 		return 0
 	}
-	if d.Fragment[:4] == "key-" {
+	if strings.HasPrefix(d.Fragment, "key-") {
 		if i, err := strconv.Atoi(d.Fragment[4:]); err == nil {
 			return i
 		}
@@ -195,6 +211,22 @@ func (d *MemoDIDUrl) GetMethodIndex() int {
 	return -1
 }
 
+// ChainID returns the chain encoded in the DID url, or "" if it is not chain-qualified.
+func (d MemoDIDUrl) ChainID() string {
+	return chainIDOf(d.Identidiers)
+}
+
+// WithChainID returns a copy of the DID url qualified with chainID, an empty
+// chainID returns the unqualified form.
+func (d MemoDIDUrl) WithChainID(chainID string) MemoDIDUrl {
+	return MemoDIDUrl{
+		Method:      d.Method,
+		Identifier:  d.Identifier,
+		Identidiers: qualify(d.Identifier, chainID),
+		Fragment:    d.Fragment,
+	}
+}
+
 func (d *MemoDIDUrl) DID() MemoDID {
 	return MemoDID{
 		Method:      d.Method,
@@ -203,6 +235,34 @@ func (d *MemoDIDUrl) DID() MemoDID {
 	}
 }
 
+// checkIDStrings accepts <hex> and {chainID}:<hex>
+func checkIDStrings(idStrings []string) error {
+	if len(idStrings) > 2 {
+		return xerrors.Errorf("memo-specific-id must match the syntax: [{chainID}:]<hex>")
+	}
+	if len(idStrings) == 2 && isNotChainID(idStrings[0]) {
+		return xerrors.Errorf("%s is not a valid chain id", idStrings[0])
+	}
+	if isNot32ByteHex(idStrings[len(idStrings)-1]) {
+		return xerrors.Errorf("%s is not 32 byte hex string", idStrings[len(idStrings)-1])
+	}
+	return nil
+}
+
+func chainIDOf(idStrings []string) string {
+	if len(idStrings) > 1 {
+		return idStrings[0]
+	}
+	return ""
+}
+
+func qualify(identifier, chainID string) []string {
+	if chainID == "" {
+		return []string{identifier}
+	}
+	return []string{chainID, identifier}
+}
+
 func isSupport(fragment string) bool {
 	for _, frag := range supportFrangment {
 		if frag == fragment {
@@ -226,6 +286,21 @@ func isNot32ByteHex(s string) bool {
 	return false
 }
 
+// chain id is the chain name(dev, megrez...) or the numeric chain id
+func isNotChainID(s string) bool {
+	if s == "" || len(s) > 32 {
+		return true
+	}
+
+	for _, b := range s {
+		if !((b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || b == '-') {
+			return true
+		}
+	}
+
+	return false
+}
+
 func isNotPositiveNumber(s string) bool {
 	if s == "" || s == "0" {
 		return true
@@ -1,28 +1,36 @@
 package types
 
 import (
-	"encoding/hex"
+	"bytes"
 	"errors"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/memoio/did-solidity/go-contracts/proxy"
+	"golang.org/x/xerrors"
 )
 
+// MemoDIDDocument is serialized as JSON-LD, Context is the base @context
+// which the security suites in use are appended to.
 type MemoDIDDocument struct {
-	Context              string               `json:"@context"`
-	ID                   MemoDID              `json:"id"`
-	VerificationMethod   []VerificationMethod `json:"verifycationMethod"`
-	Authentication       []MemoDIDUrl         `json:"authentication,omitempty"`
-	AssertionMethod      []MemoDIDUrl         `json:"assertionMethod,omitempty"`
-	CapabilityDelegation []MemoDIDUrl         `json:"capabilityDelegation,omitempty"`
-	Recovery             []MemoDIDUrl         `json:"recovery,omitempty"`
+	Context              string
+	ID                   MemoDID
+	Controller           []MemoDID
+	AlsoKnownAs          []string
+	VerificationMethod   []VerificationMethod
+	Authentication       []MemoDIDUrl
+	AssertionMethod      []MemoDIDUrl
+	CapabilityDelegation []MemoDIDUrl
+	Recovery             []MemoDIDUrl
+	Service              []Service
 }
 
+// PublicKey is represented by one of publicKeyHex, publicKeyMultibase and publicKeyJwk
 type PublicKey struct {
-	Type         string `json:"type"`
-	PublicKeyHex string `json:"publicKeyHex"`
+	Type               string `json:"type"`
+	PublicKeyHex       string `json:"publicKeyHex,omitempty"`
+	PublicKeyMultibase string `json:"publicKeyMultibase,omitempty"`
+	PublicKeyJwk       *JWK   `json:"publicKeyJwk,omitempty"`
 }
 
 type VerificationMethod struct {
@@ -31,70 +39,82 @@ type VerificationMethod struct {
 	PublicKey
 }
 
+// zeroIdentifier is the controller of verification methods saved without controller
+const zeroIdentifier = "0000000000000000000000000000000000000000000000000000000000000000"
+
 func FromSolityData(did MemoDID, methodIndex int64, method *proxy.IAccountDidPublicKey) (*VerificationMethod, error) {
+	controllerString := "did:memo:" + method.Controller
 	if method.Controller == "" {
-		method.Controller = "did:memo:0000000000000000000000000000000000000000000000000000000000000000"
-	} else {
-		method.Controller = "did:memo:" + method.Controller
+		controllerString = "did:memo:" + zeroIdentifier
 	}
 
-	controller, err := ParseMemoDID(method.Controller)
+	controller, err := ParseMemoDID(controllerString)
 	if err != nil {
 		return nil, err
 	}
+	// controller is saved without chain id, it lives on the same chain as did
+	if controller.ChainID() == "" && did.ChainID() != "" {
+		*controller = controller.WithChainID(did.ChainID())
+	}
 
 	didUrl, err := did.DIDUrl(methodIndex)
 	if err != nil {
 		return nil, err
 	}
 
-	publicKeyHex := hexutil.Encode(method.PubKeyData)
 	return &VerificationMethod{
 		ID:         didUrl,
 		Controller: *controller,
-		PublicKey: PublicKey{
-			Type:         method.MethodType,
-			PublicKeyHex: publicKeyHex,
-		},
+		PublicKey:  NewPublicKey(method.MethodType, method.PubKeyData, DefaultPublicKeyFormat),
 	}, nil
 }
 
 func ToSolidityData(method *VerificationMethod) (*proxy.IAccountDidPublicKey, error) {
-	publicKeyData, err := hexutil.Decode(method.PublicKeyHex)
+	publicKeyData, err := method.Bytes()
 	if err != nil {
 		return nil, err
 	}
+	// controller is saved as identifier, and the zero identifier is saved as empty
+	controller := method.Controller.Identifier
+	if controller == zeroIdentifier {
+		controller = ""
+	}
 	return &proxy.IAccountDidPublicKey{
-		Controller: method.Controller.String(),
+		Controller: controller,
 		MethodType: method.Type,
 		PubKeyData: publicKeyData,
 	}, nil
 }
 
+// VerifySignature verifies sig of the concatenated message. Secp256k1 signatures
+// are recoverable signatures of its keccak256 hash, and bls signatures are on G1.
+// Key agreement keys cannot verify signatures.
 func (v PublicKey) VerifySignature(sig []byte, message ...[]byte) (bool, error) {
-	switch v.Type {
-	case "EcdsaSecp256k1VerificationKey2019":
-		hash := crypto.Keccak256(message...)
-
-		pubKey, err := crypto.SigToPub(hash, sig)
-		if err != nil {
-			return false, err
-		}
+	publicKey, err := v.Bytes()
+	if err != nil {
+		return false, err
+	}
 
-		if v.PublicKeyHex != hexutil.Encode(crypto.CompressPubkey(pubKey)) {
-			return false, nil
-		}
+	switch v.Type {
+	case EcdsaSecp256k1VerificationKey2019:
+		return verifySecp256k1(publicKey, sig, bytes.Join(message, nil))
+	case Ed25519VerificationKey2020:
+		return verifyEd25519(publicKey, sig, bytes.Join(message, nil))
+	case Bls12381G2Key2020:
+		return verifyBls(publicKey, sig, bytes.Join(message, nil))
+	case X25519KeyAgreementKey2020:
+		return false, xerrors.Errorf("%s is used for key agreement, cannot verify signature", v.Type)
 	default:
 		return false, errors.New("unsupport type")
 	}
-	return true, nil
 }
 
+// PublicKeyToAddress returns the address of a secp256k1 key, keys of
+// other types have no address
 func PublicKeyToAddress(pk PublicKey) (common.Address, error) {
-	var address common.Address
 	switch pk.Type {
-	case "EcdsaSecp256k1VerificationKey2019":
-		pubkey, err := hex.DecodeString(pk.PublicKeyHex)
+	case EcdsaSecp256k1VerificationKey2019:
+		pubkey, err := pk.Bytes()
 		if err != nil {
 			return common.Address{}, err
 		}
@@ -102,9 +122,10 @@ func PublicKeyToAddress(pk PublicKey) (common.Address, error) {
 		if err != nil {
 			return common.Address{}, err
 		}
-		address = crypto.PubkeyToAddress(*publicKey)
+		return crypto.PubkeyToAddress(*publicKey), nil
+	case Ed25519VerificationKey2020, X25519KeyAgreementKey2020, Bls12381G2Key2020:
+		return common.Address{}, xerrors.Errorf("%s has no address", pk.Type)
 	default:
 		return common.Address{}, errors.New("unsupport type")
 	}
-	return address, nil
 }
@@ -1,12 +1,13 @@
 package types
 
+// MfileDIDDocument is serialized as JSON-LD, Context is the base @context
 type MfileDIDDocument struct {
-	Context    string    `json:"context"`
-	ID         MfileDID  `json:"id"`
-	Type       string    `json:"type"`
-	Encode     string    `json:"encode"`
-	Price      int64     `json:"price"`
-	Controller MemoDID   `json:"controller"`
-	Keywords   []string  `json:"keywords"`
-	Read       []MemoDID `json:"read,omitempty"`
+	Context    string
+	ID         MfileDID
+	Type       string
+	Encode     string
+	Price      int64
+	Controller MemoDID
+	Keywords   []string
+	Read       []MemoDID
 }
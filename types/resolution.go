@@ -0,0 +1,151 @@
+package types
+
+import (
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// ResolutionContext is the @context of DID resolution result
+var ResolutionContext = "https://w3id.org/did-resolution/v1"
+
+// content type of the resolved DID document
+const (
+	ContentTypeDIDJSON   = "application/did+json"
+	ContentTypeDIDLDJSON = "application/did+ld+json"
+	// ContentTypeURIList is the content type of the service endpoint url dereferenced
+	ContentTypeURIList = "text/uri-list"
+)
+
+// error codes of DID resolution metadata
+const (
+	ErrorInvalidDid         = "invalidDid"
+	ErrorNotFound           = "notFound"
+	ErrorMethodNotSupported = "methodNotSupported"
+	ErrorDeactivated        = "deactivated"
+	ErrorInvalidDidUrl      = "invalidDidUrl"
+)
+
+// ResolutionMetadata is the didResolutionMetadata of DID resolution result
+type ResolutionMetadata struct {
+	ContentType string `json:"contentType,omitempty"`
+	// Error is one of the error codes, empty if DID is resolved
+	Error string `json:"error,omitempty"`
+}
+
+// DocumentMetadata is the didDocumentMetadata of DID resolution result
+type DocumentMetadata struct {
+	// Created is the time of the first update of DID
+	Created string `json:"created,omitempty"`
+	// Updated is the time of the last update of DID, empty if it is never updated after creation
+	Updated     string `json:"updated,omitempty"`
+	Deactivated bool   `json:"deactivated,omitempty"`
+	// VersionID is the number of the block that DID is updated at last
+	VersionID string `json:"versionId,omitempty"`
+	// NextUpdate and NextVersionID are the time and block of the update after
+	// the resolved version, empty if the latest version is resolved
+	NextUpdate    string `json:"nextUpdate,omitempty"`
+	NextVersionID string `json:"nextVersionId,omitempty"`
+}
+
+// MemoDIDResolutionResult is the result of resolving a memo DID
+type MemoDIDResolutionResult struct {
+	Context               string             `json:"@context"`
+	DIDDocument           *MemoDIDDocument   `json:"didDocument"`
+	DIDResolutionMetadata ResolutionMetadata `json:"didResolutionMetadata"`
+	DIDDocumentMetadata   DocumentMetadata   `json:"didDocumentMetadata"`
+}
+
+// MfileDIDResolutionResult is the result of resolving a mfile DID
+type MfileDIDResolutionResult struct {
+	Context               string             `json:"@context"`
+	DIDDocument           *MfileDIDDocument  `json:"didDocument"`
+	DIDResolutionMetadata ResolutionMetadata `json:"didResolutionMetadata"`
+	DIDDocumentMetadata   DocumentMetadata   `json:"didDocumentMetadata"`
+}
+
+// DereferencingMetadata is the dereferencingMetadata of DID URL dereferencing result
+type DereferencingMetadata struct {
+	ContentType string `json:"contentType,omitempty"`
+	// Error is one of the error codes, empty if DID URL is dereferenced
+	Error string `json:"error,omitempty"`
+}
+
+// MemoDIDDereferencingResult is the result of dereferencing a memo DID URL
+type MemoDIDDereferencingResult struct {
+	Context               string                `json:"@context"`
+	DereferencingMetadata DereferencingMetadata `json:"dereferencingMetadata"`
+	// ContentStream is the resource dereferenced: *MemoDIDDocument, *VerificationMethod,
+	// *Service, or the service endpoint url string selected by service parameter
+	ContentStream interface{} `json:"contentStream"`
+	// ContentMetadata is the metadata of the DID document
+	ContentMetadata DocumentMetadata `json:"contentMetadata"`
+}
+
+// ParseErrorCode returns the error code of didString which cannot be parsed as a DID of method
+func ParseErrorCode(didString, method string) string {
+	parts := strings.SplitN(didString, ":", 3)
+	if len(parts) == 3 && parts[0] == "did" && parts[1] != "" && parts[1] != method {
+		return ErrorMethodNotSupported
+	}
+	return ErrorInvalidDid
+}
+
+// FormatTime formats t as the datetime of DID document metadata
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// Version selects a historical version of DID document by the versionId or
+// versionTime parameter of DID, the latest version is selected if both are empty.
+type Version struct {
+	// ID is the number of block
+	ID *big.Int
+	// Time selects the last block mined at or before it
+	Time time.Time
+}
+
+// SplitVersion splits the versionId or versionTime parameter from didString,
+// version is nil if didString has no parameters.
+func SplitVersion(didString string) (string, *Version, error) {
+	i := strings.IndexByte(didString, '?')
+	if i < 0 {
+		return didString, nil, nil
+	}
+
+	query, err := url.ParseQuery(didString[i+1:])
+	if err != nil {
+		return "", nil, err
+	}
+
+	var version Version
+	for key, values := range query {
+		if len(values) != 1 {
+			return "", nil, xerrors.Errorf("parameter %s should be set once", key)
+		}
+		switch key {
+		case "versionId":
+			id, ok := new(big.Int).SetString(values[0], 10)
+			if !ok || id.Sign() < 0 {
+				return "", nil, xerrors.Errorf("versionId %s is not a block number", values[0])
+			}
+			version.ID = id
+		case "versionTime":
+			t, err := time.Parse(time.RFC3339, values[0])
+			if err != nil {
+				return "", nil, xerrors.Errorf("versionTime %s is not a datetime: %w", values[0], err)
+			}
+			version.Time = t
+		default:
+			return "", nil, xerrors.Errorf("unsupported parameter %s", key)
+		}
+	}
+	if version.ID != nil && !version.Time.IsZero() {
+		return "", nil, xerrors.Errorf("versionId and versionTime cannot be set at the same time")
+	}
+
+	return didString[:i], &version, nil
+}
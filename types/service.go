@@ -0,0 +1,123 @@
+package types
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/nuts-foundation/did-ockam"
+	"golang.org/x/xerrors"
+)
+
+// ServiceType is the type of the verification method slots saving services,
+// since the account did contract has no storage for services
+const ServiceType = "DIDService"
+
+// Service is a service endpoint of did, its ID is a did url with the
+// service id as fragment
+type Service struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	ServiceEndpoint string `json:"serviceEndpoint"`
+}
+
+// ServiceID returns the service id of #id or id, the did url of a service is accepted by
+// ServiceIDOf, which checks the did
+func ServiceID(id string) (string, error) {
+	id = strings.TrimPrefix(id, "#")
+	if strings.Contains(id, "#") {
+		return "", xerrors.Errorf("service id %s is a did url", id)
+	}
+	if id == "" {
+		return "", xerrors.Errorf("service id is empty")
+	}
+	if isSupport(id) || strings.HasPrefix(id, "key-") {
+		return "", xerrors.Errorf("service id %s is used by verification methods", id)
+	}
+	for _, b := range id {
+		if !((b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || b == '-' || b == '_' || b == '.') {
+			return "", xerrors.Errorf("service id %s contains invalid character %q", id, b)
+		}
+	}
+	return id, nil
+}
+
+// ServiceIDOf returns the service id of a service of did, which is did#id, #id or id. The did url
+// of a service of another did is rejected.
+func ServiceIDOf(did MemoDID, id string) (string, error) {
+	if i := strings.LastIndex(id, "#"); i > 0 {
+		owner, err := ParseMemoDID(id[:i])
+		if err != nil {
+			return "", err
+		}
+		if owner.Identifier != did.Identifier {
+			return "", xerrors.Errorf("service %s is not a service of %s", id, did.String())
+		}
+		id = id[i:]
+	}
+	return ServiceID(id)
+}
+
+// ParseServiceUrl parses a did url selecting a service by #id or ?service=id,
+// it returns the did and the service id.
+func ParseServiceUrl(didUrl string) (*MemoDID, string, error) {
+	parsed, err := did.Parse(didUrl)
+	if err != nil {
+		return nil, "", err
+	}
+	if parsed.Method != "memo" {
+		return nil, "", xerrors.Errorf("unsupported method %s", parsed.Method)
+	}
+	if err := checkIDStrings(parsed.IDStrings); err != nil {
+		return nil, "", err
+	}
+	if parsed.Path != "" {
+		return nil, "", xerrors.Errorf("unsupported path in memo did")
+	}
+
+	id := parsed.Fragment
+	if parsed.Query != "" {
+		query, err := url.ParseQuery(parsed.Query)
+		if err != nil {
+			return nil, "", err
+		}
+		id = query.Get("service")
+	}
+	id, err = ServiceID(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &MemoDID{
+		Method:      parsed.Method,
+		Identifier:  parsed.IDStrings[len(parsed.IDStrings)-1],
+		Identifiers: parsed.IDStrings,
+	}, id, nil
+}
+
+// EncodeService encodes service to be saved on chain, its id is saved without did
+func EncodeService(service Service) ([]byte, error) {
+	id, err := ServiceID(service.ID)
+	if err != nil {
+		return nil, err
+	}
+	if service.Type == "" || service.ServiceEndpoint == "" {
+		return nil, xerrors.Errorf("service type and endpoint should not be empty")
+	}
+	service.ID = id
+	return json.Marshal(service)
+}
+
+// DecodeService decodes a service of did saved on chain
+func DecodeService(did MemoDID, data []byte) (*Service, error) {
+	var service Service
+	if err := json.Unmarshal(data, &service); err != nil {
+		return nil, err
+	}
+	id, err := ServiceID(service.ID)
+	if err != nil {
+		return nil, err
+	}
+	service.ID = did.String() + "#" + id
+	return &service, nil
+}
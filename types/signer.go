@@ -0,0 +1,140 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"math/big"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/xerrors"
+)
+
+// proof purposes, which are the verification relationships a key is used in
+const (
+	PurposeAuthentication       = "authentication"
+	PurposeAssertionMethod      = "assertionMethod"
+	PurposeCapabilityDelegation = "capabilityDelegation"
+	PurposeRecovery             = "recovery"
+	// memo dids have no capabilityInvocation relationship, capabilities are invoked by authentication keys
+	PurposeCapabilityInvocation = "capabilityInvocation"
+)
+
+// Relationship returns the verification methods of document in the relationship of purpose
+func (d *MemoDIDDocument) Relationship(purpose string) ([]MemoDIDUrl, error) {
+	switch purpose {
+	case PurposeAuthentication, PurposeCapabilityInvocation:
+		return d.Authentication, nil
+	case PurposeAssertionMethod:
+		return d.AssertionMethod, nil
+	case PurposeCapabilityDelegation:
+		return d.CapabilityDelegation, nil
+	case PurposeRecovery:
+		return d.Recovery, nil
+	default:
+		return nil, xerrors.Errorf("unsupported proof purpose %s", purpose)
+	}
+}
+
+// Signature is a signature with the verification method signing it
+type Signature struct {
+	VerificationMethod MemoDIDUrl `json:"verificationMethod"`
+	Signature          []byte     `json:"signature"`
+}
+
+// Signer signs messages with the private key of a verification method, the
+// signatures are verified by PublicKey.VerifySignature.
+type Signer interface {
+	// VerificationMethod returns the id of the verification method signing
+	VerificationMethod() MemoDIDUrl
+	// Type returns the type of the verification method
+	Type() string
+	Sign(message []byte) (*Signature, error)
+}
+
+type secp256k1Signer struct {
+	method     MemoDIDUrl
+	privateKey *ecdsa.PrivateKey
+}
+
+// NewSecp256k1Signer signs the keccak256 hash of messages with recoverable signatures
+func NewSecp256k1Signer(method MemoDIDUrl, privateKey *ecdsa.PrivateKey) Signer {
+	return &secp256k1Signer{method: method, privateKey: privateKey}
+}
+
+func (s *secp256k1Signer) VerificationMethod() MemoDIDUrl {
+	return s.method
+}
+
+func (s *secp256k1Signer) Type() string {
+	return EcdsaSecp256k1VerificationKey2019
+}
+
+func (s *secp256k1Signer) Sign(message []byte) (*Signature, error) {
+	sig, err := crypto.Sign(crypto.Keccak256(message), s.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{VerificationMethod: s.method, Signature: sig}, nil
+}
+
+type ed25519Signer struct {
+	method     MemoDIDUrl
+	privateKey ed25519.PrivateKey
+}
+
+// NewEd25519Signer signs messages with ed25519
+func NewEd25519Signer(method MemoDIDUrl, privateKey ed25519.PrivateKey) Signer {
+	return &ed25519Signer{method: method, privateKey: privateKey}
+}
+
+func (s *ed25519Signer) VerificationMethod() MemoDIDUrl {
+	return s.method
+}
+
+func (s *ed25519Signer) Type() string {
+	return Ed25519VerificationKey2020
+}
+
+func (s *ed25519Signer) Sign(message []byte) (*Signature, error) {
+	return &Signature{VerificationMethod: s.method, Signature: ed25519.Sign(s.privateKey, message)}, nil
+}
+
+type blsSigner struct {
+	method     MemoDIDUrl
+	privateKey *big.Int
+}
+
+// NewBlsSigner signs messages hashed to G1 with BlsSignatureDST, the public
+// key of privateKey is privateKey * g2.
+func NewBlsSigner(method MemoDIDUrl, privateKey *big.Int) Signer {
+	return &blsSigner{method: method, privateKey: privateKey}
+}
+
+// BlsPublicKey returns the compressed G2 public key of privateKey
+func BlsPublicKey(privateKey *big.Int) []byte {
+	_, _, _, g2 := bls12381.Generators()
+	var pk bls12381.G2Affine
+	pk.ScalarMultiplication(&g2, privateKey)
+	publicKey := pk.Bytes()
+	return publicKey[:]
+}
+
+func (s *blsSigner) VerificationMethod() MemoDIDUrl {
+	return s.method
+}
+
+func (s *blsSigner) Type() string {
+	return Bls12381G2Key2020
+}
+
+func (s *blsSigner) Sign(message []byte) (*Signature, error) {
+	hash, err := bls12381.HashToG1(message, BlsSignatureDST)
+	if err != nil {
+		return nil, err
+	}
+	var sig bls12381.G1Affine
+	sig.ScalarMultiplication(&hash, s.privateKey)
+	sigBytes := sig.Bytes()
+	return &Signature{VerificationMethod: s.method, Signature: sigBytes[:]}, nil
+}
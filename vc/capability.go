@@ -0,0 +1,404 @@
+package vc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// CapabilityContextV1 is the base @context of authorization capabilities
+	CapabilityContextV1 = "https://w3id.org/zcap/v1"
+
+	rootCapabilityPrefix      = "urn:zcap:root:"
+	delegatedCapabilityPrefix = "urn:zcap:delegated:"
+)
+
+// Capability is an authorization capability (zcap) delegated to Controller, a verification method
+// url of the delegate, which allows it to perform AllowedAction on InvocationTarget until Expires.
+// No AllowedAction allows any action, and an action "ns:*" allows the actions of ns, such as
+// "mfile:*" allows "mfile:grantRead".
+//
+// The capability is secured by the Data Integrity proof of the controller of its parent capability.
+// The parent of the first delegation is the root capability of the target, which is controlled by
+// the memo did controlling the target. CapabilityChain is the capabilities delegated before it,
+// starting from the first delegation.
+type Capability struct {
+	Context          []string      `json:"@context"`
+	ID               string        `json:"id"`
+	ParentCapability string        `json:"parentCapability"`
+	InvocationTarget string        `json:"invocationTarget"`
+	Controller       string        `json:"controller"`
+	AllowedAction    []string      `json:"allowedAction,omitempty"`
+	Expires          time.Time     `json:"expires"`
+	CapabilityChain  []*Capability `json:"capabilityChain,omitempty"`
+	Proof            *Proof        `json:"proof,omitempty"`
+}
+
+// RootCapabilityID returns the id of the root capability of target
+func RootCapabilityID(target string) string {
+	return rootCapabilityPrefix + url.QueryEscape(target)
+}
+
+// NewCapability returns the first delegation of the root capability of target to delegate, which
+// should be signed by a key in the capabilityDelegation of the memo did controlling target.
+func NewCapability(target string, delegate types.MemoDIDUrl, expires time.Time, actions ...string) (*Capability, error) {
+	id, err := newCapabilityID()
+	if err != nil {
+		return nil, err
+	}
+	return &Capability{
+		Context:          []string{CapabilityContextV1},
+		ID:               id,
+		ParentCapability: RootCapabilityID(target),
+		InvocationTarget: target,
+		Controller:       delegate.String(),
+		AllowedAction:    actions,
+		Expires:          expires.UTC().Truncate(time.Second),
+	}, nil
+}
+
+// Delegate returns the delegation of c to delegate, which should be signed by the controller of c.
+// The actions and expiry of the delegation should not exceed the ones of c.
+func (c *Capability) Delegate(delegate types.MemoDIDUrl, expires time.Time, actions ...string) (*Capability, error) {
+	if c.Proof == nil {
+		return nil, xerrors.Errorf("capability %s is not delegated yet", c.ID)
+	}
+	expires = expires.UTC().Truncate(time.Second)
+	if expires.After(c.Expires) {
+		return nil, xerrors.Errorf("delegation expires at %s, after its parent at %s", expires, c.Expires)
+	}
+	if !attenuated(actions, c.AllowedAction) {
+		return nil, xerrors.Errorf("actions %v are not allowed by its parent %v", actions, c.AllowedAction)
+	}
+
+	id, err := newCapabilityID()
+	if err != nil {
+		return nil, err
+	}
+	return &Capability{
+		Context:          []string{CapabilityContextV1},
+		ID:               id,
+		ParentCapability: c.ID,
+		InvocationTarget: c.InvocationTarget,
+		Controller:       delegate.String(),
+		AllowedAction:    actions,
+		Expires:          expires,
+		CapabilityChain:  append(append([]*Capability{}, c.CapabilityChain...), c.link()),
+	}, nil
+}
+
+// Allows reports whether c allows action
+func (c *Capability) Allows(action string) bool {
+	return allows(c.AllowedAction, action)
+}
+
+// DelegateDataIntegrity returns capability secured by a Data Integrity proof signed by signer, which
+// should be the controller of its parent capability, or a key in the capabilityDelegation of the memo
+// did controlling the target for the first delegation.
+func DelegateDataIntegrity(capability *Capability, signer types.Signer) (*Capability, error) {
+	if n := len(capability.CapabilityChain); n > 0 {
+		method := signer.VerificationMethod()
+		if !sameMethodUrl(method.String(), capability.CapabilityChain[n-1].Controller, "") {
+			return nil, xerrors.Errorf("%s is not the controller of parent capability", method.String())
+		}
+	}
+
+	secured := capability.copy()
+	if !contains(secured.Context, DataIntegrityContextV1) {
+		secured.Context = append(append([]string{}, secured.Context...), DataIntegrityContextV1)
+	}
+	proof, err := createProof(secured, secured.Context, signer, types.PurposeCapabilityDelegation, "", "")
+	if err != nil {
+		return nil, err
+	}
+	secured.Proof = proof
+	return secured, nil
+}
+
+// Invocation invokes Capability to perform CapabilityAction on InvocationTarget, it is secured by
+// the Data Integrity proof of the controller of the capability.
+type Invocation struct {
+	Context          []string    `json:"@context"`
+	Capability       *Capability `json:"capability"`
+	CapabilityAction string      `json:"capabilityAction"`
+	InvocationTarget string      `json:"invocationTarget"`
+	Proof            *Proof      `json:"proof,omitempty"`
+}
+
+// Invoke returns the invocation of capability to perform action, which is signed by signer, the
+// controller of capability. challenge and domain are given by the verifier, so that the invocation
+// cannot be replayed.
+func Invoke(capability *Capability, action string, signer types.Signer, challenge, domain string) (*Invocation, error) {
+	if challenge == "" {
+		return nil, xerrors.Errorf("challenge is empty")
+	}
+	if capability.Proof == nil {
+		return nil, xerrors.Errorf("capability %s is not delegated yet", capability.ID)
+	}
+	if !capability.Allows(action) {
+		return nil, xerrors.Errorf("action %s is not allowed by capability %v", action, capability.AllowedAction)
+	}
+	method := signer.VerificationMethod()
+	if !sameMethodUrl(method.String(), capability.Controller, "") {
+		return nil, xerrors.Errorf("%s is not the controller of capability", method.String())
+	}
+
+	invocation := &Invocation{
+		Context:          []string{CapabilityContextV1, DataIntegrityContextV1},
+		Capability:       capability,
+		CapabilityAction: action,
+		InvocationTarget: capability.InvocationTarget,
+	}
+	proof, err := createProof(invocation, invocation.Context, signer, types.PurposeCapabilityInvocation, challenge, domain)
+	if err != nil {
+		return nil, err
+	}
+	invocation.Proof = proof
+	return invocation, nil
+}
+
+// TargetResolver returns the memo did controlling target, which delegates the root capability of target
+type TargetResolver func(ctx context.Context, target string) (*types.MemoDID, error)
+
+// MfileTargets returns a TargetResolver of mfile dids, which are controlled by the memo did of
+// their documents resolved by resolver, such as mfile.MfileDIDResolver
+func MfileTargets(resolver interface {
+	ResolveContext(ctx context.Context, didString string) (*types.MfileDIDDocument, error)
+}) TargetResolver {
+	return func(ctx context.Context, target string) (*types.MemoDID, error) {
+		document, err := resolver.ResolveContext(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		if document.Controller.Identifier == "" {
+			return nil, xerrors.Errorf("%s has no controller", target)
+		}
+		return &document.Controller, nil
+	}
+}
+
+// VerifyCapability verifies the chain of delegations of capability, each of them is valid now, signed
+// by the controller of its parent and attenuates the actions of its parent. The keys signing should be
+// in the capabilityDelegation of their dids now, and the first delegation is signed by the memo did
+// controlling the target.
+func (v *Verifier) VerifyCapability(capability *Capability) error {
+	return v.VerifyCapabilityContext(context.Background(), capability)
+}
+
+func (v *Verifier) VerifyCapabilityContext(ctx context.Context, capability *Capability) error {
+	controller, err := v.targetController(ctx, capability.InvocationTarget)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	chain := append(append([]*Capability{}, capability.CapabilityChain...), capability.link())
+	for i, c := range chain {
+		if err := c.validate(now); err != nil {
+			return xerrors.Errorf("capability %s: %w", c.ID, err)
+		}
+		if c.Proof == nil {
+			return xerrors.Errorf("capability %s has no proof", c.ID)
+		}
+		if i < len(chain)-1 && len(c.CapabilityChain) > 0 {
+			return xerrors.Errorf("capability %s in chain should not have a chain", c.ID)
+		}
+		if c.InvocationTarget != capability.InvocationTarget {
+			return xerrors.Errorf("capability %s is for %s, not %s", c.ID, c.InvocationTarget, capability.InvocationTarget)
+		}
+
+		if i == 0 {
+			if c.ParentCapability != RootCapabilityID(c.InvocationTarget) {
+				return xerrors.Errorf("parent of capability %s is not the root capability of %s", c.ID, c.InvocationTarget)
+			}
+			if err := checkIssuer(c.Proof.VerificationMethod, controller.String()); err != nil {
+				return xerrors.Errorf("capability %s is not delegated by %s: %w", c.ID, controller.String(), err)
+			}
+		} else {
+			parent := chain[i-1]
+			if c.ParentCapability != parent.ID {
+				return xerrors.Errorf("parent of capability %s is %s, not %s", c.ID, c.ParentCapability, parent.ID)
+			}
+			if c.Expires.After(parent.Expires) {
+				return xerrors.Errorf("capability %s expires after its parent", c.ID)
+			}
+			if !attenuated(c.AllowedAction, parent.AllowedAction) {
+				return xerrors.Errorf("actions %v of capability %s are not allowed by its parent %v", c.AllowedAction, c.ID, parent.AllowedAction)
+			}
+			if !sameMethodUrl(c.Proof.VerificationMethod, parent.Controller, v.verifier.Chain()) {
+				return xerrors.Errorf("capability %s is not delegated by %s", c.ID, parent.Controller)
+			}
+		}
+
+		// each capability is signed with the chain before it
+		signed := c.copy()
+		signed.CapabilityChain = chain[:i]
+		_, err := verifyProof(ctx, v.verifier, signed, c.Context, c.Proof, types.PurposeCapabilityDelegation)
+		if err != nil {
+			return xerrors.Errorf("capability %s: %w", c.ID, err)
+		}
+	}
+	return nil
+}
+
+// VerifyInvocation verifies invocation performs action on target with a capability verified by
+// VerifyCapability, and it is signed for challenge and domain by the controller of the capability,
+// which should be in the authentication of its did. The verification method signing is returned.
+func (v *Verifier) VerifyInvocation(invocation *Invocation, target, action, challenge, domain string) (*types.VerificationMethod, error) {
+	return v.VerifyInvocationContext(context.Background(), invocation, target, action, challenge, domain)
+}
+
+func (v *Verifier) VerifyInvocationContext(ctx context.Context, invocation *Invocation, target, action, challenge, domain string) (*types.VerificationMethod, error) {
+	if len(invocation.Context) == 0 || invocation.Context[0] != CapabilityContextV1 {
+		return nil, xerrors.Errorf("the first @context of invocation should be %s", CapabilityContextV1)
+	}
+	capability := invocation.Capability
+	if capability == nil {
+		return nil, xerrors.Errorf("invocation has no capability")
+	}
+	if invocation.InvocationTarget != target || capability.InvocationTarget != target {
+		return nil, xerrors.Errorf("invocation is for %s, not %s", invocation.InvocationTarget, target)
+	}
+	if invocation.CapabilityAction != action {
+		return nil, xerrors.Errorf("invocation performs %s, not %s", invocation.CapabilityAction, action)
+	}
+	if !capability.Allows(action) {
+		return nil, xerrors.Errorf("action %s is not allowed by capability %v", action, capability.AllowedAction)
+	}
+
+	proof := invocation.Proof
+	if proof == nil {
+		return nil, xerrors.Errorf("invocation has no proof")
+	}
+	if err := checkBinding(proof.Challenge, proof.Domain, challenge, domain); err != nil {
+		return nil, err
+	}
+	if !sameMethodUrl(proof.VerificationMethod, capability.Controller, v.verifier.Chain()) {
+		return nil, xerrors.Errorf("invocation is not signed by the controller %s of capability", capability.Controller)
+	}
+	if err := v.VerifyCapabilityContext(ctx, capability); err != nil {
+		return nil, err
+	}
+
+	unsigned := *invocation
+	unsigned.Proof = nil
+	return verifyProof(ctx, v.verifier, &unsigned, invocation.Context, proof, types.PurposeCapabilityInvocation)
+}
+
+// targetController returns the memo did controlling target, memo dids and their urls control themselves
+func (v *Verifier) targetController(ctx context.Context, target string) (*types.MemoDID, error) {
+	if strings.HasPrefix(target, "did:memo:") {
+		didUrl, err := types.ParseDIDURL(target)
+		if err != nil {
+			return nil, err
+		}
+		return &didUrl.DID, nil
+	}
+	if v.Targets == nil {
+		return nil, xerrors.Errorf("controller of target %s cannot be resolved", target)
+	}
+	return v.Targets(ctx, target)
+}
+
+// validate checks the data model of capability and whether it is valid at now
+func (c *Capability) validate(now time.Time) error {
+	if len(c.Context) == 0 || c.Context[0] != CapabilityContextV1 {
+		return xerrors.Errorf("the first @context of capability should be %s", CapabilityContextV1)
+	}
+	if c.ID == "" || c.ParentCapability == "" || c.InvocationTarget == "" {
+		return xerrors.Errorf("id, parentCapability and invocationTarget should not be empty")
+	}
+	controller, err := types.ParseDIDURL(c.Controller)
+	if err != nil {
+		return xerrors.Errorf("invalid controller %s: %w", c.Controller, err)
+	}
+	if controller.Fragment == "" {
+		return xerrors.Errorf("controller %s is not a verification method", c.Controller)
+	}
+	if !now.Before(c.Expires) {
+		return xerrors.Errorf("capability expired at %s", c.Expires)
+	}
+	return nil
+}
+
+// copy returns a shallow copy of capability without proof
+func (c *Capability) copy() *Capability {
+	capability := *c
+	capability.Proof = nil
+	return &capability
+}
+
+// link returns a shallow copy of capability without chain, which is saved in the chain of its delegations
+func (c *Capability) link() *Capability {
+	capability := *c
+	capability.CapabilityChain = nil
+	return &capability
+}
+
+func newCapabilityID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return delegatedCapabilityPrefix + hex.EncodeToString(id), nil
+}
+
+// allows reports whether actions allow action
+func allows(actions []string, action string) bool {
+	if len(actions) == 0 {
+		return true
+	}
+	for _, allowed := range actions {
+		if allowed == action || (strings.HasSuffix(allowed, ":*") && strings.HasPrefix(action, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// attenuated reports whether actions are allowed by the actions of parent
+func attenuated(actions, parent []string) bool {
+	if len(parent) == 0 {
+		return true
+	}
+	if len(actions) == 0 {
+		return false
+	}
+	for _, action := range actions {
+		if !allows(parent, action) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameMethodUrl reports whether a and b are the same verification method url on the same chain,
+// the urls without chain id are on chain, which is the default chain of the resolver verifying them.
+func sameMethodUrl(a, b, chain string) bool {
+	aUrl, err := types.ParseDIDURL(a)
+	if err != nil {
+		return false
+	}
+	bUrl, err := types.ParseDIDURL(b)
+	if err != nil {
+		return false
+	}
+	return aUrl.Fragment != "" && aUrl.DID.Identifier == bUrl.DID.Identifier && aUrl.Fragment == bUrl.Fragment &&
+		chainOf(aUrl.DID, chain) == chainOf(bUrl.DID, chain) &&
+		aUrl.Path == "" && bUrl.Path == "" && len(aUrl.Query) == 0 && len(bUrl.Query) == 0
+}
+
+// chainOf returns the chain id of did, or chain if it has no chain id
+func chainOf(did types.MemoDID, chain string) string {
+	if did.ChainID() != "" {
+		return did.ChainID()
+	}
+	return chain
+}
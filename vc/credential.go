@@ -0,0 +1,145 @@
+package vc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// CredentialsContextV1 is the base @context of verifiable credentials
+	CredentialsContextV1 = "https://www.w3.org/2018/credentials/v1"
+	// DataIntegrityContextV1 defines DataIntegrityProof
+	DataIntegrityContextV1 = "https://w3id.org/security/data-integrity/v1"
+
+	VerifiableCredentialType = "VerifiableCredential"
+)
+
+// Credential is a W3C verifiable credential issued by a memo did, it is secured
+// by Proof or by the JWT it is encoded in. The members unknown to Credential are
+// kept when it is parsed from JSON, and serialized with it.
+type Credential struct {
+	Context           []string               `json:"@context"`
+	ID                string                 `json:"id,omitempty"`
+	Type              []string               `json:"type"`
+	Issuer            string                 `json:"issuer"`
+	IssuanceDate      time.Time              `json:"issuanceDate"`
+	ExpirationDate    *time.Time             `json:"expirationDate,omitempty"`
+	CredentialSubject map[string]interface{} `json:"credentialSubject"`
+	Proof             *Proof                 `json:"proof,omitempty"`
+
+	raw received
+}
+
+func (c Credential) MarshalJSON() ([]byte, error) {
+	type credential Credential
+	return marshalReceived(credential(c), c.raw, func(data []byte) (interface{}, error) {
+		var parsed credential
+		err := json.Unmarshal(data, &parsed)
+		return parsed, err
+	})
+}
+
+func (c *Credential) UnmarshalJSON(data []byte) error {
+	type credential Credential
+	var parsed credential
+	raw, err := unmarshalReceived(data, &parsed)
+	if err != nil {
+		return err
+	}
+	*c = Credential(parsed)
+	c.raw = raw
+	return nil
+}
+
+// NewCredential returns a credential of issuer about subject issued now, credentialType
+// is appended to VerifiableCredential.
+func NewCredential(issuer types.MemoDID, subject map[string]interface{}, credentialType ...string) *Credential {
+	return &Credential{
+		Context:           []string{CredentialsContextV1},
+		Type:              append([]string{VerifiableCredentialType}, credentialType...),
+		Issuer:            issuer.String(),
+		IssuanceDate:      time.Now().UTC().Truncate(time.Second),
+		CredentialSubject: subject,
+	}
+}
+
+// SubjectID returns the id of the credential subject, empty if the subject has no id
+func (c *Credential) SubjectID() string {
+	id, _ := c.CredentialSubject["id"].(string)
+	return id
+}
+
+// Validate checks the data model of credential and whether it is valid at now
+func (c *Credential) Validate(now time.Time) error {
+	if len(c.Context) == 0 || c.Context[0] != CredentialsContextV1 {
+		return xerrors.Errorf("the first @context of credential should be %s", CredentialsContextV1)
+	}
+	if !contains(c.Type, VerifiableCredentialType) {
+		return xerrors.Errorf("type of credential should include %s", VerifiableCredentialType)
+	}
+	if _, err := types.ParseMemoDID(c.Issuer); err != nil {
+		return xerrors.Errorf("invalid issuer %s: %w", c.Issuer, err)
+	}
+	if c.IssuanceDate.IsZero() {
+		return xerrors.Errorf("credential has no issuanceDate")
+	}
+	if len(c.CredentialSubject) == 0 {
+		return xerrors.Errorf("credential has no credentialSubject")
+	}
+
+	if now.Before(c.IssuanceDate) {
+		return xerrors.Errorf("credential is not valid until %s", c.IssuanceDate.Format(time.RFC3339))
+	}
+	if c.ExpirationDate != nil && !now.Before(*c.ExpirationDate) {
+		return xerrors.Errorf("credential expired at %s", c.ExpirationDate.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// IssueDataIntegrity returns credential secured by a Data Integrity proof signed by
+// signer, which should be a key in the assertionMethod of the issuer.
+func IssueDataIntegrity(credential *Credential, signer types.Signer) (*Credential, error) {
+	method := signer.VerificationMethod()
+	if err := checkIssuer(method.String(), credential.Issuer); err != nil {
+		return nil, err
+	}
+
+	secured := credential.copy()
+	if !contains(secured.Context, DataIntegrityContextV1) {
+		secured.Context = append(append([]string{}, secured.Context...), DataIntegrityContextV1)
+	}
+	proof, err := createProof(secured, secured.Context, signer, types.PurposeAssertionMethod, "", "")
+	if err != nil {
+		return nil, err
+	}
+	secured.Proof = proof
+	return secured, nil
+}
+
+// signed returns what the proof of credential is signed over
+func (c *Credential) signed() (interface{}, error) {
+	return signedDocument(c.copy(), c.raw, func(data []byte) (interface{}, error) {
+		var parsed Credential
+		err := json.Unmarshal(data, &parsed)
+		return parsed.copy(), err
+	})
+}
+
+// copy returns a shallow copy of credential without proof
+func (c *Credential) copy() *Credential {
+	credential := *c
+	credential.Proof = nil
+	return &credential
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
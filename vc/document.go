@@ -0,0 +1,123 @@
+package vc
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// received is the JSON a credential or presentation is parsed from. Its proof is verified
+// over the members received, so that the members unknown to the Go types, such as
+// credentialStatus or evidence, are covered by the proof, and values such as dates are
+// verified as they are written by the issuer.
+type received json.RawMessage
+
+// unmarshalReceived parses data into v, a pointer to the struct type of a document, and
+// returns data to be kept as the received document
+func unmarshalReceived(data []byte, v interface{}) (received, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	return append(received{}, data...), nil
+}
+
+// marshalReceived serializes v, a struct of a document, and parse parses raw into the same
+// type. raw is returned as it is received if v is not changed since it is parsed, otherwise
+// v is serialized with the members of raw unknown to it.
+func marshalReceived(v interface{}, raw received, parse func([]byte) (interface{}, error)) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || raw == nil {
+		return data, err
+	}
+	parsed, err := parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	original, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(data, original) {
+		return raw, nil
+	}
+
+	var extra map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &extra); err != nil {
+		return nil, err
+	}
+	known := jsonNames(reflect.TypeOf(v))
+	for name := range extra {
+		if known[name] {
+			delete(extra, name)
+		}
+	}
+	if len(extra) == 0 {
+		return data, nil
+	}
+
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	for name, value := range extra {
+		members[name] = value
+	}
+	return json.Marshal(members)
+}
+
+// signedDocument returns what the proof of a document is signed over. unsigned is the document
+// without proof, and parse parses raw, the document received, into the same form. The members of
+// raw without proof are returned if unsigned is not changed since it is parsed, otherwise unsigned.
+func signedDocument(unsigned interface{}, raw received, parse func([]byte) (interface{}, error)) (interface{}, error) {
+	if raw == nil {
+		return unsigned, nil
+	}
+	parsed, err := parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	current, err := canonicalize(unsigned)
+	if err != nil {
+		return nil, err
+	}
+	original, err := canonicalize(parsed)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(current, original) {
+		return unsigned, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var members map[string]interface{}
+	if err := decoder.Decode(&members); err != nil {
+		return nil, err
+	}
+	delete(members, "proof")
+	return members, nil
+}
+
+// jsonNames returns the names of the json members of the fields of struct type t
+func jsonNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
@@ -0,0 +1,163 @@
+package vc
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/xerrors"
+)
+
+// canonicalize serializes v following the JSON Canonicalization Scheme (RFC 8785): object
+// members are sorted by the utf-16 code units of their keys without insignificant whitespace,
+// and strings and numbers are serialized as JSON.stringify of ECMAScript does.
+func canonicalize(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(value))
+	case json.Number:
+		number, err := canonicalNumber(value)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(number)
+	case string:
+		return writeCanonicalString(buf, value)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range value {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonicalString(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, value[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return xerrors.Errorf("unexpected json value %T", value)
+	}
+	return nil
+}
+
+// canonicalNumber serializes number as an IEEE 754 double like Number.prototype.toString,
+// such as 1.0 -> 1, 1e2 -> 100, 1e-7 -> 1e-7 and 1e21 -> 1e+21
+func canonicalNumber(number json.Number) (string, error) {
+	f, err := strconv.ParseFloat(string(number), 64)
+	if err != nil {
+		return "", xerrors.Errorf("number %s cannot be canonicalized: %w", number, err)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", xerrors.Errorf("number %s cannot be canonicalized", number)
+	}
+	if f == 0 {
+		// -0 is serialized as 0
+		return "0", nil
+	}
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+
+	// the exponent has no leading zeros
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	i := strings.IndexByte(s, 'e')
+	mantissa, sign, exponent := s[:i], s[i+1], strings.TrimLeft(s[i+2:], "0")
+	return mantissa + "e" + string(sign) + exponent, nil
+}
+
+// writeCanonicalString escapes only quotation mark, reverse solidus and control characters,
+// the others are written as utf-8
+func writeCanonicalString(buf *bytes.Buffer, s string) error {
+	if !utf8.ValidString(s) {
+		return xerrors.Errorf("string %q is not valid utf-8", s)
+	}
+
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c == '\b':
+			buf.WriteString(`\b`)
+		case c == '\t':
+			buf.WriteString(`\t`)
+		case c == '\n':
+			buf.WriteString(`\n`)
+		case c == '\f':
+			buf.WriteString(`\f`)
+		case c == '\r':
+			buf.WriteString(`\r`)
+		case c < 0x20:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hex[c>>4])
+			buf.WriteByte(hex[c&0xf])
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte('"')
+	return nil
+}
+
+// lessUTF16 compares a and b by their utf-16 code units
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
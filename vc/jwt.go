@@ -0,0 +1,85 @@
+package vc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/memoio/go-did/jws"
+	"golang.org/x/xerrors"
+)
+
+// credentialClaims are the claims of a credential encoded as JWT following VC-JWT 1.1,
+// the credential is kept in vc with its properties mapped to the registered claims.
+type credentialClaims struct {
+	Issuer     string      `json:"iss"`
+	Subject    string      `json:"sub,omitempty"`
+	ID         string      `json:"jti,omitempty"`
+	NotBefore  int64       `json:"nbf"`
+	ExpiresAt  int64       `json:"exp,omitempty"`
+	Credential *Credential `json:"vc"`
+}
+
+// IssueJWT returns credential encoded as JWT and signed by signer, a verification method of the issuer
+func IssueJWT(credential *Credential, signer jws.Signer) (string, error) {
+	kid := signer.KeyID()
+	if err := checkIssuer(kid.String(), credential.Issuer); err != nil {
+		return "", err
+	}
+
+	claims := credentialClaims{
+		Issuer:     credential.Issuer,
+		Subject:    credential.SubjectID(),
+		ID:         credential.ID,
+		NotBefore:  credential.IssuanceDate.Unix(),
+		Credential: credential.copy(),
+	}
+	if credential.ExpirationDate != nil {
+		claims.ExpiresAt = credential.ExpirationDate.Unix()
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return jws.Sign("JWT", payload, signer)
+}
+
+// presentationClaims are the claims of a presentation encoded as JWT following VC-JWT 1.1,
+// the challenge is kept in nonce and the domain in aud.
+type presentationClaims struct {
+	Issuer       string        `json:"iss"`
+	Audience     string        `json:"aud,omitempty"`
+	Nonce        string        `json:"nonce"`
+	ID           string        `json:"jti,omitempty"`
+	IssuedAt     int64         `json:"iat"`
+	Presentation *Presentation `json:"vp"`
+}
+
+// PresentJWT returns presentation encoded as JWT and signed by signer, a verification method
+// of the holder. challenge and domain are given by the verifier.
+func PresentJWT(presentation *Presentation, signer jws.Signer, challenge, domain string) (string, error) {
+	if challenge == "" {
+		return "", xerrors.Errorf("challenge is empty")
+	}
+	kid := signer.KeyID()
+	if err := checkIssuer(kid.String(), presentation.Holder); err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(presentationClaims{
+		Issuer:       presentation.Holder,
+		Audience:     domain,
+		Nonce:        challenge,
+		ID:           presentation.ID,
+		IssuedAt:     time.Now().Unix(),
+		Presentation: presentation.copy(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return jws.Sign("JWT", payload, signer)
+}
+
+func unixTime(seconds int64) time.Time {
+	return time.Unix(seconds, 0).UTC()
+}
@@ -0,0 +1,143 @@
+package vc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+const VerifiablePresentationType = "VerifiablePresentation"
+
+// Presentation is a W3C verifiable presentation of credentials by their holder, it is
+// secured by Proof or by the JWT it is encoded in. The credentials are secured by
+// Data Integrity proofs. As Credential, the members unknown to Presentation are kept.
+type Presentation struct {
+	Context              []string      `json:"@context"`
+	ID                   string        `json:"id,omitempty"`
+	Type                 []string      `json:"type"`
+	Holder               string        `json:"holder"`
+	VerifiableCredential []*Credential `json:"verifiableCredential,omitempty"`
+	Proof                *Proof        `json:"proof,omitempty"`
+
+	raw received
+}
+
+func (p Presentation) MarshalJSON() ([]byte, error) {
+	type presentation Presentation
+	return marshalReceived(presentation(p), p.raw, func(data []byte) (interface{}, error) {
+		var parsed presentation
+		err := json.Unmarshal(data, &parsed)
+		return parsed, err
+	})
+}
+
+func (p *Presentation) UnmarshalJSON(data []byte) error {
+	type presentation Presentation
+	var parsed presentation
+	raw, err := unmarshalReceived(data, &parsed)
+	if err != nil {
+		return err
+	}
+	*p = Presentation(parsed)
+	p.raw = raw
+	return nil
+}
+
+// NewPresentation returns a presentation of credentials by holder
+func NewPresentation(holder types.MemoDID, credentials ...*Credential) *Presentation {
+	return &Presentation{
+		Context:              []string{CredentialsContextV1},
+		Type:                 []string{VerifiablePresentationType},
+		Holder:               holder.String(),
+		VerifiableCredential: credentials,
+	}
+}
+
+// NewChallenge returns a random challenge, a verifier should send a new one for
+// each presentation it requests and accept it only once.
+func NewChallenge() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(nonce), nil
+}
+
+// Validate checks the data model of presentation, and the credentials are about the holder
+func (p *Presentation) Validate() error {
+	if len(p.Context) == 0 || p.Context[0] != CredentialsContextV1 {
+		return xerrors.Errorf("the first @context of presentation should be %s", CredentialsContextV1)
+	}
+	if !contains(p.Type, VerifiablePresentationType) {
+		return xerrors.Errorf("type of presentation should include %s", VerifiablePresentationType)
+	}
+	if _, err := types.ParseMemoDID(p.Holder); err != nil {
+		return xerrors.Errorf("invalid holder %s: %w", p.Holder, err)
+	}
+	for _, credential := range p.VerifiableCredential {
+		if credential == nil {
+			return xerrors.Errorf("presentation has an empty credential")
+		}
+		if credential.SubjectID() != p.Holder {
+			return xerrors.Errorf("credential subject %s is not the holder %s", credential.SubjectID(), p.Holder)
+		}
+	}
+	return nil
+}
+
+// PresentDataIntegrity returns presentation secured by a Data Integrity proof signed by signer,
+// which should be a key in the authentication of the holder. challenge and domain are given by
+// the verifier, so that the presentation cannot be replayed to others.
+func PresentDataIntegrity(presentation *Presentation, signer types.Signer, challenge, domain string) (*Presentation, error) {
+	if challenge == "" {
+		return nil, xerrors.Errorf("challenge is empty")
+	}
+	method := signer.VerificationMethod()
+	if err := checkIssuer(method.String(), presentation.Holder); err != nil {
+		return nil, err
+	}
+
+	secured := presentation.copy()
+	if !contains(secured.Context, DataIntegrityContextV1) {
+		secured.Context = append(append([]string{}, secured.Context...), DataIntegrityContextV1)
+	}
+	proof, err := createProof(secured, secured.Context, signer, types.PurposeAuthentication, challenge, domain)
+	if err != nil {
+		return nil, err
+	}
+	secured.Proof = proof
+	return secured, nil
+}
+
+// signed returns what the proof of presentation is signed over
+func (p *Presentation) signed() (interface{}, error) {
+	return signedDocument(p.copy(), p.raw, func(data []byte) (interface{}, error) {
+		var parsed Presentation
+		err := json.Unmarshal(data, &parsed)
+		return parsed.copy(), err
+	})
+}
+
+// copy returns a shallow copy of presentation without proof
+func (p *Presentation) copy() *Presentation {
+	presentation := *p
+	presentation.Proof = nil
+	return &presentation
+}
+
+// checkBinding checks the challenge and domain of a presentation are the ones expected
+func checkBinding(challenge, domain, expectedChallenge, expectedDomain string) error {
+	if expectedChallenge == "" {
+		return xerrors.Errorf("expected challenge is empty")
+	}
+	if challenge != expectedChallenge {
+		return xerrors.Errorf("challenge %s does not match %s", challenge, expectedChallenge)
+	}
+	if domain != expectedDomain {
+		return xerrors.Errorf("domain %s does not match %s", domain, expectedDomain)
+	}
+	return nil
+}
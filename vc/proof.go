@@ -0,0 +1,146 @@
+package vc
+
+import (
+	"context"
+	"crypto/sha256"
+	"time"
+
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/types"
+	"github.com/multiformats/go-multibase"
+	"golang.org/x/xerrors"
+)
+
+const DataIntegrityProofType = "DataIntegrityProof"
+
+// Cryptosuites are the cryptosuites of Data Integrity proofs signed by the keys of each
+// type. Only eddsa-jcs-2022 is standardized and verified by other implementations.
+// secp256k1-jcs-2023 and bls12381-jcs-2023 are private suites of go-did, which sign the
+// hash data of eddsa-jcs-2022 with the signatures of types.Signer; credentials for other
+// verifiers should be signed by Ed25519 keys, or issued as JWT.
+var Cryptosuites = map[string]string{
+	types.Ed25519VerificationKey2020:        "eddsa-jcs-2022",
+	types.EcdsaSecp256k1VerificationKey2019: "secp256k1-jcs-2023",
+	types.Bls12381G2Key2020:                 "bls12381-jcs-2023",
+}
+
+// Proof is a Data Integrity proof, ProofValue is the signature encoded in base58btc multibase.
+// Challenge and Domain bind the proof of a presentation to a verifier.
+type Proof struct {
+	Type               string    `json:"type"`
+	Cryptosuite        string    `json:"cryptosuite"`
+	Created            time.Time `json:"created"`
+	VerificationMethod string    `json:"verificationMethod"`
+	ProofPurpose       string    `json:"proofPurpose"`
+	Challenge          string    `json:"challenge,omitempty"`
+	Domain             string    `json:"domain,omitempty"`
+	ProofValue         string    `json:"proofValue,omitempty"`
+}
+
+// createProof signs document with signer for purpose, context is the @context of document.
+// challenge and domain are signed with the proof if they are not empty.
+func createProof(document interface{}, context []string, signer types.Signer, purpose, challenge, domain string) (*Proof, error) {
+	suite, ok := Cryptosuites[signer.Type()]
+	if !ok {
+		return nil, xerrors.Errorf("%s has no cryptosuite", signer.Type())
+	}
+	method := signer.VerificationMethod()
+	proof := &Proof{
+		Type:               DataIntegrityProofType,
+		Cryptosuite:        suite,
+		Created:            time.Now().UTC().Truncate(time.Second),
+		VerificationMethod: method.String(),
+		ProofPurpose:       purpose,
+		Challenge:          challenge,
+		Domain:             domain,
+	}
+
+	data, err := hashData(document, context, *proof)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.Sign(data)
+	if err != nil {
+		return nil, err
+	}
+	proof.ProofValue, err = multibase.Encode(multibase.Base58BTC, sig.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// verifyProof checks proof of document is signed by a key currently in the relationship
+// of purpose, the verification method signing is returned.
+func verifyProof(ctx context.Context, verifier *memo.Verifier, document interface{}, context []string, proof *Proof, purpose string) (*types.VerificationMethod, error) {
+	if proof == nil {
+		return nil, xerrors.Errorf("proof is missing")
+	}
+	if proof.Type != DataIntegrityProofType {
+		return nil, xerrors.Errorf("unsupported proof type %s", proof.Type)
+	}
+	if proof.ProofPurpose != purpose {
+		return nil, xerrors.Errorf("proof purpose is %s, should be %s", proof.ProofPurpose, purpose)
+	}
+	methodUrl, err := types.ParseDIDURL(proof.VerificationMethod)
+	if err != nil {
+		return nil, err
+	}
+	if methodUrl.Fragment == "" {
+		return nil, xerrors.Errorf("%s is not a verification method", proof.VerificationMethod)
+	}
+
+	_, sig, err := multibase.Decode(proof.ProofValue)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid proofValue: %w", err)
+	}
+	data, err := hashData(document, context, *proof)
+	if err != nil {
+		return nil, err
+	}
+	method, err := verifier.VerifyContext(ctx, proof.VerificationMethod, sig, data, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if Cryptosuites[method.Type] != proof.Cryptosuite {
+		return nil, xerrors.Errorf("cryptosuite %s does not match %s", proof.Cryptosuite, method.Type)
+	}
+	return method, nil
+}
+
+// hashData returns sha256(proof config) || sha256(document) as eddsa-jcs-2022, both are canonicalized
+// and the proof config is proof without proofValue under the @context of document.
+func hashData(document interface{}, context []string, proof Proof) ([]byte, error) {
+	proof.ProofValue = ""
+	config, err := canonicalize(struct {
+		Context []string `json:"@context"`
+		Proof
+	}{context, proof})
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := canonicalize(document)
+	if err != nil {
+		return nil, err
+	}
+
+	configHash := sha256.Sum256(config)
+	documentHash := sha256.Sum256(canonical)
+	return append(configHash[:], documentHash[:]...), nil
+}
+
+// checkIssuer checks the verification method methodUrl is of issuer
+func checkIssuer(methodUrl, issuer string) error {
+	didUrl, err := types.ParseDIDURL(methodUrl)
+	if err != nil {
+		return err
+	}
+	did, err := types.ParseMemoDID(issuer)
+	if err != nil {
+		return err
+	}
+	if didUrl.DID.String() != did.String() {
+		return xerrors.Errorf("%s is not a verification method of %s", methodUrl, issuer)
+	}
+	return nil
+}
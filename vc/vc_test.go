@@ -0,0 +1,593 @@
+package vc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/memoio/go-did/jws"
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/memotest"
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+func newTestCredential(i *memotest.Identity) *Credential {
+	credential := NewCredential(i.DID, map[string]interface{}{
+		"id":    "did:memo:" + strings.Repeat("cd", 32),
+		"read":  "did:mfile:bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku",
+		"price": 100,
+	}, "ReadPermissionCredential")
+	credential.IssuanceDate = credential.IssuanceDate.Add(-time.Minute)
+	return credential
+}
+
+func TestDataIntegrity(t *testing.T) {
+	documents := memotest.Documents{}
+	i := documents.NewIdentity(t, strings.Repeat("ab", 32))
+	other := documents.NewIdentity(t, strings.Repeat("ef", 32))
+	verifier := NewVerifier(documents)
+	credential := newTestCredential(i)
+
+	for index, signer := range []types.Signer{
+		types.NewSecp256k1Signer(i.Methods[0], i.Sk),
+		types.NewEd25519Signer(i.Methods[1], i.EdSk),
+		types.NewBlsSigner(i.Methods[2], i.BlsSk),
+	} {
+		secured, err := IssueDataIntegrity(credential, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secured.Proof.Cryptosuite != Cryptosuites[signer.Type()] || secured.Proof.ProofPurpose != types.PurposeAssertionMethod {
+			t.Fatalf("unexpected proof %+v", secured.Proof)
+		}
+
+		// the proof is verified after it is serialized
+		data, err := json.Marshal(secured)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var received Credential
+		err = json.Unmarshal(data, &received)
+		if err != nil {
+			t.Fatal(err)
+		}
+		method, err := verifier.VerifyCredential(&received)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if method.ID.GetMethodIndex() != index {
+			t.Fatalf("verified by %s, should be key %d", method.ID.String(), index)
+		}
+
+		received.CredentialSubject["price"] = 0
+		_, err = verifier.VerifyCredential(&received)
+		if !errors.Is(err, memo.ErrNotVerified) {
+			t.Fatalf("tampered credential should not be verified: %v", err)
+		}
+	}
+
+	// the key is not an assertion method
+	secured, err := IssueDataIntegrity(credential, types.NewEd25519Signer(i.Methods[3], i.AuthEdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyCredential(secured)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("credential signed by authentication key should not be verified: %v", err)
+	}
+
+	// the key is not of the issuer
+	_, err = IssueDataIntegrity(credential, types.NewEd25519Signer(other.Methods[1], other.EdSk))
+	if err == nil {
+		t.Fatal("credential should not be issued by the key of others")
+	}
+	secured, err = IssueDataIntegrity(credential, types.NewEd25519Signer(i.Methods[1], i.EdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secured.Issuer = other.DID.String()
+	_, err = verifier.VerifyCredential(secured)
+	if err == nil {
+		t.Fatal("credential of other issuer should not be verified")
+	}
+
+	expired := newTestCredential(i)
+	expirationDate := time.Now().Add(-time.Second)
+	expired.ExpirationDate = &expirationDate
+	secured, err = IssueDataIntegrity(expired, types.NewEd25519Signer(i.Methods[1], i.EdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyCredential(secured)
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("expired credential should not be verified: %v", err)
+	}
+}
+
+func TestReceivedCredential(t *testing.T) {
+	documents := memotest.Documents{}
+	i := documents.NewIdentity(t, strings.Repeat("ab", 32))
+	verifier := NewVerifier(documents)
+
+	// a credential issued by another implementation, with members unknown to Credential
+	// and dates not in the form of time.RFC3339
+	document := map[string]interface{}{
+		"@context":          []string{CredentialsContextV1, DataIntegrityContextV1},
+		"type":              []string{VerifiableCredentialType},
+		"issuer":            i.DID.String(),
+		"issuanceDate":      "2023-06-01T08:00:00.000Z",
+		"credentialSubject": map[string]interface{}{"id": "did:memo:" + strings.Repeat("cd", 32)},
+		"credentialStatus":  map[string]interface{}{"id": "https://example.com/status/1#3", "type": "StatusList2021Entry"},
+	}
+	proof, err := createProof(document, []string{CredentialsContextV1, DataIntegrityContextV1}, types.NewEd25519Signer(i.Methods[1], i.EdSk), types.PurposeAssertionMethod, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	document["proof"] = proof
+	data, err := json.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var credential Credential
+	err = json.Unmarshal(data, &credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyCredential(&credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the unknown members are kept when the credential is serialized again
+	data, err = json.Marshal(&credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var serialized Credential
+	err = json.Unmarshal(data, &serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "StatusList2021Entry") {
+		t.Fatalf("credentialStatus is dropped: %s", data)
+	}
+	_, err = verifier.VerifyCredential(&serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the unknown members are covered by the proof
+	delete(document, "credentialStatus")
+	data, err = json.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stripped Credential
+	err = json.Unmarshal(data, &stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyCredential(&stripped)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("credential without credentialStatus should not be verified: %v", err)
+	}
+
+	// the credential is changed after it is received
+	credential.CredentialSubject["id"] = i.DID.String()
+	_, err = verifier.VerifyCredential(&credential)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("changed credential should not be verified: %v", err)
+	}
+}
+
+func TestJWT(t *testing.T) {
+	documents := memotest.Documents{}
+	i := documents.NewIdentity(t, strings.Repeat("ab", 32))
+	verifier := NewVerifier(documents)
+	credential := newTestCredential(i)
+
+	for _, signer := range []jws.Signer{
+		jws.NewES256KSigner(i.Methods[0], i.Sk),
+		jws.NewEdDSASigner(i.Methods[1], i.EdSk),
+	} {
+		token, err := IssueJWT(credential, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verified, err := verifier.VerifyCredentialJWT(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if verified.Issuer != credential.Issuer || !verified.IssuanceDate.Equal(credential.IssuanceDate) || verified.CredentialSubject["read"] != credential.CredentialSubject["read"] {
+			t.Fatalf("unexpected credential %+v", verified)
+		}
+
+		parts := strings.Split(token, ".")
+		tampered := parts[0] + "." + parts[0] + "." + parts[2]
+		_, err = verifier.VerifyCredentialJWT(tampered)
+		if err == nil {
+			t.Fatal("tampered JWT should not be verified")
+		}
+	}
+
+	token, err := IssueJWT(credential, jws.NewEdDSASigner(i.Methods[3], i.AuthEdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyCredentialJWT(token)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("JWT signed by authentication key should not be verified: %v", err)
+	}
+
+	expired := newTestCredential(i)
+	expirationDate := time.Now().Add(-time.Second)
+	expired.ExpirationDate = &expirationDate
+	token, err = IssueJWT(expired, jws.NewEdDSASigner(i.Methods[1], i.EdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyCredentialJWT(token)
+	if err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("expired JWT should not be verified: %v", err)
+	}
+}
+
+func TestPresentation(t *testing.T) {
+	documents := memotest.Documents{}
+	i := documents.NewIdentity(t, strings.Repeat("ab", 32))
+	holder := documents.NewIdentity(t, strings.Repeat("cd", 32))
+	verifier := NewVerifier(documents)
+
+	credential, err := IssueDataIntegrity(newTestCredential(i), types.NewEd25519Signer(i.Methods[1], i.EdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	presentation := NewPresentation(holder.DID, credential)
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := "gateway.metamemo.one"
+
+	secured, err := PresentDataIntegrity(presentation, types.NewEd25519Signer(holder.Methods[3], holder.AuthEdSk), challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(secured)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received Presentation
+	err = json.Unmarshal(data, &received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, err := verifier.VerifyPresentation(&received, challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method.ID.GetMethodIndex() != 3 {
+		t.Fatalf("verified by %s, should be key 3", method.ID.String())
+	}
+
+	// replayed with another challenge or to another domain
+	_, err = verifier.VerifyPresentation(&received, challenge+"00", domain)
+	if err == nil {
+		t.Fatal("presentation should not be verified with another challenge")
+	}
+	_, err = verifier.VerifyPresentation(&received, challenge, "other.metamemo.one")
+	if err == nil {
+		t.Fatal("presentation should not be verified in another domain")
+	}
+	received.Proof.Challenge = challenge + "00"
+	_, err = verifier.VerifyPresentation(&received, challenge+"00", domain)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("presentation with tampered challenge should not be verified: %v", err)
+	}
+
+	// the key is not an authentication method
+	secured, err = PresentDataIntegrity(presentation, types.NewEd25519Signer(holder.Methods[1], holder.EdSk), challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyPresentation(secured, challenge, domain)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("presentation signed by assertion key should not be verified: %v", err)
+	}
+
+	// the credential is tampered
+	tampered := *credential
+	tampered.CredentialSubject = map[string]interface{}{"id": holder.DID.String(), "price": 0}
+	secured, err = PresentDataIntegrity(NewPresentation(holder.DID, &tampered), types.NewEd25519Signer(holder.Methods[3], holder.AuthEdSk), challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyPresentation(secured, challenge, domain)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("presentation of tampered credential should not be verified: %v", err)
+	}
+
+	// the credential is not about the holder
+	secured, err = PresentDataIntegrity(NewPresentation(i.DID, credential), types.NewEd25519Signer(i.Methods[3], i.AuthEdSk), challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyPresentation(secured, challenge, domain)
+	if err == nil {
+		t.Fatal("presentation of credentials about others should not be verified")
+	}
+}
+
+func TestPresentationJWT(t *testing.T) {
+	documents := memotest.Documents{}
+	i := documents.NewIdentity(t, strings.Repeat("ab", 32))
+	holder := documents.NewIdentity(t, strings.Repeat("cd", 32))
+	verifier := NewVerifier(documents)
+
+	credential, err := IssueDataIntegrity(newTestCredential(i), types.NewSecp256k1Signer(i.Methods[0], i.Sk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	presentation := NewPresentation(holder.DID, credential)
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := "gateway.metamemo.one"
+
+	token, err := PresentJWT(presentation, jws.NewEdDSASigner(holder.Methods[3], holder.AuthEdSk), challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, err := verifier.VerifyPresentationJWT(token, challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.Holder != holder.DID.String() || len(verified.VerifiableCredential) != 1 || verified.VerifiableCredential[0].Issuer != i.DID.String() {
+		t.Fatalf("unexpected presentation %+v", verified)
+	}
+
+	_, err = verifier.VerifyPresentationJWT(token, challenge+"00", domain)
+	if err == nil {
+		t.Fatal("JWT should not be verified with another challenge")
+	}
+	_, err = verifier.VerifyPresentationJWT(token, challenge, "other.metamemo.one")
+	if err == nil {
+		t.Fatal("JWT should not be verified in another domain")
+	}
+
+	token, err = PresentJWT(presentation, jws.NewES256KSigner(holder.Methods[0], holder.Sk), challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyPresentationJWT(token, challenge, domain)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("JWT signed by assertion key should not be verified: %v", err)
+	}
+
+	_, err = PresentJWT(presentation, jws.NewEdDSASigner(i.Methods[3], i.AuthEdSk), challenge, domain)
+	if err == nil {
+		t.Fatal("presentation should not be signed by the key of others")
+	}
+}
+
+func TestCapability(t *testing.T) {
+	documents := memotest.Documents{}
+	owner := documents.NewIdentity(t, strings.Repeat("ab", 32))
+	agent := documents.NewIdentity(t, strings.Repeat("cd", 32))
+	other := documents.NewIdentity(t, strings.Repeat("ef", 32))
+	// the owner delegates with key 1, and the agent delegates again with key 3
+	documents[owner.DID.Identifier].CapabilityDelegation = []types.MemoDIDUrl{owner.Methods[1]}
+	documents[agent.DID.Identifier].CapabilityDelegation = []types.MemoDIDUrl{agent.Methods[3]}
+	verifier := NewVerifier(documents)
+	target := "did:mfile:bafybeihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku"
+	verifier.Targets = func(ctx context.Context, didString string) (*types.MemoDID, error) {
+		if didString != target {
+			return nil, xerrors.Errorf("%s is not found", didString)
+		}
+		return &owner.DID, nil
+	}
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := "gateway.metamemo.one"
+
+	capability, err := NewCapability(target, agent.Methods[3], time.Now().Add(time.Hour), "mfile:grantRead", "mfile:deactivateRead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	capability, err = DelegateDataIntegrity(capability, types.NewEd25519Signer(owner.Methods[1], owner.EdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	invocation, err := Invoke(capability, "mfile:grantRead", types.NewEd25519Signer(agent.Methods[3], agent.AuthEdSk), challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(invocation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received Invocation
+	err = json.Unmarshal(data, &received)
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, err := verifier.VerifyInvocation(&received, target, "mfile:grantRead", challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method.ID.Identifier != agent.DID.Identifier || method.ID.GetMethodIndex() != 3 {
+		t.Fatalf("invoked by %s, should be key 3 of agent", method.ID.String())
+	}
+
+	// the action is not allowed, or the invocation is replayed for others
+	_, err = Invoke(capability, "mfile:changePrice", types.NewEd25519Signer(agent.Methods[3], agent.AuthEdSk), challenge, domain)
+	if err == nil {
+		t.Fatal("action not allowed should not be invoked")
+	}
+	_, err = verifier.VerifyInvocation(&received, target, "mfile:deactivateRead", challenge, domain)
+	if err == nil {
+		t.Fatal("invocation should not be verified for another action")
+	}
+	_, err = verifier.VerifyInvocation(&received, target, "mfile:grantRead", challenge+"00", domain)
+	if err == nil {
+		t.Fatal("invocation should not be verified with another challenge")
+	}
+	// the capability is invoked by others
+	_, err = Invoke(capability, "mfile:grantRead", types.NewEd25519Signer(other.Methods[3], other.AuthEdSk), challenge, domain)
+	if err == nil {
+		t.Fatal("capability should not be invoked by others")
+	}
+	// the key of the controller on another chain invokes the capability
+	_, err = Invoke(capability, "mfile:grantRead", types.NewEd25519Signer(agent.Methods[3].WithChainID("othernet"), agent.AuthEdSk), challenge, domain)
+	if err == nil {
+		t.Fatal("capability should not be invoked by the key on another chain")
+	}
+	otherChain := received
+	proof := *received.Proof
+	otherKey := agent.Methods[3].WithChainID("othernet")
+	proof.VerificationMethod = otherKey.String()
+	otherChain.Proof = &proof
+	_, err = verifier.VerifyInvocation(&otherChain, target, "mfile:grantRead", challenge, domain)
+	if err == nil {
+		t.Fatal("invocation signed by the key on another chain should not be verified")
+	}
+	unsigned := received
+	unsigned.Proof = nil
+	_, err = verifier.VerifyInvocation(&unsigned, target, "mfile:grantRead", challenge, domain)
+	if err == nil {
+		t.Fatal("invocation without proof should not be verified")
+	}
+	// the controller of capability is replaced by others
+	forged := *capability
+	otherKey = other.Methods[3]
+	forged.Controller = otherKey.String()
+	forgedInvocation, err := Invoke(&forged, "mfile:grantRead", types.NewEd25519Signer(other.Methods[3], other.AuthEdSk), challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyInvocation(forgedInvocation, target, "mfile:grantRead", challenge, domain)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("capability delegated to others should not be verified: %v", err)
+	}
+
+	// the agent delegates again with less actions and shorter expiry
+	_, err = capability.Delegate(other.Methods[3], time.Now().Add(time.Minute), "mfile:*")
+	if err == nil {
+		t.Fatal("delegation should not allow more actions")
+	}
+	_, err = capability.Delegate(other.Methods[3], time.Now().Add(2*time.Hour), "mfile:grantRead")
+	if err == nil {
+		t.Fatal("delegation should not expire after its parent")
+	}
+	delegated, err := capability.Delegate(other.Methods[3], time.Now().Add(time.Minute), "mfile:grantRead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = DelegateDataIntegrity(delegated, types.NewEd25519Signer(owner.Methods[1], owner.EdSk))
+	if err == nil {
+		t.Fatal("delegation should be signed by the controller of its parent")
+	}
+	delegated, err = DelegateDataIntegrity(delegated, types.NewEd25519Signer(agent.Methods[3], agent.AuthEdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	invocation, err = Invoke(delegated, "mfile:grantRead", types.NewEd25519Signer(other.Methods[3], other.AuthEdSk), challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = verifier.VerifyInvocation(invocation, target, "mfile:grantRead", challenge, domain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the delegation attenuated is tampered
+	tampered := *delegated
+	tampered.AllowedAction = []string{"mfile:*"}
+	err = verifier.VerifyCapability(&tampered)
+	if err == nil {
+		t.Fatal("delegation allowing more actions should not be verified")
+	}
+	tampered.AllowedAction = []string{"mfile:deactivateRead"}
+	err = verifier.VerifyCapability(&tampered)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("tampered delegation should not be verified: %v", err)
+	}
+
+	// the capability expires
+	expired, err := NewCapability(target, agent.Methods[3], time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err = DelegateDataIntegrity(expired, types.NewEd25519Signer(owner.Methods[1], owner.EdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifier.VerifyCapability(expired)
+	if err == nil {
+		t.Fatal("expired capability should not be verified")
+	}
+
+	// the delegation of the owner key expires on chain
+	documents[owner.DID.Identifier].CapabilityDelegation = nil
+	err = verifier.VerifyCapability(delegated)
+	if !errors.Is(err, memo.ErrNotVerified) {
+		t.Fatalf("capability delegated by key not in capabilityDelegation should not be verified: %v", err)
+	}
+
+	// the capability of a memo did is delegated by itself
+	self, err := NewCapability(agent.DID.String()+"/files", other.Methods[3], time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	self, err = DelegateDataIntegrity(self, types.NewEd25519Signer(agent.Methods[3], agent.AuthEdSk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifier.VerifyCapability(self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = verifier.VerifyCapability(&Capability{})
+	if err == nil {
+		t.Fatal("empty capability should not be verified")
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	for _, test := range []struct {
+		json      string
+		canonical string
+	}{
+		// numbers of RFC 8785 appendix B
+		{`[1.0, 1e2, 4.50, 2e-3, -0, 1e-6, 1e-7, 1e21, 1e20, 1e30, 333333333.33333329, 9007199254740992, -1.5e-9]`,
+			`[1,100,4.5,0.002,0,0.000001,1e-7,1e+21,100000000000000000000,1e+30,333333333.3333333,9007199254740992,-1.5e-9]`},
+		// only quotation mark, reverse solidus and control characters are escaped
+		{`"\u20ac$\u000f\u000aA'\u0042\u0022\u005c\\\"\/<>&\u2028"`,
+			"\"\u20ac$\\u000f\\nA'B\\\"\\\\\\\\\\\"/<>&\u2028\""},
+		// keys are sorted by utf-16 code units, so the surrogate pair of U+1F600 sorts before U+FB33
+		{`{"\u20ac": 1, "\r": 2, "\ufb33": 3, "1": 4, "\ud83d\ude00": 5, "\u0080": 6, "\u00f6": 7, "a": {"c": true, "b": null}}`,
+			"{\"\\r\":2,\"1\":4,\"a\":{\"b\":null,\"c\":true},\"\u0080\":6,\"\u00f6\":7,\"\u20ac\":1,\"\U0001f600\":5,\"\ufb33\":3}"},
+	} {
+		var value interface{}
+		decoder := json.NewDecoder(strings.NewReader(test.json))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			t.Fatal(err)
+		}
+		canonical, err := canonicalize(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(canonical) != test.canonical {
+			t.Fatalf("%s is canonicalized as %s, not %s", test.json, canonical, test.canonical)
+		}
+	}
+}
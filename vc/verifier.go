@@ -0,0 +1,205 @@
+package vc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/memoio/go-did/jws"
+	"github.com/memoio/go-did/memo"
+	"github.com/memoio/go-did/types"
+	"golang.org/x/xerrors"
+)
+
+// Verifier verifies credentials with the keys currently in the assertionMethod of their issuers,
+// presentations with the keys currently in the authentication of their holders, and capabilities
+// with the keys currently in the capabilityDelegation of their delegators
+type Verifier struct {
+	// Targets resolves the memo dids controlling capability targets other than memo dids,
+	// such as MfileTargets
+	Targets TargetResolver
+
+	verifier *memo.Verifier
+}
+
+func NewVerifier(resolver memo.DocumentResolver) *Verifier {
+	return &Verifier{verifier: memo.NewVerifier(resolver)}
+}
+
+// VerifyCredential verifies the Data Integrity proof of credential and checks it is
+// valid now, the verification method signing is returned.
+func (v *Verifier) VerifyCredential(credential *Credential) (*types.VerificationMethod, error) {
+	return v.VerifyCredentialContext(context.Background(), credential)
+}
+
+func (v *Verifier) VerifyCredentialContext(ctx context.Context, credential *Credential) (*types.VerificationMethod, error) {
+	if err := credential.Validate(time.Now()); err != nil {
+		return nil, err
+	}
+	if credential.Proof == nil {
+		return nil, xerrors.Errorf("credential has no proof")
+	}
+	if err := checkIssuer(credential.Proof.VerificationMethod, credential.Issuer); err != nil {
+		return nil, err
+	}
+	signed, err := credential.signed()
+	if err != nil {
+		return nil, err
+	}
+	return verifyProof(ctx, v.verifier, signed, credential.Context, credential.Proof, types.PurposeAssertionMethod)
+}
+
+// VerifyCredentialJWT verifies the credential encoded as JWT and checks it is valid now.
+// The JWT is signed by the key of kid, or any key of the issuer if it has no kid.
+func (v *Verifier) VerifyCredentialJWT(token string) (*Credential, error) {
+	return v.VerifyCredentialJWTContext(context.Background(), token)
+}
+
+func (v *Verifier) VerifyCredentialJWTContext(ctx context.Context, token string) (*Credential, error) {
+	signed, err := jws.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	var claims credentialClaims
+	if err := json.Unmarshal(signed.Payload, &claims); err != nil {
+		return nil, xerrors.Errorf("invalid JWT claims: %w", err)
+	}
+	credential := claims.Credential
+	if credential == nil {
+		return nil, xerrors.Errorf("JWT has no vc claim")
+	}
+	if claims.Issuer != credential.Issuer {
+		return nil, xerrors.Errorf("iss %s does not match issuer %s", claims.Issuer, credential.Issuer)
+	}
+	if claims.Subject != "" && claims.Subject != credential.SubjectID() {
+		return nil, xerrors.Errorf("sub %s does not match credential subject %s", claims.Subject, credential.SubjectID())
+	}
+
+	kid := signed.Header.Kid
+	if kid == "" {
+		kid = claims.Issuer
+	}
+	if err := checkIssuer(kid, claims.Issuer); err != nil {
+		return nil, err
+	}
+	if err := v.verifyJWS(ctx, signed, kid, types.PurposeAssertionMethod); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if claims.NotBefore != 0 && now.Before(unixTime(claims.NotBefore)) {
+		return nil, xerrors.Errorf("JWT is not valid until %s", unixTime(claims.NotBefore).Format(time.RFC3339))
+	}
+	if claims.ExpiresAt != 0 && !now.Before(unixTime(claims.ExpiresAt)) {
+		return nil, xerrors.Errorf("JWT expired at %s", unixTime(claims.ExpiresAt).Format(time.RFC3339))
+	}
+	if err := credential.Validate(now); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// VerifyPresentation verifies the Data Integrity proof of presentation is signed for challenge
+// and domain, and verifies the credentials presented. The verification method signing is returned.
+func (v *Verifier) VerifyPresentation(presentation *Presentation, challenge, domain string) (*types.VerificationMethod, error) {
+	return v.VerifyPresentationContext(context.Background(), presentation, challenge, domain)
+}
+
+func (v *Verifier) VerifyPresentationContext(ctx context.Context, presentation *Presentation, challenge, domain string) (*types.VerificationMethod, error) {
+	if err := presentation.Validate(); err != nil {
+		return nil, err
+	}
+	proof := presentation.Proof
+	if proof == nil {
+		return nil, xerrors.Errorf("presentation has no proof")
+	}
+	if err := checkBinding(proof.Challenge, proof.Domain, challenge, domain); err != nil {
+		return nil, err
+	}
+	if err := checkIssuer(proof.VerificationMethod, presentation.Holder); err != nil {
+		return nil, err
+	}
+	signed, err := presentation.signed()
+	if err != nil {
+		return nil, err
+	}
+	method, err := verifyProof(ctx, v.verifier, signed, presentation.Context, proof, types.PurposeAuthentication)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.verifyCredentials(ctx, presentation.VerifiableCredential); err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+// VerifyPresentationJWT verifies the presentation encoded as JWT is signed for challenge and
+// domain, and verifies the credentials presented. The JWT is signed by the key of kid, or any
+// key of the holder if it has no kid.
+func (v *Verifier) VerifyPresentationJWT(token, challenge, domain string) (*Presentation, error) {
+	return v.VerifyPresentationJWTContext(context.Background(), token, challenge, domain)
+}
+
+func (v *Verifier) VerifyPresentationJWTContext(ctx context.Context, token, challenge, domain string) (*Presentation, error) {
+	signed, err := jws.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+	var claims presentationClaims
+	if err := json.Unmarshal(signed.Payload, &claims); err != nil {
+		return nil, xerrors.Errorf("invalid JWT claims: %w", err)
+	}
+	presentation := claims.Presentation
+	if presentation == nil {
+		return nil, xerrors.Errorf("JWT has no vp claim")
+	}
+	if claims.Issuer != presentation.Holder {
+		return nil, xerrors.Errorf("iss %s does not match holder %s", claims.Issuer, presentation.Holder)
+	}
+	if err := checkBinding(claims.Nonce, claims.Audience, challenge, domain); err != nil {
+		return nil, err
+	}
+	if err := presentation.Validate(); err != nil {
+		return nil, err
+	}
+
+	kid := signed.Header.Kid
+	if kid == "" {
+		kid = claims.Issuer
+	}
+	if err := checkIssuer(kid, claims.Issuer); err != nil {
+		return nil, err
+	}
+	if err := v.verifyJWS(ctx, signed, kid, types.PurposeAuthentication); err != nil {
+		return nil, err
+	}
+
+	if err := v.verifyCredentials(ctx, presentation.VerifiableCredential); err != nil {
+		return nil, err
+	}
+	return presentation, nil
+}
+
+// verifyCredentials verifies each credential of a presentation
+func (v *Verifier) verifyCredentials(ctx context.Context, credentials []*Credential) error {
+	for i, credential := range credentials {
+		if _, err := v.VerifyCredentialContext(ctx, credential); err != nil {
+			return xerrors.Errorf("credential %d is not verified: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// verifyJWS checks signed is signed by a key of kid currently in the relationship of purpose
+func (v *Verifier) verifyJWS(ctx context.Context, signed *jws.JWS, kid string, purpose string) error {
+	methods, err := v.verifier.MethodsContext(ctx, kid, purpose)
+	if err != nil {
+		return err
+	}
+	for _, method := range methods {
+		if signed.Verify(method.PublicKey) == nil {
+			return nil
+		}
+	}
+	return memo.ErrNotVerified
+}